@@ -1,17 +1,35 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/application/customer"
 	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/application/invoice"
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/application/numbering"
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/application/outbox"
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/application/recurring"
+	apptax "github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/application/tax"
 	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/config"
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/boleto"
+	domainexchange "github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/exchange"
+	domaininvoice "github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/invoice"
+	domainoutbox "github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/outbox"
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/tax"
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/infrastructure/events"
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/infrastructure/exchange"
 	httphandlers "github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/infrastructure/http/handlers"
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/infrastructure/http/middleware"
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/infrastructure/inventory"
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/infrastructure/nfe"
 	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/infrastructure/persistence"
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/infrastructure/pix"
 
 	"github.com/gorilla/mux"
 	_ "github.com/lib/pq"
@@ -34,22 +52,136 @@ func main() {
 	defer db.Close()
 
 	invoiceRepo := persistence.NewInvoiceRepository(db)
-	invoiceService := invoice.NewInvoiceService(invoiceRepo, cfg.InventoryServiceURL)
-	invoiceHandler := httphandlers.NewInvoiceHandler(invoiceService)
+	sagaRepo := persistence.NewSagaRepository(db)
+	creditNoteRepo := persistence.NewCreditNoteRepository(db)
+	paymentRepo := persistence.NewPaymentRepository(db)
+	boletoRepo := persistence.NewBoletoRepository(db)
+	customerRepo := persistence.NewCustomerRepository(db)
+	taxClassRepo := persistence.NewTaxClassRepository(db)
+	seriesRepo := persistence.NewSeriesRepository(db)
+	inventoryClient := inventory.NewClient(inventory.Config{
+		BaseURL:          cfg.InventoryServiceURL,
+		Timeout:          time.Duration(cfg.Inventory.TimeoutMs) * time.Millisecond,
+		MaxRetries:       cfg.Inventory.MaxRetries,
+		RetryBaseDelay:   time.Duration(cfg.Inventory.RetryBaseDelayMs) * time.Millisecond,
+		RetryMaxDelay:    time.Duration(cfg.Inventory.RetryMaxDelayMs) * time.Millisecond,
+		BreakerThreshold: cfg.Inventory.BreakerFailureThreshold,
+		BreakerOpenFor:   time.Duration(cfg.Inventory.BreakerOpenSeconds) * time.Second,
+		HoldTTL:          time.Duration(cfg.Inventory.HoldTTLSeconds) * time.Second,
+	})
+	rates, err := setupExchange(cfg)
+	if err != nil {
+		log.Fatalf("Falha ao carregar as cotações de câmbio: %v", err)
+	}
+	invoiceService := invoice.NewInvoiceService(invoiceRepo, sagaRepo, creditNoteRepo, paymentRepo, boletoRepo, customerRepo, rates,
+		setupTaxEngine(cfg), taxClassRepo, seriesRepo, cfg.Invoice.SeriesCode, paymentTerms(cfg), setupBoleto(cfg), cfg.Boleto.DueDays, inventoryClient,
+		time.Duration(cfg.Invoice.CancellationWindowHours)*time.Hour, time.Duration(cfg.Invoice.PrintLeaseSeconds)*time.Second)
+
+	seriesService := numbering.NewSeriesService(seriesRepo)
+	if err := seriesService.EnsureSeries(context.Background(), cfg.Invoice.SeriesCode, cfg.Invoice.SeriesPrefix,
+		cfg.Invoice.SeriesPadding, cfg.Invoice.SeriesResetYearly); err != nil {
+		log.Fatalf("Falha ao preparar a série de numeração %s: %v", cfg.Invoice.SeriesCode, err)
+	}
+
+	go func() {
+		if err := invoiceService.RecoverPrintSagas(context.Background()); err != nil {
+			log.Printf("Falha ao recuperar sagas de impressão: %v", err)
+		}
+		if err := invoiceService.ReturnPendingStock(context.Background()); err != nil {
+			log.Printf("Falha ao retomar devoluções de estoque: %v", err)
+		}
+	}()
+	if cfg.Invoice.OverdueCheckMinutes > 0 {
+		go invoiceService.RunOverdueCheck(context.Background(), time.Duration(cfg.Invoice.OverdueCheckMinutes)*time.Minute)
+	}
+
+	recurringService := recurring.NewRecurringService(persistence.NewRecurringRepository(db), invoiceService,
+		time.Duration(cfg.Invoice.RecurringLeaseSeconds)*time.Second)
+	if cfg.Invoice.RecurringPollSeconds > 0 && cfg.Invoice.RecurringLeaseSeconds > 0 {
+		go recurringService.Run(context.Background(), time.Duration(cfg.Invoice.RecurringPollSeconds)*time.Second)
+	}
+
+	printQueue := invoice.NewPrintQueue(invoiceService, persistence.NewPrintJobRepository(db),
+		cfg.Invoice.PrintWorkers, time.Duration(cfg.Invoice.PrintPollSeconds)*time.Second,
+		time.Duration(cfg.Invoice.PrintLeaseSeconds)*time.Second, cfg.Invoice.PrintMaxAttempts)
+	if cfg.Invoice.PrintWorkers > 0 && cfg.Invoice.PrintPollSeconds > 0 && cfg.Invoice.PrintLeaseSeconds > 0 {
+		go printQueue.Run(context.Background())
+	}
+	invoiceHandler := httphandlers.NewInvoiceHandler(invoiceService, printQueue, setupNFeExporter(cfg), setupPix(cfg))
+	customerHandler := httphandlers.NewCustomerHandler(customer.NewCustomerService(customerRepo))
+	taxClassHandler := httphandlers.NewTaxClassHandler(apptax.NewTaxService(taxClassRepo))
+	seriesHandler := httphandlers.NewSeriesHandler(seriesService)
+	recurringHandler := httphandlers.NewRecurringHandler(recurringService)
+	idempotent := middleware.NewIdempotency(persistence.NewIdempotencyRepository(db),
+		time.Duration(cfg.Idempotency.LockSeconds)*time.Second, time.Duration(cfg.Idempotency.RetentionHours)*time.Hour)
+	if cfg.Idempotency.CleanupMinutes > 0 {
+		go idempotent.RunCleanup(context.Background(), time.Duration(cfg.Idempotency.CleanupMinutes)*time.Minute)
+	}
+
+	relay := outbox.NewRelay(persistence.NewOutboxRepository(db), setupPublisher(cfg), outbox.Config{
+		Interval:    time.Duration(cfg.Outbox.PollIntervalMs) * time.Millisecond,
+		BatchSize:   cfg.Outbox.BatchSize,
+		ClaimTTL:    time.Duration(cfg.Outbox.ClaimSeconds) * time.Second,
+		RetryDelay:  time.Duration(cfg.Outbox.RetrySeconds) * time.Second,
+		MaxAttempts: cfg.Outbox.MaxAttempts,
+	})
+	go relay.Run(context.Background())
 
 	router := mux.NewRouter()
-	router.HandleFunc("/invoices", invoiceHandler.CreateInvoice).Methods("POST")
+	router.HandleFunc("/customers", customerHandler.Create).Methods("POST")
+	router.HandleFunc("/customers", customerHandler.List).Methods("GET")
+	router.HandleFunc("/customers/{id}", customerHandler.Get).Methods("GET")
+	router.HandleFunc("/customers/{id}", customerHandler.Update).Methods("PUT")
+	router.HandleFunc("/customers/{id}", customerHandler.Delete).Methods("DELETE")
+
+	router.HandleFunc("/invoice-series", seriesHandler.Create).Methods("POST")
+	router.HandleFunc("/invoice-series", seriesHandler.List).Methods("GET")
+	router.HandleFunc("/invoice-series/{code}", seriesHandler.Get).Methods("GET")
+
+	router.HandleFunc("/recurring-invoices", idempotent.Wrap(recurringHandler.Create)).Methods("POST")
+	router.HandleFunc("/recurring-invoices", recurringHandler.List).Methods("GET")
+	router.HandleFunc("/recurring-invoices/{id}", recurringHandler.Get).Methods("GET")
+	router.HandleFunc("/recurring-invoices/{id}", recurringHandler.Update).Methods("PUT")
+	router.HandleFunc("/recurring-invoices/{id}", recurringHandler.Delete).Methods("DELETE")
+	router.HandleFunc("/recurring-invoices/{id}/runs", recurringHandler.ListRuns).Methods("GET")
+
+	router.HandleFunc("/tax-classes/{productId}", taxClassHandler.Get).Methods("GET")
+	router.HandleFunc("/tax-classes/{productId}", taxClassHandler.Save).Methods("PUT")
+
+	router.HandleFunc("/invoices", idempotent.Wrap(invoiceHandler.CreateInvoice)).Methods("POST")
 	router.HandleFunc("/invoices", invoiceHandler.ListInvoices).Methods("GET")
 	router.HandleFunc("/invoices/{id}", invoiceHandler.GetInvoice).Methods("GET")
-	router.HandleFunc("/invoices/{id}/items", invoiceHandler.AddInvoiceItem).Methods("POST")
-	router.HandleFunc("/invoices/{id}/print", invoiceHandler.PrintInvoice).Methods("POST")
+	router.HandleFunc("/invoices/{id}/items", idempotent.Wrap(invoiceHandler.AddInvoiceItem)).Methods("POST")
+	router.HandleFunc("/invoices/{id}/items/{itemId}", invoiceHandler.UpdateInvoiceItem).Methods("PATCH")
+	router.HandleFunc("/invoices/{id}/items/{itemId}", invoiceHandler.RemoveInvoiceItem).Methods("DELETE")
+	router.HandleFunc("/invoices/{id}/adjustments", idempotent.Wrap(invoiceHandler.AddAdjustment)).Methods("POST")
+	router.HandleFunc("/invoices/{id}/adjustments/{adjustmentId}", invoiceHandler.RemoveAdjustment).Methods("DELETE")
+	router.HandleFunc("/invoices/{id}/print", idempotent.Wrap(invoiceHandler.PrintInvoice)).Methods("POST")
+	router.HandleFunc("/invoices/{id}/nfe.xml", invoiceHandler.GetInvoiceNFe).Methods("GET")
+	router.HandleFunc("/invoices/{id}/pdf", invoiceHandler.GetInvoicePDF).Methods("GET")
+	router.HandleFunc("/invoices/{id}/payment-terms", invoiceHandler.SetPaymentTerms).Methods("PUT")
+	router.HandleFunc("/invoices/{id}/open", invoiceHandler.OpenInvoice).Methods("POST")
+	router.HandleFunc("/invoices/{id}/cancel", invoiceHandler.CancelInvoice).Methods("POST")
+	router.HandleFunc("/invoices/{id}/void", invoiceHandler.VoidInvoice).Methods("POST")
+	router.HandleFunc("/invoices/{id}/transitions", invoiceHandler.ListTransitions).Methods("GET")
+	router.HandleFunc("/invoices/{id}/credit-notes", idempotent.Wrap(invoiceHandler.IssueCreditNote)).Methods("POST")
+	router.HandleFunc("/invoices/{id}/credit-notes", invoiceHandler.ListCreditNotes).Methods("GET")
+	router.HandleFunc("/invoices/{id}/payments", idempotent.Wrap(invoiceHandler.RecordPayment)).Methods("POST")
+	router.HandleFunc("/invoices/{id}/payments", invoiceHandler.ListPayments).Methods("GET")
+	router.HandleFunc("/invoices/{id}/boletos", idempotent.Wrap(invoiceHandler.IssueBoleto)).Methods("POST")
+	router.HandleFunc("/invoices/{id}/boletos", invoiceHandler.ListBoletos).Methods("GET")
+	router.HandleFunc("/invoices/{id}/pix", invoiceHandler.GetPix).Methods("GET")
+	router.HandleFunc("/invoices/{id}/pix.png", invoiceHandler.GetPixQRCode).Methods("GET")
+
+	router.HandleFunc("/print-jobs/{id}", invoiceHandler.GetPrintJob).Methods("GET")
 
 	router.Use(loggingMiddleware)
 
 	c := cors.New(cors.Options{
 		AllowedOrigins: []string{"*"},
-		AllowedMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders: []string{"Content-Type", "Authorization"},
+		AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders: []string{"Content-Type", "Authorization", middleware.IdempotencyKeyHeader},
+		ExposedHeaders: []string{middleware.IdempotentReplayedHeader},
 	})
 	handler := c.Handler(router)
 
@@ -81,6 +213,150 @@ func getPort() string {
 	return port
 }
 
+func setupPublisher(cfg *config.Config) domainoutbox.Publisher {
+	switch cfg.Outbox.Publisher {
+	case "webhook":
+		log.Printf("Publicando eventos via webhook em %s", cfg.Outbox.WebhookURL)
+		return events.NewWebhookPublisher(cfg.Outbox.WebhookURL, cfg.Outbox.WebhookSecret, 5*time.Second)
+	case "broker":
+		log.Printf("Publicando eventos no tópico %s via %s", cfg.Outbox.BrokerTopic, cfg.Outbox.BrokerURL)
+		return events.NewBrokerPublisher(events.NewRESTProxyProducer(cfg.Outbox.BrokerURL, 5*time.Second), cfg.Outbox.BrokerTopic)
+	default:
+		bus := events.NewInProcessBus()
+		bus.Subscribe("*", events.LogHandler)
+		return bus
+	}
+}
+
+func setupTaxEngine(cfg *config.Config) *tax.Engine {
+	origin := tax.Location{UF: cfg.NFe.IssuerUF}
+
+	switch cfg.Tax.Engine {
+	case "brazil":
+		pisCofins := tax.NonCumulative
+		if cfg.Tax.PISCOFINSRegime == "cumulative" {
+			pisCofins = tax.Cumulative
+		}
+		return tax.NewEngine(origin, tax.ICMS{InternalRate: parseRate(cfg.Tax.ICMSRate)}, tax.IPI{}, pisCofins)
+	case "simples":
+		return tax.NewEngine(origin, tax.IPI{})
+	case "vat":
+		vat := tax.VAT{DefaultRate: parseRate(cfg.Tax.VATRate), Rates: map[string]tax.Rate{}}
+		for _, entry := range strings.Split(cfg.Tax.VATRates, ",") {
+			region, rate, ok := strings.Cut(strings.TrimSpace(entry), "=")
+			if ok {
+				vat.Rates[strings.ToUpper(region)] = parseRate(rate)
+			}
+		}
+		return tax.NewEngine(origin, vat)
+	case "none":
+		return tax.NewEngine(origin)
+	}
+
+	log.Printf("Motor de impostos %q desconhecido, nenhum imposto será calculado", cfg.Tax.Engine)
+	return tax.NewEngine(origin)
+}
+
+// setupExchange picks the source of exchange rates: "static" quotes the
+// FX_RATES list, "file" reads FX_RATES_FILE whenever it changes.
+func setupExchange(cfg *config.Config) (domainexchange.Provider, error) {
+	switch cfg.Exchange.Provider {
+	case "file":
+		log.Printf("Cotações de câmbio lidas de %s", cfg.Exchange.RatesFile)
+		return exchange.NewFileProvider(cfg.Exchange.RatesFile)
+	case "static":
+	default:
+		log.Printf("Provedor de câmbio %q desconhecido, usando cotações estáticas", cfg.Exchange.Provider)
+	}
+	return exchange.NewStaticProvider(cfg.Exchange.BaseCurrency, cfg.Exchange.Rates)
+}
+
+func paymentTerms(cfg *config.Config) domaininvoice.PaymentTerms {
+	terms, err := domaininvoice.ParsePaymentTerms(cfg.Invoice.PaymentTerms)
+	if err != nil {
+		log.Printf("Condição de pagamento %q inválida, faturas vencem na emissão", cfg.Invoice.PaymentTerms)
+		return nil
+	}
+	return terms
+}
+
+func parseRate(s string) tax.Rate {
+	rate, err := tax.ParseRate(s)
+	if err != nil {
+		log.Printf("Alíquota %q inválida, usando zero", s)
+		return 0
+	}
+	return rate
+}
+
+func setupNFeExporter(cfg *config.Config) *nfe.Exporter {
+	location, err := time.LoadLocation(cfg.NFe.Timezone)
+	if err != nil {
+		log.Printf("Fuso horário %q inválido, usando horário local: %v", cfg.NFe.Timezone, err)
+		location = time.Local
+	}
+
+	return nfe.NewExporter(nfe.Issuer{
+		CNPJ:        cfg.NFe.IssuerCNPJ,
+		Name:        cfg.NFe.IssuerName,
+		TradeName:   cfg.NFe.IssuerTradeName,
+		IE:          cfg.NFe.IssuerIE,
+		CRT:         cfg.NFe.IssuerCRT,
+		Street:      cfg.NFe.IssuerStreet,
+		Number:      cfg.NFe.IssuerNumber,
+		District:    cfg.NFe.IssuerDistrict,
+		CityCode:    cfg.NFe.IssuerCityCode,
+		City:        cfg.NFe.IssuerCity,
+		UF:          cfg.NFe.IssuerUF,
+		CEP:         cfg.NFe.IssuerCEP,
+		Series:      cfg.NFe.Series,
+		Environment: cfg.NFe.Environment,
+		Nature:      cfg.NFe.Nature,
+		DefaultNCM:  cfg.NFe.DefaultNCM,
+		DefaultCFOP: cfg.NFe.DefaultCFOP,
+		DefaultUnit: cfg.NFe.DefaultUnit,
+		ICMSRate:    cfg.NFe.ICMSRate,
+		Location:    location,
+	})
+}
+
+// setupPix returns nil when PIX is not configured, which disables the PIX
+// endpoints and leaves the QR code out of the DANFE.
+func setupPix(cfg *config.Config) *pix.Receiver {
+	if cfg.Pix.Key == "" && cfg.Pix.LocationURL == "" {
+		return nil
+	}
+	if cfg.Pix.MerchantName == "" || cfg.Pix.MerchantCity == "" {
+		log.Printf("PIX desativado: nome e cidade do recebedor são obrigatórios")
+		return nil
+	}
+	return &pix.Receiver{
+		Key:              cfg.Pix.Key,
+		Name:             cfg.Pix.MerchantName,
+		City:             cfg.Pix.MerchantCity,
+		LocationTemplate: cfg.Pix.LocationURL,
+	}
+}
+
+// setupBoleto returns nil when no bank is configured, which disables boleto
+// generation.
+func setupBoleto(cfg *config.Config) *boleto.Beneficiary {
+	if cfg.Boleto.BankCode == "" {
+		return nil
+	}
+	if !boleto.Supported(cfg.Boleto.BankCode) {
+		log.Printf("Boleto desativado: banco %s não suportado", cfg.Boleto.BankCode)
+		return nil
+	}
+	return &boleto.Beneficiary{
+		BankCode:  cfg.Boleto.BankCode,
+		Agency:    cfg.Boleto.Agency,
+		Account:   cfg.Boleto.Account,
+		Wallet:    cfg.Boleto.Wallet,
+		Agreement: cfg.Boleto.Agreement,
+	}
+}
+
 func setupDatabase(cfg *config.Config) (*sql.DB, error) {
 	connStr := os.Getenv("DATABASE_URL")
 	if connStr == "" {
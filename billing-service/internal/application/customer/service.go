@@ -0,0 +1,67 @@
+package customer
+
+import (
+	"context"
+	"time"
+
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/customer"
+)
+
+type Service struct {
+	repo customer.Repository
+}
+
+// Input carries the editable customer fields for create and update.
+type Input struct {
+	Name              string
+	Document          string
+	Email             string
+	StateRegistration string
+	Addresses         []customer.Address
+}
+
+func NewCustomerService(repo customer.Repository) *Service {
+	return &Service{repo: repo}
+}
+
+func (s *Service) CreateCustomer(ctx context.Context, in Input) (*customer.Customer, error) {
+	c, err := customer.NewCustomer(in.Name, in.Document, in.Email, in.StateRegistration, in.Addresses)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Create(ctx, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *Service) GetCustomer(ctx context.Context, id int) (*customer.Customer, error) {
+	return s.repo.GetByID(ctx, id)
+}
+
+func (s *Service) ListCustomers(ctx context.Context) ([]*customer.Customer, error) {
+	return s.repo.List(ctx)
+}
+
+// UpdateCustomer replaces the customer data. Invoices that were already
+// issued keep the snapshot taken when they were closed.
+func (s *Service) UpdateCustomer(ctx context.Context, id int, in Input) (*customer.Customer, error) {
+	c, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.Change(in.Name, in.Document, in.Email, in.StateRegistration, in.Addresses, time.Now()); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Update(ctx, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *Service) DeleteCustomer(ctx context.Context, id int) error {
+	return s.repo.Delete(ctx, id)
+}
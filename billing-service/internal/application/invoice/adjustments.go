@@ -0,0 +1,72 @@
+package invoice
+
+import (
+	"context"
+	"log"
+
+	domaininvoice "github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/invoice"
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/tax"
+	"github.com/vitorwhois/microservice-invoice-billing/money"
+)
+
+// AdjustmentInput describes a discount or surcharge. ItemID zero applies it
+// to the whole invoice. A fixed Amount is in the currency of the invoice.
+type AdjustmentInput struct {
+	ItemID int
+	Kind   domaininvoice.AdjustmentKind
+	Method domaininvoice.AdjustmentMethod
+	Rate   tax.Rate
+	Amount money.Literal
+	Reason string
+}
+
+func (s *Service) AddAdjustment(ctx context.Context, invoiceID int, in AdjustmentInput) (*domaininvoice.Adjustment, error) {
+	inv, err := s.repo.GetByID(ctx, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+
+	amount, err := in.Amount.In(inv.Currency)
+	if err != nil {
+		return nil, domaininvoice.ErrInvalidAdjustment
+	}
+	adj, err := domaininvoice.NewAdjustment(in.ItemID, in.Kind, in.Method, in.Rate, amount, in.Reason)
+	if err != nil {
+		return nil, err
+	}
+	if err := inv.AddAdjustment(adj); err != nil {
+		return nil, err
+	}
+	if err := s.applyTaxes(ctx, inv); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.AddAdjustment(ctx, inv, adj); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Ajuste %d (%s) adicionado à fatura %d", adj.ID, adj.Kind, invoiceID)
+	return adj, nil
+}
+
+func (s *Service) RemoveAdjustment(ctx context.Context, invoiceID int, adjustmentID int) error {
+	inv, err := s.repo.GetByID(ctx, invoiceID)
+	if err != nil {
+		return err
+	}
+
+	adj, err := inv.RemoveAdjustment(adjustmentID)
+	if err != nil {
+		return err
+	}
+	if err := s.applyTaxes(ctx, inv); err != nil {
+		return err
+	}
+
+	if err := s.repo.RemoveAdjustment(ctx, inv, adj); err != nil {
+		return err
+	}
+
+	log.Printf("Ajuste %d removido da fatura %d", adjustmentID, invoiceID)
+	return nil
+}
@@ -0,0 +1,62 @@
+package invoice
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/boleto"
+	domaininvoice "github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/invoice"
+)
+
+var ErrBoletoDisabled = errors.New("boleto is not configured")
+
+// IssueBoleto generates a boleto for the outstanding balance of an issued
+// invoice. A zero dueDate means the configured number of days from today.
+func (s *Service) IssueBoleto(ctx context.Context, invoiceID int, dueDate time.Time) (*domaininvoice.Boleto, error) {
+	if s.beneficiary == nil {
+		return nil, ErrBoletoDisabled
+	}
+
+	today := time.Now()
+	if dueDate.IsZero() {
+		dueDate = today.AddDate(0, 0, s.boletoDueDays)
+	}
+	dueDate = time.Date(dueDate.Year(), dueDate.Month(), dueDate.Day(), 0, 0, 0, 0, time.UTC)
+	if dueDate.Format("2006-01-02") < today.Format("2006-01-02") {
+		return nil, boleto.ErrInvalidDueDate
+	}
+
+	inv, amount, err := s.AmountDue(ctx, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+
+	sequence, err := s.boletos.NextSequence(ctx)
+	if err != nil {
+		return nil, err
+	}
+	code, err := boleto.Generate(*s.beneficiary, sequence, dueDate, amount)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := inv.IssueBoleto(s.beneficiary.BankCode, sequence, code, dueDate, amount)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.boletos.Create(ctx, b, inv); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Boleto %s de %s emitido para a fatura %d", b.OurNumber, amount, invoiceID)
+	return b, nil
+}
+
+func (s *Service) ListBoletos(ctx context.Context, invoiceID int) ([]*domaininvoice.Boleto, error) {
+	if _, err := s.repo.GetByID(ctx, invoiceID); err != nil {
+		return nil, err
+	}
+	return s.boletos.ListByInvoice(ctx, invoiceID)
+}
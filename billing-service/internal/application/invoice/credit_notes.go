@@ -0,0 +1,90 @@
+package invoice
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	domaininvoice "github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/invoice"
+)
+
+// IssueCreditNote credits part or, with no lines, all of an issued invoice and
+// returns the credited units to inventory.
+func (s *Service) IssueCreditNote(ctx context.Context, invoiceID int, lines []domaininvoice.CreditLine, reason string) (*domaininvoice.CreditNote, error) {
+	inv, err := s.repo.GetByID(ctx, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+
+	previous, err := s.creditNotes.ListByInvoice(ctx, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+
+	note, err := inv.IssueCreditNote(lines, reasonOr(reason, "credit note"), previous)
+	if err != nil {
+		return nil, err
+	}
+
+	payments, err := s.payments.ListByInvoice(ctx, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	if err := inv.SettleCredit(note, payments, append(previous, note)); err != nil {
+		return nil, err
+	}
+
+	if err := s.creditNotes.Create(ctx, note, inv); err != nil {
+		return nil, err
+	}
+
+	s.returnStock(ctx, note)
+	return note, nil
+}
+
+func (s *Service) ListCreditNotes(ctx context.Context, invoiceID int) ([]*domaininvoice.CreditNote, error) {
+	if _, err := s.repo.GetByID(ctx, invoiceID); err != nil {
+		return nil, err
+	}
+	return s.creditNotes.ListByInvoice(ctx, invoiceID)
+}
+
+// ReturnPendingStock retries the stock returns of credit notes whose restock
+// call failed earlier.
+func (s *Service) ReturnPendingStock(ctx context.Context) error {
+	notes, err := s.creditNotes.ListPendingReturns(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, note := range notes {
+		log.Printf("Retomando devolução de estoque da nota de crédito %s", note.Number)
+		s.returnStock(ctx, note)
+	}
+	return nil
+}
+
+// returnStock restocks every line not returned yet. Lines that fail stay
+// pending for ReturnPendingStock; a line restocked whose MarkReturned failed
+// is sent again under the same key, which inventory ignores.
+func (s *Service) returnStock(ctx context.Context, note *domaininvoice.CreditNote) {
+	for _, item := range note.Items {
+		if item.Returned {
+			continue
+		}
+
+		if err := s.inventory.Restock(ctx, item.ProductID, item.Quantity, restockKey(item)); err != nil {
+			log.Printf("Falha ao devolver %d unidades do produto %d (nota de crédito %s): %v", item.Quantity, item.ProductID, note.Number, err)
+			continue
+		}
+
+		if err := s.creditNotes.MarkReturned(ctx, item); err != nil {
+			log.Printf("Falha ao registrar devolução do item %d da nota de crédito %s: %v", item.ID, note.Number, err)
+		}
+	}
+}
+
+// restockKey identifies the return of a credit note line to inventory.
+func restockKey(item *domaininvoice.CreditNoteItem) string {
+	return fmt.Sprintf("credit-note-item-%d", item.ID)
+}
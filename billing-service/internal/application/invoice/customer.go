@@ -0,0 +1,45 @@
+package invoice
+
+import (
+	"context"
+
+	domaininvoice "github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/invoice"
+)
+
+// freezeCustomer copies the current customer data onto the invoice so the
+// issued document no longer depends on the customer record.
+func (s *Service) freezeCustomer(ctx context.Context, inv *domaininvoice.Invoice) error {
+	if inv.CustomerID == 0 || inv.Customer != nil {
+		return nil
+	}
+
+	c, err := s.customers.GetByID(ctx, inv.CustomerID)
+	if err != nil {
+		return err
+	}
+
+	snapshot := &domaininvoice.CustomerSnapshot{
+		CustomerID:        c.ID,
+		Name:              c.Name,
+		Document:          c.Document,
+		DocumentType:      string(c.DocumentType),
+		Email:             c.Email,
+		StateRegistration: c.StateRegistration,
+	}
+	if a := c.BillingAddress(); a != nil {
+		snapshot.Address = &domaininvoice.CustomerAddress{
+			Street:     a.Street,
+			Number:     a.Number,
+			Complement: a.Complement,
+			District:   a.District,
+			CityCode:   a.CityCode,
+			City:       a.City,
+			UF:         a.UF,
+			CEP:        a.CEP,
+			Country:    a.Country,
+		}
+	}
+
+	inv.FreezeCustomer(snapshot)
+	return nil
+}
@@ -0,0 +1,41 @@
+package invoice
+
+import (
+	"context"
+	"log"
+
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/exchange"
+	domaininvoice "github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/invoice"
+	"github.com/vitorwhois/microservice-invoice-billing/money"
+)
+
+// convert prices an item of an open invoice at the current rate. The price
+// is only indicative until the rates are frozen when the invoice is printed.
+func (s *Service) convert(ctx context.Context, price money.Money, currency string) (money.Money, error) {
+	if price.Currency == currency {
+		return price, nil
+	}
+
+	rate, err := s.rates.Rate(ctx, price.Currency, currency)
+	if err != nil {
+		log.Printf("Cotação de %s para %s indisponível: %v", price.Currency, currency, err)
+		return money.Money{}, err
+	}
+	return rate.Convert(price)
+}
+
+// freezeRates looks up the current rate of every foreign currency the items
+// are quoted in and fixes the item prices and the rates on the invoice.
+func (s *Service) freezeRates(ctx context.Context, inv *domaininvoice.Invoice) error {
+	foreign := inv.ForeignCurrencies()
+	rates := make([]exchange.Rate, 0, len(foreign))
+	for _, currency := range foreign {
+		rate, err := s.rates.Rate(ctx, currency, inv.Currency)
+		if err != nil {
+			log.Printf("Cotação de %s para %s indisponível ao imprimir a fatura %d: %v", currency, inv.Currency, inv.ID, err)
+			return err
+		}
+		rates = append(rates, rate)
+	}
+	return inv.FreezeRates(rates)
+}
@@ -0,0 +1,70 @@
+package invoice
+
+import (
+	"context"
+	"log"
+	"time"
+
+	domaininvoice "github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/invoice"
+)
+
+// SetPaymentTerms changes the terms of an invoice that was not issued yet.
+func (s *Service) SetPaymentTerms(ctx context.Context, invoiceID int, terms domaininvoice.PaymentTerms) (*domaininvoice.Invoice, error) {
+	inv, err := s.repo.GetByID(ctx, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := inv.SetPaymentTerms(terms); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(ctx, inv); err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
+
+func (s *Service) ListOverdueInvoices(ctx context.Context) ([]*domaininvoice.Invoice, error) {
+	return s.repo.ListOverdue(ctx)
+}
+
+// MarkOverdueInstallments flags every pending installment that passed its due
+// date.
+func (s *Service) MarkOverdueInstallments(ctx context.Context, asOf time.Time) error {
+	invoices, err := s.repo.ListPastDue(ctx, asOf)
+	if err != nil {
+		return err
+	}
+
+	for _, inv := range invoices {
+		overdue := inv.MarkOverdue(asOf)
+		if len(overdue) == 0 {
+			continue
+		}
+		if err := s.repo.Update(ctx, inv); err != nil {
+			log.Printf("Falha ao marcar parcelas vencidas da fatura %d: %v", inv.ID, err)
+			continue
+		}
+		log.Printf("%d parcela(s) da fatura %d vencida(s)", len(overdue), inv.ID)
+	}
+	return nil
+}
+
+// RunOverdueCheck marks overdue installments at start and then on every
+// interval until ctx is done.
+func (s *Service) RunOverdueCheck(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.MarkOverdueInstallments(ctx, time.Now()); err != nil {
+			log.Printf("Falha ao verificar parcelas vencidas: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package invoice
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	domaininvoice "github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/invoice"
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/infrastructure/inventory"
+)
+
+// UpdateInvoiceItem changes the quantity of a line. The line's reservation is
+// resized before the line is saved and restored if saving fails, including
+// when another edit changed the line first (ErrConcurrentUpdate).
+func (s *Service) UpdateInvoiceItem(ctx context.Context, invoiceID int, itemID int, quantity int) error {
+	if quantity <= 0 {
+		return ErrInvalidQuantity
+	}
+
+	inv, err := s.repo.GetByID(ctx, invoiceID)
+	if err != nil {
+		return err
+	}
+
+	item, previous, err := inv.UpdateItemQuantity(itemID, quantity)
+	if err != nil {
+		return err
+	}
+
+	if quantity == previous {
+		return nil
+	}
+
+	if err := s.applyTaxes(ctx, inv); err != nil {
+		return err
+	}
+
+	holdID := item.ReservationID
+	if err := s.resizeHold(ctx, item, previous); err != nil {
+		return err
+	}
+
+	if err := s.repo.UpdateItem(ctx, inv, item, previous); err != nil {
+		s.restoreHold(ctx, item, previous, holdID)
+		return err
+	}
+
+	log.Printf("Item %d da fatura %d alterado de %d para %d unidades", itemID, invoiceID, previous, quantity)
+	return nil
+}
+
+// RemoveInvoiceItem deletes a line and releases the stock reserved for it.
+func (s *Service) RemoveInvoiceItem(ctx context.Context, invoiceID int, itemID int) error {
+	inv, err := s.repo.GetByID(ctx, invoiceID)
+	if err != nil {
+		return err
+	}
+
+	item, err := inv.RemoveItem(itemID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.applyTaxes(ctx, inv); err != nil {
+		return err
+	}
+
+	if err := s.repo.RemoveItem(ctx, inv, item); err != nil {
+		return err
+	}
+
+	s.releaseStock(ctx, invoiceID, item.ReservationID)
+
+	log.Printf("Item %d removido da fatura %d", itemID, invoiceID)
+	return nil
+}
+
+// resizeHold makes the line's reservation hold item.Quantity units instead of
+// previous. A hold that expired, or was released by a print that was rolled
+// back, is replaced by a new one.
+func (s *Service) resizeHold(ctx context.Context, item *domaininvoice.InvoiceItem, previous int) error {
+	if item.ReservationID != 0 {
+		err := s.inventory.ResizeReservation(ctx, item.ReservationID, item.Quantity, previous)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, inventory.ErrReservationInactive) && !errors.Is(err, inventory.ErrNotFound) {
+			log.Printf("Erro ao ajustar a reserva %d do produto %d: %v", item.ReservationID, item.ProductID, err)
+			return reservationError(err)
+		}
+	}
+
+	hold, err := s.inventory.Hold(ctx, item.ProductID, item.Quantity, holdOwner(item.InvoiceID))
+	if err != nil {
+		log.Printf("Erro ao reservar estoque para o produto %d: %v", item.ProductID, err)
+		return reservationError(err)
+	}
+	item.ReservationID = hold.ID
+	return nil
+}
+
+// restoreHold undoes resizeHold after the line could not be saved: a new
+// hold is cancelled, a resized one goes back to previous units.
+func (s *Service) restoreHold(ctx context.Context, item *domaininvoice.InvoiceItem, previous int, holdID int) {
+	if item.ReservationID != holdID {
+		s.releaseStock(ctx, item.InvoiceID, item.ReservationID)
+		item.ReservationID = holdID
+		return
+	}
+	if err := s.inventory.ResizeReservation(ctx, holdID, previous, item.Quantity); err != nil {
+		log.Printf("Falha ao restaurar a reserva %d da fatura %d: %v", holdID, item.InvoiceID, err)
+	}
+}
+
+// releaseStock cancels a reservation after the invoice has already been
+// changed. A failure only leaves stock held until the reservation expires, so
+// it is logged rather than returned.
+func (s *Service) releaseStock(ctx context.Context, invoiceID int, reservationID int) {
+	if reservationID == 0 {
+		return
+	}
+	if err := s.inventory.CancelReservation(ctx, reservationID); err != nil {
+		log.Printf("Falha ao liberar a reserva %d da fatura %d: %v", reservationID, invoiceID, err)
+	}
+}
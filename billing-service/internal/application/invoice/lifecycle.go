@@ -0,0 +1,99 @@
+package invoice
+
+import (
+	"context"
+	"time"
+
+	domaininvoice "github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/invoice"
+)
+
+func (s *Service) OpenInvoice(ctx context.Context, invoiceID int) (*domaininvoice.Invoice, error) {
+	return s.transition(ctx, invoiceID, func(inv *domaininvoice.Invoice) error {
+		return inv.Open()
+	})
+}
+
+// CancelInvoice cancels an invoice. Stock still held for an unprinted invoice
+// is released; an issued invoice can only be cancelled within the configured
+// window and is fully credited, returning its stock to inventory.
+func (s *Service) CancelInvoice(ctx context.Context, invoiceID int, reason string) (*domaininvoice.Invoice, error) {
+	inv, err := s.repo.GetByID(ctx, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+
+	reason = reasonOr(reason, "cancelled")
+	if err := inv.EnsureCancellable(time.Now(), s.cancellationWindow); err != nil {
+		return nil, err
+	}
+
+	held := inv.IsEditable()
+
+	var note *domaininvoice.CreditNote
+	if inv.IsIssued() {
+		previous, err := s.creditNotes.ListByInvoice(ctx, invoiceID)
+		if err != nil {
+			return nil, err
+		}
+		note, err = inv.IssueCreditNote(nil, reason, previous)
+		if err != nil && err != domaininvoice.ErrNothingToCredit {
+			return nil, err
+		}
+	}
+
+	if err := inv.Cancel(reason); err != nil {
+		return nil, err
+	}
+
+	if note != nil {
+		if err := s.creditNotes.Create(ctx, note, inv); err != nil {
+			return nil, err
+		}
+		s.returnStock(ctx, note)
+	} else if err := s.repo.Update(ctx, inv); err != nil {
+		return nil, err
+	}
+
+	if held {
+		for _, item := range inv.Items {
+			s.releaseStock(ctx, invoiceID, item.ReservationID)
+		}
+	}
+	return inv, nil
+}
+
+func (s *Service) VoidInvoice(ctx context.Context, invoiceID int, reason string) (*domaininvoice.Invoice, error) {
+	return s.transition(ctx, invoiceID, func(inv *domaininvoice.Invoice) error {
+		return inv.Void(reasonOr(reason, "voided"))
+	})
+}
+
+func (s *Service) ListTransitions(ctx context.Context, invoiceID int) ([]*domaininvoice.Transition, error) {
+	if _, err := s.repo.GetByID(ctx, invoiceID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListTransitions(ctx, invoiceID)
+}
+
+func (s *Service) transition(ctx context.Context, invoiceID int, apply func(*domaininvoice.Invoice) error) (*domaininvoice.Invoice, error) {
+	inv, err := s.repo.GetByID(ctx, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := apply(inv); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Update(ctx, inv); err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
+
+func reasonOr(reason, fallback string) string {
+	if reason == "" {
+		return fallback
+	}
+	return reason
+}
@@ -0,0 +1,122 @@
+package invoice
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	domaininvoice "github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/invoice"
+	"github.com/vitorwhois/microservice-invoice-billing/money"
+)
+
+// PaymentSummary lists the payments of an invoice with the resulting balance.
+type PaymentSummary struct {
+	InvoiceID   int                      `json:"invoice_id"`
+	Status      domaininvoice.Status     `json:"status"`
+	Currency    string                   `json:"currency"`
+	TotalValue  money.Money              `json:"total_value"`
+	Credited    money.Money              `json:"credited"`
+	AmountPaid  money.Money              `json:"amount_paid"`
+	Outstanding money.Money              `json:"outstanding"`
+	Overpaid    money.Money              `json:"overpaid"`
+	Payments    []*domaininvoice.Payment `json:"payments"`
+}
+
+// RecordPayment registers money received for an issued invoice, paid being
+// in the currency of the invoice. A zero paidAt means now.
+func (s *Service) RecordPayment(ctx context.Context, invoiceID int, method domaininvoice.PaymentMethod, paid money.Literal, paidAt time.Time, reference string) (*PaymentSummary, error) {
+	inv, err := s.repo.GetByID(ctx, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	amount, err := paid.In(inv.Currency)
+	if err != nil {
+		return nil, domaininvoice.ErrInvalidPayment
+	}
+
+	previous, err := s.payments.ListByInvoice(ctx, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	credits, err := s.creditNotes.ListByInvoice(ctx, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if paidAt.IsZero() {
+		paidAt = time.Now()
+	}
+	payment, err := inv.RecordPayment(method, amount, paidAt, reference, previous, credits)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.payments.Create(ctx, payment, inv); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Pagamento de %s registrado na fatura %d (%s)", amount, invoiceID, inv.Status)
+	return s.paymentSummary(inv, append(previous, payment), credits), nil
+}
+
+func (s *Service) ListPayments(ctx context.Context, invoiceID int) (*PaymentSummary, error) {
+	inv, err := s.repo.GetByID(ctx, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+
+	payments, err := s.payments.ListByInvoice(ctx, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	credits, err := s.creditNotes.ListByInvoice(ctx, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	return s.paymentSummary(inv, payments, credits), nil
+}
+
+func (s *Service) paymentSummary(inv *domaininvoice.Invoice, payments []*domaininvoice.Payment, credits []*domaininvoice.CreditNote) *PaymentSummary {
+	balance := inv.Balance(payments, credits)
+	return &PaymentSummary{
+		InvoiceID:   inv.ID,
+		Status:      inv.Status,
+		Currency:    inv.Currency,
+		TotalValue:  balance.TotalValue,
+		Credited:    balance.Credited,
+		AmountPaid:  balance.AmountPaid,
+		Outstanding: balance.Outstanding,
+		Overpaid:    balance.Overpaid,
+		Payments:    payments,
+	}
+}
+
+// AmountDue returns the invoice with what is still left to pay on it. Only
+// issued invoices with an outstanding balance can be charged.
+func (s *Service) AmountDue(ctx context.Context, invoiceID int) (*domaininvoice.Invoice, money.Money, error) {
+	inv, err := s.repo.GetByID(ctx, invoiceID)
+	if err != nil {
+		return nil, money.Money{}, err
+	}
+	if inv.Status == domaininvoice.StatusPaid {
+		return nil, money.Money{}, domaininvoice.ErrAlreadyPaid
+	}
+	if inv.Status != domaininvoice.StatusIssued && inv.Status != domaininvoice.StatusPartiallyPaid {
+		return nil, money.Money{}, fmt.Errorf("%w: only issued invoices can be charged", domaininvoice.ErrInvalidStatus)
+	}
+
+	payments, err := s.payments.ListByInvoice(ctx, invoiceID)
+	if err != nil {
+		return nil, money.Money{}, err
+	}
+	credits, err := s.creditNotes.ListByInvoice(ctx, invoiceID)
+	if err != nil {
+		return nil, money.Money{}, err
+	}
+	outstanding := inv.Balance(payments, credits).Outstanding
+	if !outstanding.IsPositive() {
+		return nil, money.Money{}, domaininvoice.ErrAlreadyPaid
+	}
+	return inv, outstanding, nil
+}
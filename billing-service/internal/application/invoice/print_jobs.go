@@ -0,0 +1,238 @@
+package invoice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	domaininvoice "github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/invoice"
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/printjob"
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/saga"
+)
+
+// PrintQueue runs print sagas in the background, so a slow inventory service
+// cannot outlive the HTTP request that asked for the print. Jobs are kept in
+// the database and claimed by a pool of workers, which hold each job under a
+// lease they renew while it runs. A job is claimed at most maxAttempts times;
+// zero means no limit.
+type PrintQueue struct {
+	service     *Service
+	jobs        printjob.Repository
+	workers     int
+	poll        time.Duration
+	lease       time.Duration
+	maxAttempts int
+	wake        chan struct{}
+}
+
+func NewPrintQueue(service *Service, jobs printjob.Repository, workers int, poll, lease time.Duration, maxAttempts int) *PrintQueue {
+	return &PrintQueue{
+		service:     service,
+		jobs:        jobs,
+		workers:     workers,
+		poll:        poll,
+		lease:       lease,
+		maxAttempts: maxAttempts,
+		wake:        make(chan struct{}, workers),
+	}
+}
+
+// Submit queues a print of the invoice. While a job for it is queued or
+// running, that job is returned instead of a new one.
+func (q *PrintQueue) Submit(ctx context.Context, invoiceID int) (*printjob.Job, error) {
+	inv, err := q.service.GetInvoiceByID(ctx, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	if inv.IsIssued() {
+		return nil, domaininvoice.ErrAlreadyClosed
+	}
+
+	job, err := q.jobs.FindUnfinishedByInvoice(ctx, invoiceID)
+	if err == nil {
+		return job, nil
+	}
+	if err != printjob.ErrNotFound {
+		return nil, err
+	}
+
+	job = printjob.NewJob(invoiceID)
+	if err := q.jobs.Create(ctx, job); err == printjob.ErrAlreadyQueued {
+		// Submitted concurrently: hand back the job that won
+		return q.jobs.FindUnfinishedByInvoice(ctx, invoiceID)
+	} else if err != nil {
+		return nil, err
+	}
+	log.Printf("Impressão da fatura %d enfileirada (job %d)", invoiceID, job.ID)
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return job, nil
+}
+
+func (q *PrintQueue) Get(ctx context.Context, id int) (*printjob.Job, error) {
+	return q.jobs.Get(ctx, id)
+}
+
+// Run works the queue until ctx is done.
+func (q *PrintQueue) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for n := 0; n < q.workers; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.work(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+// work runs queued jobs one after the other, waiting for a new submission or
+// the next poll when there are none. Jobs whose worker died, on this instance
+// before a restart or on another one, are queued again once their lease
+// expires; jobs still held by a live worker are left alone.
+func (q *PrintQueue) work(ctx context.Context) {
+	ticker := time.NewTicker(q.poll)
+	defer ticker.Stop()
+
+	for {
+		if n, err := q.jobs.RequeueExpired(ctx, time.Now()); err != nil {
+			log.Printf("Falha ao retomar impressões interrompidas: %v", err)
+		} else if n > 0 {
+			log.Printf("%d impressão(ões) interrompida(s) reenfileirada(s)", n)
+		}
+
+		for {
+			now := time.Now()
+			job, err := q.jobs.Claim(ctx, now, now.Add(q.lease))
+			if err == printjob.ErrNotFound {
+				break
+			}
+			if err != nil {
+				log.Printf("Falha ao buscar impressões pendentes: %v", err)
+				break
+			}
+			q.process(ctx, job)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-q.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+func (q *PrintQueue) process(ctx context.Context, job *printjob.Job) {
+	if q.exhausted(job.Attempts - 1) {
+		// Its worker died on the last attempt allowed: the job is settled from
+		// the invoice without printing again
+		q.settle(ctx, job, nil, errors.New("lease expired"))
+	} else {
+		log.Printf("Executando impressão da fatura %d (job %d, tentativa %d)", job.InvoiceID, job.ID, job.Attempts)
+
+		stop := q.keepLease(ctx, job)
+		result, err := q.service.PrintInvoice(ctx, job.InvoiceID)
+		stop()
+
+		q.settle(ctx, job, result, err)
+	}
+
+	if err := q.jobs.Release(ctx, job); err == printjob.ErrNotFound {
+		log.Printf("Impressão %d retomada por outro worker, resultado descartado", job.ID)
+	} else if err != nil {
+		log.Printf("Falha ao registrar o resultado da impressão %d: %v", job.ID, err)
+	}
+}
+
+// settle decides the outcome of the job from the invoice, which follows the
+// print saga, rather than from the error of this attempt alone. An issued
+// invoice was printed, if need be by an earlier attempt or by the recovery
+// of its saga. A PRINTING invoice has a saga still to be resumed or
+// compensated: the job is tried again once the lease is over, which is also
+// the lease of the saga, so that attempt can take the saga over, unless it
+// has run out of attempts.
+func (q *PrintQueue) settle(ctx context.Context, job *printjob.Job, result *InvoiceProcessResult, err error) {
+	if err == nil {
+		job.Succeed(result.StepReached)
+		return
+	}
+
+	stepReached, reason := "", err.Error()
+	recovery := printjob.Recovery{}
+	if result != nil {
+		stepReached = result.StepReached
+		if result.FailedReason != "" {
+			reason = result.FailedReason
+		}
+		recovery = printjob.Recovery{
+			Attempted:  result.Recovery.Attempted,
+			Successful: result.Recovery.Successful,
+			Message:    result.Recovery.Message,
+			Details:    result.Recovery.Details,
+		}
+	}
+
+	inv, ierr := q.service.GetInvoiceByID(ctx, job.InvoiceID)
+	switch {
+	case ierr == nil && inv.IsIssued():
+		job.Succeed(saga.ActionCloseInvoice.Phase())
+	case ierr == nil && inv.Status == domaininvoice.StatusPrinting && q.exhausted(job.Attempts):
+		q.giveUp(ctx, job, stepReached, reason, recovery)
+	case ierr == nil && inv.Status == domaininvoice.StatusPrinting:
+		log.Printf("Impressão da fatura %d pendente (tentativa %d), nova tentativa em %s: %s", job.InvoiceID, job.Attempts, q.lease, reason)
+		job.Retry(stepReached, reason, time.Now().Add(q.lease))
+	default:
+		job.Fail(stepReached, reason, recovery)
+	}
+}
+
+// exhausted reports whether a job claimed attempts times may not be tried
+// again.
+func (q *PrintQueue) exhausted(attempts int) bool {
+	return q.maxAttempts > 0 && attempts >= q.maxAttempts
+}
+
+// giveUp fails a job that ran out of attempts and records the failure on the
+// invoice. The invoice is left PRINTING: its saga is still recovered on the
+// next restart, or has to be settled by hand.
+func (q *PrintQueue) giveUp(ctx context.Context, job *printjob.Job, stepReached, reason string, recovery printjob.Recovery) {
+	reason = fmt.Sprintf("gave up after %d attempts: %s", q.maxAttempts, reason)
+	log.Printf("Impressão da fatura %d abandonada (job %d): %s", job.InvoiceID, job.ID, reason)
+	job.Fail(stepReached, reason, recovery)
+	q.service.recordPrintFailure(ctx, job.InvoiceID, stepReached, reason)
+}
+
+// keepLease renews the lease of job until the returned function is called.
+func (q *PrintQueue) keepLease(ctx context.Context, job *printjob.Job) func() {
+	id, attempt := job.ID, job.Attempts
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(q.lease / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := q.jobs.RenewLease(ctx, id, attempt, time.Now().Add(q.lease)); err != nil {
+					log.Printf("Falha ao renovar a impressão %d: %v", id, err)
+				}
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
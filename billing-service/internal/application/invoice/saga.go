@@ -0,0 +1,351 @@
+package invoice
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	domaininvoice "github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/invoice"
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/saga"
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/infrastructure/inventory"
+)
+
+func newProcessResult(invoiceID int) *InvoiceProcessResult {
+	return &InvoiceProcessResult{
+		Success:      false,
+		InvoiceID:    invoiceID,
+		StepReached:  "started",
+		FailedReason: "",
+		Recovery:     RecoveryDetails{Attempted: false, Successful: false},
+	}
+}
+
+// runPrintSaga executes every step that is not done yet, persisting each
+// transition before and after talking to the inventory service.
+func (s *Service) runPrintSaga(ctx context.Context, sg *saga.Saga, result *InvoiceProcessResult) (*InvoiceProcessResult, error) {
+	for _, step := range sg.Steps {
+		if step.Status == saga.StepDone {
+			continue
+		}
+
+		result.StepReached = step.Action.Phase()
+		sg.StepReached = result.StepReached
+		s.saveSaga(ctx, sg)
+
+		step.Status = saga.StepStarted
+		step.Error = ""
+		s.saveStep(ctx, step)
+
+		if err := s.executeStep(ctx, sg, step); err != nil {
+			step.Status = saga.StepFailed
+			step.Error = err.Error()
+			s.saveStep(ctx, step)
+
+			result.FailedReason = describeFailure(step, err)
+			sg.FailedReason = result.FailedReason
+			s.recordPrintFailure(ctx, sg.InvoiceID, result.StepReached, result.FailedReason)
+
+			if sg.PastPivot() {
+				// Stock was already consumed, so the saga can only move forward.
+				// It stays RUNNING and is resumed on the next attempt or restart.
+				result.Recovery.Message = "Stock already confirmed; the print will be resumed"
+				s.saveSaga(ctx, sg)
+				return result, err
+			}
+
+			s.compensatePrintSaga(ctx, sg, result)
+			return result, stepError(step, err)
+		}
+
+		step.Status = saga.StepDone
+		s.saveStep(ctx, step)
+	}
+
+	sg.Status = saga.StatusCompleted
+	sg.FailedReason = ""
+	s.saveSaga(ctx, sg)
+
+	result.Success = true
+	result.FailedReason = ""
+	return result, nil
+}
+
+// compensatePrintSaga undoes the reservations made by the saga. Steps whose
+// outcome is unknown (interrupted while talking to inventory) are reported but
+// left alone, since cancelling a reservation that never happened would release
+// somebody else's stock.
+func (s *Service) compensatePrintSaga(ctx context.Context, sg *saga.Saga, result *InvoiceProcessResult) {
+	result.Recovery.Attempted = true
+	result.Recovery.Message = "Attempting to cancel existing reservations"
+
+	ok := true
+	for _, step := range sg.PendingCompensations() {
+		result.Recovery.Details = append(result.Recovery.Details,
+			fmt.Sprintf("Canceling reservation batch %d", step.ReservationID))
+
+		if err := s.compensateStep(ctx, step); err != nil {
+			log.Printf("Falha ao compensar passo %d da saga %d: %v", step.Seq, sg.ID, err)
+			result.Recovery.Details = append(result.Recovery.Details,
+				fmt.Sprintf("Failed to cancel reservation batch %d: %v", step.ReservationID, err))
+			ok = false
+			continue
+		}
+
+		step.Status = saga.StepCompensated
+		s.saveStep(ctx, step)
+	}
+
+	for _, step := range sg.Steps {
+		if step.Status == saga.StepStarted && step.Compensation != "" {
+			result.Recovery.Details = append(result.Recovery.Details,
+				fmt.Sprintf("Outcome unknown for %s; the reservation expires on its own", step.Action))
+		}
+	}
+
+	result.Recovery.Successful = ok
+	if ok {
+		// Otherwise the saga stays RUNNING and compensation is retried later
+		sg.Status = saga.StatusCompensated
+	}
+	s.saveSaga(ctx, sg)
+
+	if ok {
+		s.reopenInvoice(ctx, sg.InvoiceID, sg.FailedReason)
+	}
+}
+
+func (s *Service) resumePrintSaga(ctx context.Context, sg *saga.Saga) (*InvoiceProcessResult, error) {
+	result := newProcessResult(sg.InvoiceID)
+	result.Recovery.Attempted = true
+	result.Recovery.Message = fmt.Sprintf("Resuming interrupted print saga %d", sg.ID)
+
+	result, err := s.runPrintSaga(ctx, sg, result)
+	result.Recovery.Successful = err == nil
+	return result, err
+}
+
+// RecoverPrintSagas settles every print saga left unfinished by a previous
+// process: sagas that already consumed stock are driven to completion, the
+// others are compensated. Sagas saved within the print lease are left alone.
+func (s *Service) RecoverPrintSagas(ctx context.Context) error {
+	sagas, err := s.sagas.ListUnfinished(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, sg := range sagas {
+		if time.Since(sg.LastActivity()) < s.printLease {
+			// Possibly still running on another instance; the next print
+			// attempt after the lease settles it
+			log.Printf("Saga %d ainda ativa, recuperação adiada", sg.ID)
+			continue
+		}
+
+		inv, err := s.repo.GetByID(ctx, sg.InvoiceID)
+		if err != nil {
+			log.Printf("Falha ao carregar a fatura da saga %d: %v", sg.ID, err)
+			continue
+		}
+		if err := s.takeOverPrint(ctx, inv); err != nil {
+			// Another instance got to it first
+			log.Printf("Saga %d não assumida: %v", sg.ID, err)
+			continue
+		}
+
+		if sg.PastPivot() {
+			log.Printf("Retomando saga de impressão %d da fatura %d", sg.ID, sg.InvoiceID)
+			if _, err := s.resumePrintSaga(ctx, sg); err != nil {
+				log.Printf("Falha ao retomar saga %d: %v", sg.ID, err)
+			}
+			continue
+		}
+
+		log.Printf("Compensando saga de impressão %d da fatura %d", sg.ID, sg.InvoiceID)
+		result := newProcessResult(sg.InvoiceID)
+		if sg.FailedReason == "" {
+			sg.FailedReason = "interrupted before stock confirmation"
+		}
+		s.compensatePrintSaga(ctx, sg, result)
+		if !result.Recovery.Successful {
+			log.Printf("Compensação incompleta para a saga %d: %v", sg.ID, result.Recovery.Details)
+		}
+	}
+	return nil
+}
+
+func (s *Service) executeStep(ctx context.Context, sg *saga.Saga, step *saga.Step) error {
+	switch step.Action {
+	case saga.ActionReserveBatch:
+		return s.reserveBatch(ctx, sg.InvoiceID, step)
+	case saga.ActionConfirmBatch:
+		id := sg.BatchID()
+		log.Printf("Confirmando o lote de reservas %d da fatura %d", id, sg.InvoiceID)
+		return s.inventory.ConfirmBatch(ctx, id)
+	case saga.ActionCloseInvoice:
+		return s.closeInvoice(ctx, sg.InvoiceID)
+	}
+	return fmt.Errorf("unknown saga action %q", step.Action)
+}
+
+// reserveBatch reserves the stock of every line of the invoice, which is
+// frozen while it prints. The holds taken when the lines were added are
+// handed over to the batch rather than reserved a second time.
+//
+// The customer and the exchange rates are frozen first and the taxes and
+// totals computed from them: a missing rate, a failed customer lookup or a
+// discount that no longer fits must fail the print while it can still be
+// rolled back. The stock is confirmed at these totals, so nothing after the
+// pivot changes them.
+func (s *Service) reserveBatch(ctx context.Context, invoiceID int, step *saga.Step) error {
+	inv, err := s.repo.GetByID(ctx, invoiceID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.freezeCustomer(ctx, inv); err != nil {
+		return err
+	}
+	if err := s.freezeRates(ctx, inv); err != nil {
+		return err
+	}
+	// The buyer's location is final now, so the taxes are too
+	if err := s.applyTaxes(ctx, inv); err != nil {
+		return err
+	}
+	if err := s.repo.SaveTotals(ctx, inv); err != nil {
+		return err
+	}
+	if err := s.repo.Update(ctx, inv); err != nil {
+		return err
+	}
+
+	lines := make([]inventory.BatchLine, 0, len(inv.Items))
+	for _, item := range inv.Items {
+		lines = append(lines, inventory.BatchLine{
+			ProductID: item.ProductID,
+			Quantity:  item.Quantity,
+			HoldID:    item.ReservationID,
+		})
+	}
+
+	log.Printf("Reservando estoque de %d itens da fatura %d", len(lines), invoiceID)
+	b, err := s.inventory.ReserveBatch(ctx, lines, printOwner(invoiceID), 0)
+	if err != nil {
+		return err
+	}
+	step.ReservationID = b.ID
+	return nil
+}
+
+func (s *Service) compensateStep(ctx context.Context, step *saga.Step) error {
+	switch step.Compensation {
+	case saga.ActionCancelBatch:
+		log.Printf("Cancelando o lote de reservas %d", step.ReservationID)
+		return s.inventory.CancelBatch(ctx, step.ReservationID)
+	}
+	return fmt.Errorf("unknown compensation %q", step.Compensation)
+}
+
+// printOwner and holdOwner tag the reservations billing takes in inventory.
+func printOwner(invoiceID int) string {
+	return fmt.Sprintf("invoice-%d-print", invoiceID)
+}
+
+func holdOwner(invoiceID int) string {
+	return fmt.Sprintf("invoice-%d", invoiceID)
+}
+
+// closeInvoice issues the invoice at the totals computed before the stock was
+// confirmed. It runs past the pivot, where nothing can be rolled back, so it
+// does no lookup that could fail for good.
+func (s *Service) closeInvoice(ctx context.Context, invoiceID int) error {
+	inv, err := s.repo.GetByID(ctx, invoiceID)
+	if err != nil {
+		return err
+	}
+
+	// Already closed by an earlier attempt that crashed right after the update
+	if inv.IsIssued() {
+		return nil
+	}
+
+	// Sagas started before the PRINTING status existed left the invoice OPEN
+	if inv.Status == domaininvoice.StatusOpen {
+		if err := inv.StartPrinting(); err != nil {
+			return err
+		}
+	}
+
+	if err := inv.Close(); err != nil {
+		return err
+	}
+	return s.repo.Update(ctx, inv)
+}
+
+func (s *Service) recordPrintFailure(ctx context.Context, invoiceID int, stepReached string, reason string) {
+	inv, err := s.repo.GetByID(ctx, invoiceID)
+	if err != nil {
+		log.Printf("Falha ao registrar erro de impressão da fatura %d: %v", invoiceID, err)
+		return
+	}
+
+	inv.PrintFailed(stepReached, reason)
+	if err := s.repo.Update(ctx, inv); err != nil {
+		log.Printf("Falha ao registrar erro de impressão da fatura %d: %v", invoiceID, err)
+	}
+}
+
+// reopenInvoice returns an invoice to OPEN once its print has been rolled back.
+func (s *Service) reopenInvoice(ctx context.Context, invoiceID int, reason string) {
+	inv, err := s.repo.GetByID(ctx, invoiceID)
+	if err != nil {
+		log.Printf("Falha ao reabrir a fatura %d: %v", invoiceID, err)
+		return
+	}
+	if inv.Status != domaininvoice.StatusPrinting {
+		return
+	}
+
+	if err := inv.AbortPrinting(reasonOr(reason, "print rolled back")); err != nil {
+		log.Printf("Falha ao reabrir a fatura %d: %v", invoiceID, err)
+		return
+	}
+	if err := s.repo.Update(ctx, inv); err != nil {
+		log.Printf("Falha ao reabrir a fatura %d: %v", invoiceID, err)
+	}
+}
+
+func (s *Service) saveSaga(ctx context.Context, sg *saga.Saga) {
+	if err := s.sagas.Update(ctx, sg); err != nil {
+		log.Printf("Falha ao registrar saga %d: %v", sg.ID, err)
+	}
+}
+
+func (s *Service) saveStep(ctx context.Context, step *saga.Step) {
+	if err := s.sagas.UpdateStep(ctx, step); err != nil {
+		log.Printf("Falha ao registrar passo %d da saga %d: %v", step.Seq, step.SagaID, err)
+	}
+}
+
+func describeFailure(step *saga.Step, err error) string {
+	switch step.Action {
+	case saga.ActionReserveBatch:
+		return fmt.Sprintf("Failed to reserve stock: %v", err)
+	case saga.ActionConfirmBatch:
+		return fmt.Sprintf("Failed to confirm stock: %v", err)
+	case saga.ActionCloseInvoice:
+		return fmt.Sprintf("Failed to close invoice: %v", err)
+	}
+	return err.Error()
+}
+
+func stepError(step *saga.Step, err error) error {
+	switch step.Action {
+	case saga.ActionReserveBatch:
+		return reservationError(err)
+	case saga.ActionConfirmBatch:
+		return ErrStockConfirmation
+	}
+	return err
+}
@@ -1,29 +1,42 @@
 package invoice
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"errors"
-	"fmt"
-	"io"
 	"log"
-	"net/http"
+	"strings"
+	"time"
 
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/boleto"
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/customer"
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/exchange"
 	domaininvoice "github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/invoice"
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/numbering"
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/saga"
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/tax"
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/infrastructure/inventory"
 )
 
 type Service struct {
-	repo                domaininvoice.Repository
-	inventoryServiceURL string
+	repo               domaininvoice.Repository
+	sagas              saga.Repository
+	creditNotes        domaininvoice.CreditNoteRepository
+	payments           domaininvoice.PaymentRepository
+	boletos            domaininvoice.BoletoRepository
+	customers          customer.Repository
+	rates              exchange.Provider
+	taxes              *tax.Engine
+	taxClasses         tax.ClassRepository
+	series             numbering.Repository
+	defaultSeries      string
+	defaultTerms       domaininvoice.PaymentTerms
+	beneficiary        *boleto.Beneficiary
+	boletoDueDays      int
+	inventory          *inventory.Client
+	cancellationWindow time.Duration
+	printLease         time.Duration
 }
 
-type ProductResponse struct {
-	ID    int     `json:"id"`
-	Name  string  `json:"name"`
-	Price float64 `json:"price"`
-	Stock int     `json:"stock"`
-}
 type RecoveryDetails struct {
 	Attempted  bool     `json:"attempted"`
 	Successful bool     `json:"successful"`
@@ -48,16 +61,70 @@ var (
 	ErrInvalidQuantity   = errors.New("invalid quantity")
 )
 
-func NewInvoiceService(repo domaininvoice.Repository, inventoryURL string) *Service {
+func NewInvoiceService(repo domaininvoice.Repository, sagas saga.Repository, creditNotes domaininvoice.CreditNoteRepository, payments domaininvoice.PaymentRepository, boletos domaininvoice.BoletoRepository, customers customer.Repository, rates exchange.Provider, taxes *tax.Engine, taxClasses tax.ClassRepository, series numbering.Repository, defaultSeries string, defaultTerms domaininvoice.PaymentTerms, beneficiary *boleto.Beneficiary, boletoDueDays int, inventoryClient *inventory.Client, cancellationWindow, printLease time.Duration) *Service {
 	return &Service{
-		repo:                repo,
-		inventoryServiceURL: inventoryURL,
+		repo:               repo,
+		sagas:              sagas,
+		creditNotes:        creditNotes,
+		payments:           payments,
+		boletos:            boletos,
+		customers:          customers,
+		rates:              rates,
+		taxes:              taxes,
+		taxClasses:         taxClasses,
+		series:             series,
+		defaultSeries:      defaultSeries,
+		defaultTerms:       defaultTerms,
+		beneficiary:        beneficiary,
+		boletoDueDays:      boletoDueDays,
+		inventory:          inventoryClient,
+		cancellationWindow: cancellationWindow,
+		printLease:         printLease,
 	}
 }
 
-func (s *Service) CreateInvoice(ctx context.Context, number string) (*domaininvoice.Invoice, error) {
-	inv := domaininvoice.NewInvoice(number)
-	if err := s.repo.Create(ctx, inv); err != nil {
+// CreateInput describes a new invoice. Number is optional: when empty the next
+// number of the series is generated, otherwise it must follow the series
+// format. Empty Series and PaymentTerms use the default ones and an empty
+// Currency means BRL.
+type CreateInput struct {
+	Number       string
+	Series       string
+	Draft        bool
+	CustomerID   int
+	Currency     string
+	PaymentTerms domaininvoice.PaymentTerms
+}
+
+func (s *Service) CreateInvoice(ctx context.Context, in CreateInput) (*domaininvoice.Invoice, error) {
+	if in.CustomerID != 0 {
+		if _, err := s.customers.GetByID(ctx, in.CustomerID); err != nil {
+			return nil, err
+		}
+	}
+
+	code := in.Series
+	if code == "" {
+		code = s.defaultSeries
+	}
+	series, err := s.series.Get(ctx, strings.ToUpper(code))
+	if err != nil {
+		return nil, err
+	}
+
+	inv := domaininvoice.NewInvoice(in.Number)
+	if in.Draft {
+		inv = domaininvoice.NewDraftInvoice(in.Number)
+	}
+	inv.CustomerID = in.CustomerID
+	if err := inv.SetCurrency(strings.ToUpper(in.Currency)); err != nil {
+		return nil, err
+	}
+	inv.PaymentTerms = in.PaymentTerms
+	if len(inv.PaymentTerms) == 0 {
+		inv.PaymentTerms = s.defaultTerms
+	}
+	if err := s.repo.Create(ctx, inv, series); err != nil {
 		return nil, err
 	}
 	return inv, nil
@@ -78,42 +145,43 @@ func (s *Service) AddInvoiceItem(ctx context.Context, invoiceID int, productID i
 		return err
 	}
 
-	if inv.Status == domaininvoice.StatusClosed {
-		return domaininvoice.ErrAlreadyClosed
+	if err := inv.EnsureEditable(); err != nil {
+		return err
 	}
 
 	product, err := s.getProductFromInventory(ctx, productID, quantity)
 	if err != nil {
-		log.Printf("Produto %d nao encontrado", productID)
-		return ErrProductNotFound
+		log.Printf("Produto %d indisponível: %v", productID, err)
+		return err
 	}
 
-	err = s.reserveStock(ctx, productID, quantity)
+	price, err := s.convert(ctx, product.Price, inv.Currency)
 	if err != nil {
-		log.Printf("Erro ao reservar estoque para o produto %d", productID)
-		return ErrStockReservation
-	}
-
-	item := &domaininvoice.InvoiceItem{
-		InvoiceID: invoiceID,
-		ProductID: productID,
-		Quantity:  quantity,
-		Price:     product.Price,
-		Name:      product.Name,
+		return err
 	}
 
-	if err := s.repo.AddItem(ctx, item); err != nil {
+	item := inv.AddItem(productID, quantity, price, product.Name)
+	item.InvoiceID = invoiceID
+	item.BasePrice = product.Price
+	if err := s.applyTaxes(ctx, inv); err != nil {
 		return err
 	}
 
-	inv, err = s.repo.GetByID(ctx, invoiceID)
+	hold, err := s.inventory.Hold(ctx, productID, quantity, holdOwner(invoiceID))
 	if err != nil {
+		log.Printf("Erro ao reservar estoque para o produto %d: %v", productID, err)
+		return reservationError(err)
+	}
+	item.ReservationID = hold.ID
+
+	// The invoice may have changed or left DRAFT/OPEN since it was read
+	if err := s.repo.AddItem(ctx, inv, item); err != nil {
+		s.releaseStock(ctx, invoiceID, hold.ID)
 		return err
 	}
 
 	log.Printf("Item %d adicionado ao invoice %d", productID, invoiceID)
-
-	return s.repo.Update(ctx, inv)
+	return nil
 }
 
 func (s *Service) PrintInvoice(ctx context.Context, invoiceID int) (*InvoiceProcessResult, error) {
@@ -124,202 +192,96 @@ func (s *Service) PrintInvoice(ctx context.Context, invoiceID int) (*InvoiceProc
 		return nil, err
 	}
 
-	if inv.Status == domaininvoice.StatusClosed {
-		return nil, domaininvoice.ErrAlreadyClosed
-	}
-
-	// Result object to track what happened during processing
-	result := &InvoiceProcessResult{
-		Success:      false,
-		InvoiceID:    invoiceID,
-		StepReached:  "started",
-		FailedReason: "",
-		Recovery:     RecoveryDetails{Attempted: false, Successful: false},
+	// A previous run may have crashed mid-flight: settle it before starting
+	// over, unless it is still making progress somewhere else
+	pending, err := s.sagas.FindUnfinishedByInvoice(ctx, invoiceID)
+	if err != nil && err != saga.ErrNotFound {
+		return nil, err
 	}
-
-	// Start transaction Saga
-	reservedItems := make(map[int]int)
-
-	// Step 1: Reserve stock for all items
-	result.StepReached = "stock_reservation"
-	for _, item := range inv.Items {
-		if err := s.reserveStock(ctx, item.ProductID, item.Quantity); err != nil {
-			// Compensating transaction: Cancel all reservations
-			result.FailedReason = fmt.Sprintf("Failed to reserve stock for product %d: %v", item.ProductID, err)
-			result.Recovery.Attempted = true
-			result.Recovery.Message = "Attempting to cancel existing reservations"
-
-			// Cancel previous reservations
-			for prodID, qty := range reservedItems {
-				result.Recovery.Details = append(result.Recovery.Details,
-					fmt.Sprintf("Canceling reservation for product %d, quantity %d", prodID, qty))
-				s.cancelReservation(ctx, prodID, qty)
-			}
-
-			result.Recovery.Successful = true
-			return result, err
+	if pending != nil {
+		if time.Since(pending.LastActivity()) < s.printLease {
+			return nil, domaininvoice.ErrPrintInProgress
 		}
-		reservedItems[item.ProductID] = item.Quantity
-	}
-
-	// Step 2: Confirm all reservations
-	result.StepReached = "stock_confirmation"
-	for prodID, qty := range reservedItems {
-		if err := s.confirmStock(ctx, prodID, qty); err != nil {
-			// If confirming fails, cancel remaining reservations and try to restore confirmed ones
-			result.FailedReason = fmt.Sprintf("Failed to confirm stock for product %d: %v", prodID, err)
-			result.Recovery.Attempted = true
-			result.Recovery.Message = "Attempting to cancel remaining reservations"
-
-			log.Printf("Erro ao confirmar reserva de estoque para o produto %d", prodID, err)
-			for pID, q := range reservedItems {
-				if pID != prodID {
-					result.Recovery.Details = append(result.Recovery.Details,
-						fmt.Sprintf("Canceling reservation for product %d, quantity %d", pID, q))
-					log.Printf("Cancelando reserva de estoque para o produto %d", pID)
-					s.cancelReservation(ctx, pID, q)
-				}
-			}
-
-			result.Recovery.Successful = true
-			return result, err
+		if err := s.takeOverPrint(ctx, inv); err != nil {
+			return nil, err
 		}
-	}
-
-	// Step 3: Cancel reservations before closing invoice
-	result.StepReached = "cancel_stock_reservation"
-	for prodID, qty := range reservedItems {
-		log.Printf("Cancelando reserva de estoque para o produto %d, quantidade %d", prodID, qty)
-		if err := s.cancelReservation(ctx, prodID, qty); err != nil {
-			log.Printf("Failed to cancel reservation for product %d: %v", prodID, err)
-			result.FailedReason = fmt.Sprintf("Error canceling reservation for product %d: %v", prodID, err)
-			result.Recovery.Attempted = true
-			result.Recovery.Details = append(result.Recovery.Details,
-				fmt.Sprintf("Failed to cancel reservation for product %d", prodID))
-			return result, err
+		if pending.PastPivot() {
+			return s.resumePrintSaga(ctx, pending)
 		}
-	}
+		s.compensatePrintSaga(ctx, pending, newProcessResult(invoiceID))
 
-	// 4: Close invoice
-	result.StepReached = "invoice_closing"
-	if err := inv.Close(); err != nil {
-		result.FailedReason = fmt.Sprintf("Failed to close invoice: %v", err)
-		return result, err
-	}
-
-	if err := s.repo.Update(ctx, inv); err != nil {
-		result.FailedReason = fmt.Sprintf("Failed to update invoice in database: %v", err)
-		return result, err
+		// Compensation moves the invoice back to OPEN
+		if inv, err = s.repo.GetByID(ctx, invoiceID); err != nil {
+			return nil, err
+		}
 	}
 
-	result.Success = true
-	return result, nil
-}
-
-func (s *Service) getProductFromInventory(ctx context.Context, productID int, quantity int) (*ProductResponse, error) {
-	url := fmt.Sprintf("%s/products/%d", s.inventoryServiceURL, productID)
-	log.Printf("Buscando produto %d no inventário", productID)
-
-	resp, err := http.Get(url)
-	if err != nil {
-		fmt.Println("Error making request to inventory service:", err)
-		return nil, ErrInventoryService
+	// Left PRINTING by a crash before its saga was saved
+	if inv.Status == domaininvoice.StatusPrinting && pending == nil {
+		if err := inv.AbortPrinting("print interrupted before it started"); err != nil {
+			return nil, err
+		}
 	}
-
-	if resp.StatusCode == http.StatusNotFound {
-		return nil, ErrProductNotFound
+	if err := inv.StartPrinting(); err != nil {
+		return nil, err
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, ErrInventoryService
+	// The saga is saved first so a crash never leaves a PRINTING invoice
+	// without one. Only the caller that moves the invoice to PRINTING runs it.
+	sg := saga.NewPrintSaga(invoiceID)
+	if err := s.sagas.Create(ctx, sg); err != nil {
+		return nil, err
 	}
-
-	var product ProductResponse
-	if err := json.NewDecoder(resp.Body).Decode(&product); err != nil {
-		fmt.Println("Error decoding response from inventory service:", err)
+	if err := s.repo.Update(ctx, inv); err != nil {
+		sg.Status = saga.StatusCompensated
+		sg.FailedReason = "print not started: " + err.Error()
+		s.saveSaga(ctx, sg)
+		if errors.Is(err, domaininvoice.ErrConcurrentUpdate) {
+			return nil, domaininvoice.ErrPrintInProgress
+		}
 		return nil, err
 	}
 
-	if product.Stock < quantity {
-		return nil, ErrStockReservation
-	}
-	log.Printf("Produto encontrado: %v", product)
-	return &product, nil
+	return s.runPrintSaga(ctx, sg, newProcessResult(invoiceID))
 }
 
-func (s *Service) reserveStock(ctx context.Context, productID int, quantity int) error {
-	url := fmt.Sprintf("%s/products/%d/reserve-stock", s.inventoryServiceURL, productID)
-	requestBody, _ := json.Marshal(map[string]int{"quantity": quantity})
-
-	log.Printf("Enviando requisição para reservar estoque: %s, Body: %s", url, string(requestBody))
-
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(requestBody))
-	if err != nil {
-		log.Println("Erro ao criar requisição:", err)
-		return err
-	}
-	req.Header.Set("Content-Type", "application/json")
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		log.Println("Erro ao fazer requisição para reservar estoque:", err)
-		return ErrInventoryService
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		log.Printf("Falha ao reservar estoque. Status: %d, Response: %s", resp.StatusCode, string(body))
-		return fmt.Errorf("falha ao reservar estoque, status: %d", resp.StatusCode)
+// takeOverPrint claims the print of an invoice whose saga was abandoned.
+// When several callers find the same saga, only the first to save the
+// invoice settles it.
+func (s *Service) takeOverPrint(ctx context.Context, inv *domaininvoice.Invoice) error {
+	inv.TakeOverPrint("abandoned print taken over")
+	err := s.repo.Update(ctx, inv)
+	if errors.Is(err, domaininvoice.ErrConcurrentUpdate) {
+		return domaininvoice.ErrPrintInProgress
 	}
-
-	log.Println("Estoque reservado com sucesso")
-	return nil
+	return err
 }
 
-func (s *Service) confirmStock(ctx context.Context, productID int, quantity int) error {
-	url := fmt.Sprintf("%s/products/%d/confirm-stock", s.inventoryServiceURL, productID)
-	log.Printf("Confirmando reserva de estoque para o produto %d", productID)
-
-	payload := map[string]int{"quantity": quantity}
-	jsonPayload, _ := json.Marshal(payload)
-
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(jsonPayload))
-	if err != nil {
-		fmt.Println("Error making request to inventory service:", err)
+// reservationError maps a failed inventory reservation to the service
+// errors: an unreachable inventory is worth retrying later, anything else
+// means the stock could not be reserved.
+func reservationError(err error) error {
+	if errors.Is(err, inventory.ErrUnavailable) || errors.Is(err, inventory.ErrCircuitOpen) {
 		return ErrInventoryService
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		fmt.Printf("Failed to confirm stock for product %d: %d", productID, resp.StatusCode)
-		return ErrInventoryService
-	}
-
-	return nil
+	return ErrStockReservation
 }
 
-func (s *Service) cancelReservation(ctx context.Context, productID int, quantity int) error {
-	url := fmt.Sprintf("%s/products/%d/cancel-reserve", s.inventoryServiceURL, productID)
-	log.Printf("Cancelando reserva de estoque para o produto %d", productID)
-	if quantity <= 0 {
-		return ErrInvalidQuantity
-	}
-
-	payload := map[string]int{"quantity": quantity}
-	jsonPayload, _ := json.Marshal(payload)
+func (s *Service) getProductFromInventory(ctx context.Context, productID int, quantity int) (*inventory.Product, error) {
+	log.Printf("Buscando produto %d no inventário", productID)
 
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(jsonPayload))
+	product, err := s.inventory.GetProduct(ctx, productID)
+	if errors.Is(err, inventory.ErrNotFound) {
+		return nil, ErrProductNotFound
+	}
 	if err != nil {
-		fmt.Println("Error making request cancelReservation to inventory service:", err)
-		return ErrInventoryService
+		log.Printf("Erro ao consultar o inventário: %v", err)
+		return nil, ErrInventoryService
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		fmt.Printf("Failed to cancel reservation for product %d: %d", productID, resp.StatusCode)
+	if product.Stock < quantity {
+		return nil, ErrInsufficientStock
 	}
-
-	return nil
+	log.Printf("Produto encontrado: %v", product)
+	return product, nil
 }
@@ -0,0 +1,67 @@
+package invoice
+
+import (
+	"context"
+
+	domaininvoice "github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/invoice"
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/tax"
+)
+
+// applyTaxes recomputes the tax breakdown of every item from the product tax
+// classes and the buyer's location, then refreshes the invoice totals.
+func (s *Service) applyTaxes(ctx context.Context, inv *domaininvoice.Invoice) error {
+	destination, err := s.destination(ctx, inv)
+	if err != nil {
+		return err
+	}
+
+	// Spreads the invoice-level adjustments so each item is taxed on its share
+	if err := inv.CalculateTotal(); err != nil {
+		return err
+	}
+
+	for _, item := range inv.Items {
+		class, err := s.taxClasses.Get(ctx, item.ProductID)
+		if err == tax.ErrClassNotFound {
+			class = &tax.Class{ProductID: item.ProductID}
+		} else if err != nil {
+			return err
+		}
+
+		lines := s.taxes.Compute(tax.Input{
+			Price:       item.Price,
+			Quantity:    item.Quantity,
+			Discount:    item.Discount,
+			Surcharge:   item.Surcharge,
+			Class:       *class,
+			Destination: destination,
+		})
+		item.SetTaxes(class.NCM, lines)
+	}
+
+	return inv.CalculateTotal()
+}
+
+// destination prefers the frozen customer snapshot, then the live customer
+// record. Invoices without a customer are taxed as local sales.
+func (s *Service) destination(ctx context.Context, inv *domaininvoice.Invoice) (tax.Location, error) {
+	if inv.Customer != nil {
+		if a := inv.Customer.Address; a != nil {
+			return tax.Location{UF: a.UF, Country: a.Country}, nil
+		}
+		return tax.Location{}, nil
+	}
+
+	if inv.CustomerID == 0 {
+		return tax.Location{}, nil
+	}
+
+	c, err := s.customers.GetByID(ctx, inv.CustomerID)
+	if err != nil {
+		return tax.Location{}, err
+	}
+	if a := c.BillingAddress(); a != nil {
+		return tax.Location{UF: a.UF, Country: a.Country}, nil
+	}
+	return tax.Location{}, nil
+}
@@ -0,0 +1,53 @@
+package numbering
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/numbering"
+)
+
+type Service struct {
+	repo numbering.Repository
+}
+
+func NewSeriesService(repo numbering.Repository) *Service {
+	return &Service{repo: repo}
+}
+
+func (s *Service) CreateSeries(ctx context.Context, code, prefix string, padding int, resetYearly bool) (*numbering.Series, error) {
+	series, err := numbering.NewSeries(code, prefix, padding, resetYearly)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Create(ctx, series); err != nil {
+		return nil, err
+	}
+	return series, nil
+}
+
+func (s *Service) GetSeries(ctx context.Context, code string) (*numbering.Series, error) {
+	return s.repo.Get(ctx, strings.ToUpper(code))
+}
+
+func (s *Service) ListSeries(ctx context.Context) ([]*numbering.Series, error) {
+	return s.repo.List(ctx)
+}
+
+// EnsureSeries creates the configured default series on first start. An
+// existing series is left untouched: changing its format would break the
+// numbers already issued.
+func (s *Service) EnsureSeries(ctx context.Context, code, prefix string, padding int, resetYearly bool) error {
+	_, err := s.repo.Get(ctx, strings.ToUpper(code))
+	if err != numbering.ErrNotFound {
+		return err
+	}
+
+	if _, err := s.CreateSeries(ctx, code, prefix, padding, resetYearly); err != nil && err != numbering.ErrDuplicateCode {
+		return err
+	}
+	log.Printf("Série de numeração %s criada", code)
+	return nil
+}
@@ -0,0 +1,102 @@
+package outbox
+
+import (
+	"context"
+	"log"
+	"time"
+
+	domainoutbox "github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/outbox"
+)
+
+type Config struct {
+	Interval  time.Duration
+	BatchSize int
+	// ClaimTTL bounds how long a relay may take to publish a batch before
+	// another relay can claim the same events again
+	ClaimTTL    time.Duration
+	RetryDelay  time.Duration
+	MaxAttempts int
+}
+
+// Relay moves stored events from the outbox to a publisher.
+type Relay struct {
+	repo      domainoutbox.Repository
+	publisher domainoutbox.Publisher
+	cfg       Config
+}
+
+func NewRelay(repo domainoutbox.Repository, publisher domainoutbox.Publisher, cfg Config) *Relay {
+	return &Relay{
+		repo:      repo,
+		publisher: publisher,
+		cfg:       cfg,
+	}
+}
+
+// Run polls the outbox until ctx is done, draining full batches back to back.
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for {
+				claimed, err := r.dispatch(ctx)
+				if err != nil {
+					log.Printf("Falha ao publicar eventos do outbox: %v", err)
+					break
+				}
+				if claimed < r.cfg.BatchSize {
+					break
+				}
+			}
+		}
+	}
+}
+
+// dispatch claims a batch and publishes it in order. A failure holds back
+// the rest of its aggregate only; other aggregates keep flowing. It returns
+// how many events were claimed.
+func (r *Relay) dispatch(ctx context.Context) (int, error) {
+	now := time.Now()
+	messages, err := r.repo.Claim(ctx, r.cfg.BatchSize, now, now.Add(r.cfg.ClaimTTL))
+	if err != nil {
+		return 0, err
+	}
+
+	type aggregate struct {
+		kind string
+		id   int
+	}
+	failed := make(map[aggregate]bool)
+	var skipped []int64
+
+	for _, msg := range messages {
+		key := aggregate{msg.AggregateType, msg.AggregateID}
+		if failed[key] {
+			skipped = append(skipped, msg.ID)
+			continue
+		}
+
+		if perr := r.publisher.Publish(ctx, msg); perr != nil {
+			failed[key] = true
+			dead, err := r.repo.MarkFailed(ctx, msg.ID, perr, time.Now().Add(r.cfg.RetryDelay), r.cfg.MaxAttempts)
+			if err != nil {
+				return len(messages), err
+			}
+			if dead {
+				log.Printf("Evento %d (%s) movido para dead-letter após %d tentativas: %v", msg.ID, msg.EventType, msg.Attempts+1, perr)
+			}
+			continue
+		}
+
+		if err := r.repo.MarkPublished(ctx, msg.ID, time.Now()); err != nil {
+			return len(messages), err
+		}
+	}
+
+	return len(messages), r.repo.Release(ctx, skipped)
+}
@@ -0,0 +1,218 @@
+package recurring
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	appinvoice "github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/application/invoice"
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/recurring"
+)
+
+type Service struct {
+	repo     recurring.Repository
+	invoices *appinvoice.Service
+	lease    time.Duration
+}
+
+// NewRecurringService builds the service. lease is how long a run survives
+// its process: a run whose lease was not renewed for that long is failed.
+func NewRecurringService(repo recurring.Repository, invoices *appinvoice.Service, lease time.Duration) *Service {
+	return &Service{repo: repo, invoices: invoices, lease: lease}
+}
+
+func (s *Service) CreateSchedule(ctx context.Context, def recurring.Definition) (*recurring.Schedule, error) {
+	schedule, err := recurring.NewSchedule(def, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Create(ctx, schedule); err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+func (s *Service) GetSchedule(ctx context.Context, id int) (*recurring.Schedule, error) {
+	return s.repo.Get(ctx, id)
+}
+
+func (s *Service) ListSchedules(ctx context.Context) ([]*recurring.Schedule, error) {
+	return s.repo.List(ctx)
+}
+
+func (s *Service) UpdateSchedule(ctx context.Context, id int, def recurring.Definition) (*recurring.Schedule, error) {
+	schedule, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !schedule.Active {
+		return nil, recurring.ErrInactive
+	}
+
+	if err := schedule.Redefine(def, time.Now()); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(ctx, schedule); err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+func (s *Service) DeactivateSchedule(ctx context.Context, id int) (*recurring.Schedule, error) {
+	schedule, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	schedule.Deactivate()
+	if err := s.repo.Update(ctx, schedule); err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+func (s *Service) ListRuns(ctx context.Context, id int) ([]*recurring.Run, error) {
+	if _, err := s.repo.Get(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.repo.ListRuns(ctx, id)
+}
+
+// Run invoices the due occurrences at start and then on every interval until
+// ctx is done. Before each pass, runs whose lease expired are marked failed:
+// the process working them stopped, and whatever invoice they left behind has
+// to be checked by hand. Runs of other live instances keep their lease.
+func (s *Service) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if n, err := s.repo.AbandonRuns(ctx, time.Now(), "interrupted: lease expired"); err != nil {
+			log.Printf("Falha ao encerrar execuções recorrentes interrompidas: %v", err)
+		} else if n > 0 {
+			log.Printf("%d execução(ões) recorrente(s) interrompida(s) marcada(s) como falha", n)
+		}
+
+		if err := s.RunDue(ctx, time.Now()); err != nil {
+			log.Printf("Falha ao gerar faturas recorrentes: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunDue invoices every occurrence due at now, one at a time.
+func (s *Service) RunDue(ctx context.Context, now time.Time) error {
+	for {
+		schedule, run, err := s.repo.ClaimDue(ctx, now, s.lease)
+		if err == recurring.ErrNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		runCtx, cancel := context.WithCancel(ctx)
+		stop := s.keepLease(runCtx, run, cancel)
+		s.materialize(runCtx, schedule, run)
+		stop()
+		cancel()
+		if err := s.repo.FinishRun(ctx, run); err == recurring.ErrRunLost {
+			log.Printf("Execução %d da fatura recorrente %d foi abandonada antes de ser registrada", run.ID, schedule.ID)
+		} else if err != nil {
+			log.Printf("Falha ao registrar execução %d da fatura recorrente %d: %v", run.ID, schedule.ID, err)
+		}
+	}
+}
+
+// keepLease renews the lease of run until the returned function is called.
+// When the run is found abandoned, another pass already failed it, so lost is
+// called to stop the work on it.
+func (s *Service) keepLease(ctx context.Context, run *recurring.Run, lost context.CancelFunc) func() {
+	runID, scheduleID := run.ID, run.ScheduleID
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(s.lease / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				err := s.repo.RenewRun(ctx, runID, time.Now().Add(s.lease))
+				if err == recurring.ErrRunLost {
+					log.Printf("Execução %d da fatura recorrente %d abandonada, interrompendo", runID, scheduleID)
+					lost()
+					return
+				}
+				if err != nil {
+					log.Printf("Falha ao renovar a execução %d da fatura recorrente %d: %v", runID, scheduleID, err)
+				}
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// materialize creates the invoice of one occurrence through the same steps as
+// the API: create, add each item (reserving its stock) and, if asked, print.
+// When an item cannot be added the invoice is cancelled, which releases the
+// stock already reserved. ctx is cancelled when the run loses its lease; the
+// steps left are then skipped.
+func (s *Service) materialize(ctx context.Context, schedule *recurring.Schedule, run *recurring.Run) {
+	log.Printf("Gerando fatura recorrente %d (%s) para %s", schedule.ID, schedule.Name, run.ScheduledFor.Format(time.RFC3339))
+
+	inv, err := s.invoices.CreateInvoice(ctx, appinvoice.CreateInput{
+		Series:       schedule.Series,
+		CustomerID:   schedule.CustomerID,
+		PaymentTerms: schedule.PaymentTerms,
+	})
+	if err != nil {
+		run.Fail(recurring.StepCreate, err)
+		return
+	}
+	run.InvoiceID = inv.ID
+
+	for _, item := range schedule.Items {
+		if err := s.invoices.AddInvoiceItem(ctx, inv.ID, item.ProductID, item.Quantity); err != nil {
+			run.Fail(recurring.StepItems, fmt.Errorf("product %d: %w", item.ProductID, err))
+			// The stock goes back even when the run was stopped
+			if _, err := s.invoices.CancelInvoice(context.WithoutCancel(ctx), inv.ID, "recurring invoice run failed"); err != nil {
+				log.Printf("Falha ao cancelar a fatura recorrente %d: %v", inv.ID, err)
+			}
+			return
+		}
+	}
+
+	if schedule.AutoPrint {
+		if err := ctx.Err(); err != nil {
+			run.Fail(recurring.StepPrint, err)
+			return
+		}
+		result, err := s.invoices.PrintInvoice(ctx, inv.ID)
+		if err != nil {
+			if result != nil && result.FailedReason != "" {
+				err = fmt.Errorf("%w: %s", err, result.FailedReason)
+			}
+			run.Fail(recurring.StepPrint, err)
+			return
+		}
+		run.Printed = true
+	}
+
+	run.Succeed()
+	log.Printf("Fatura recorrente %d gerada: fatura %d", schedule.ID, inv.ID)
+}
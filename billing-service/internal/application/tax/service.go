@@ -0,0 +1,34 @@
+package tax
+
+import (
+	"context"
+
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/tax"
+)
+
+type Service struct {
+	classes tax.ClassRepository
+}
+
+func NewTaxService(classes tax.ClassRepository) *Service {
+	return &Service{classes: classes}
+}
+
+func (s *Service) GetClass(ctx context.Context, productID int) (*tax.Class, error) {
+	return s.classes.Get(ctx, productID)
+}
+
+// SaveClass creates or replaces the classification of a product. Open
+// invoices pick it up the next time their items change; issued ones keep the
+// taxes they were closed with.
+func (s *Service) SaveClass(ctx context.Context, productID int, ncm string, ipiRate tax.Rate, exempt bool) (*tax.Class, error) {
+	class, err := tax.NewClass(productID, ncm, ipiRate, exempt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.classes.Save(ctx, class); err != nil {
+		return nil, err
+	}
+	return class, nil
+}
@@ -11,6 +11,15 @@ type Config struct {
 	Database            DatabaseConfig
 	Server              ServerConfig
 	InventoryServiceURL string
+	Inventory           InventoryConfig
+	Outbox              OutboxConfig
+	NFe                 NFeConfig
+	Invoice             InvoiceConfig
+	Tax                 TaxConfig
+	Pix                 PixConfig
+	Boleto              BoletoConfig
+	Exchange            ExchangeConfig
+	Idempotency         IdempotencyConfig
 	DatabaseURL         string
 }
 
@@ -28,6 +37,105 @@ type ServerConfig struct {
 	WriteTimeout int
 }
 
+type InventoryConfig struct {
+	TimeoutMs               int
+	MaxRetries              int
+	RetryBaseDelayMs        int
+	RetryMaxDelayMs         int
+	BreakerFailureThreshold int
+	BreakerOpenSeconds      int
+	HoldTTLSeconds          int
+}
+
+type OutboxConfig struct {
+	Publisher      string
+	WebhookURL     string
+	WebhookSecret  string
+	BrokerURL      string
+	BrokerTopic    string
+	PollIntervalMs int
+	BatchSize      int
+	ClaimSeconds   int
+	RetrySeconds   int
+	MaxAttempts    int
+}
+
+type InvoiceConfig struct {
+	CancellationWindowHours int
+	SeriesCode              string
+	SeriesPrefix            string
+	SeriesPadding           int
+	SeriesResetYearly       bool
+	PaymentTerms            string
+	OverdueCheckMinutes     int
+	RecurringPollSeconds    int
+	RecurringLeaseSeconds   int
+	PrintWorkers            int
+	PrintPollSeconds        int
+	PrintLeaseSeconds       int
+	PrintMaxAttempts        int
+}
+
+type TaxConfig struct {
+	Engine          string
+	ICMSRate        string
+	PISCOFINSRegime string
+	VATRate         string
+	VATRates        string
+}
+
+type PixConfig struct {
+	Key          string
+	MerchantName string
+	MerchantCity string
+	LocationURL  string
+}
+
+type BoletoConfig struct {
+	BankCode  string
+	Agency    string
+	Account   string
+	Wallet    string
+	Agreement string
+	DueDays   int
+}
+
+type ExchangeConfig struct {
+	Provider     string
+	BaseCurrency string
+	Rates        string
+	RatesFile    string
+}
+
+type IdempotencyConfig struct {
+	LockSeconds    int
+	RetentionHours int
+	CleanupMinutes int
+}
+
+type NFeConfig struct {
+	IssuerCNPJ      string
+	IssuerName      string
+	IssuerTradeName string
+	IssuerIE        string
+	IssuerCRT       string
+	IssuerStreet    string
+	IssuerNumber    string
+	IssuerDistrict  string
+	IssuerCityCode  string
+	IssuerCity      string
+	IssuerUF        string
+	IssuerCEP       string
+	Series          int
+	Environment     string
+	Nature          string
+	DefaultNCM      string
+	DefaultCFOP     string
+	DefaultUnit     string
+	ICMSRate        string
+	Timezone        string
+}
+
 func Load() (*Config, error) {
 	err := godotenv.Load()
 	if err != nil {
@@ -42,6 +150,77 @@ func Load() (*Config, error) {
 	viper.SetDefault("DB_NAME", "billing")
 	viper.SetDefault("SERVER_PORT", "8081")
 	viper.SetDefault("INVENTORY_SERVICE_URL", "http://inventory-service:8080")
+	viper.SetDefault("INVENTORY_TIMEOUT_MS", 3000)
+	viper.SetDefault("INVENTORY_MAX_RETRIES", 3)
+	viper.SetDefault("INVENTORY_RETRY_BASE_DELAY_MS", 100)
+	viper.SetDefault("INVENTORY_RETRY_MAX_DELAY_MS", 2000)
+	viper.SetDefault("INVENTORY_BREAKER_FAILURE_THRESHOLD", 5)
+	viper.SetDefault("INVENTORY_BREAKER_OPEN_SECONDS", 30)
+	viper.SetDefault("INVENTORY_HOLD_TTL_SECONDS", 86400)
+	viper.SetDefault("OUTBOX_PUBLISHER", "inprocess")
+	viper.SetDefault("OUTBOX_POLL_INTERVAL_MS", 1000)
+	viper.SetDefault("OUTBOX_BATCH_SIZE", 100)
+	viper.SetDefault("OUTBOX_BROKER_TOPIC", "invoice-events")
+	viper.SetDefault("OUTBOX_CLAIM_SECONDS", 60)
+	viper.SetDefault("OUTBOX_RETRY_SECONDS", 30)
+	viper.SetDefault("OUTBOX_MAX_ATTEMPTS", 10)
+	viper.SetDefault("INVOICE_CANCELLATION_WINDOW_HOURS", 24)
+	viper.SetDefault("INVOICE_SERIES_CODE", "INV")
+	viper.SetDefault("INVOICE_SERIES_PREFIX", "INV-")
+	viper.SetDefault("INVOICE_SERIES_PADDING", 6)
+	viper.SetDefault("INVOICE_SERIES_RESET_YEARLY", true)
+	viper.SetDefault("INVOICE_PAYMENT_TERMS", "30")
+	viper.SetDefault("INVOICE_OVERDUE_CHECK_MINUTES", 60)
+	viper.SetDefault("INVOICE_RECURRING_POLL_SECONDS", 60)
+	viper.SetDefault("INVOICE_RECURRING_LEASE_SECONDS", 300)
+	viper.SetDefault("INVOICE_PRINT_WORKERS", 4)
+	viper.SetDefault("INVOICE_PRINT_POLL_SECONDS", 5)
+	viper.SetDefault("INVOICE_PRINT_LEASE_SECONDS", 120)
+	viper.SetDefault("INVOICE_PRINT_MAX_ATTEMPTS", 10)
+	viper.SetDefault("BOLETO_DUE_DAYS", 5)
+	viper.SetDefault("FX_PROVIDER", "static")
+	viper.SetDefault("FX_BASE_CURRENCY", "BRL")
+	viper.SetDefault("IDEMPOTENCY_LOCK_SECONDS", 300)
+	viper.SetDefault("IDEMPOTENCY_RETENTION_HOURS", 24)
+	viper.SetDefault("IDEMPOTENCY_CLEANUP_MINUTES", 60)
+	viper.SetDefault("NFE_ISSUER_CRT", "1")
+	viper.SetDefault("NFE_SERIES", 1)
+	viper.SetDefault("NFE_ENVIRONMENT", "2")
+	viper.SetDefault("NFE_NATURE", "VENDA DE MERCADORIA")
+	viper.SetDefault("NFE_DEFAULT_NCM", "00000000")
+	viper.SetDefault("NFE_DEFAULT_CFOP", "5102")
+	viper.SetDefault("NFE_DEFAULT_UNIT", "UN")
+	viper.SetDefault("NFE_ICMS_RATE", "18.00")
+	viper.SetDefault("NFE_TIMEZONE", "America/Sao_Paulo")
+	viper.SetDefault("TAX_PIS_COFINS_REGIME", "non-cumulative")
+	viper.SetDefault("TAX_VAT_RATE", "0")
+
+	// Simples Nacional issuers collect ICMS, PIS and COFINS through the DAS
+	// instead of on each invoice
+	engine := viper.GetString("TAX_ENGINE")
+	if engine == "" {
+		engine = "brazil"
+		if viper.GetString("NFE_ISSUER_CRT") == "1" {
+			engine = "simples"
+		}
+	}
+	icmsRate := viper.GetString("TAX_ICMS_RATE")
+	if icmsRate == "" {
+		icmsRate = viper.GetString("NFE_ICMS_RATE")
+	}
+
+	// The receiver shown to the payer defaults to the NF-e issuer
+	pixName := viper.GetString("PIX_MERCHANT_NAME")
+	if pixName == "" {
+		pixName = viper.GetString("NFE_ISSUER_TRADE_NAME")
+	}
+	if pixName == "" {
+		pixName = viper.GetString("NFE_ISSUER_NAME")
+	}
+	pixCity := viper.GetString("PIX_MERCHANT_CITY")
+	if pixCity == "" {
+		pixCity = viper.GetString("NFE_ISSUER_CITY")
+	}
 
 	return &Config{
 		Database: DatabaseConfig{
@@ -57,5 +236,95 @@ func Load() (*Config, error) {
 			WriteTimeout: 15,
 		},
 		InventoryServiceURL: viper.GetString("INVENTORY_SERVICE_URL"),
+		Inventory: InventoryConfig{
+			TimeoutMs:               viper.GetInt("INVENTORY_TIMEOUT_MS"),
+			MaxRetries:              viper.GetInt("INVENTORY_MAX_RETRIES"),
+			RetryBaseDelayMs:        viper.GetInt("INVENTORY_RETRY_BASE_DELAY_MS"),
+			RetryMaxDelayMs:         viper.GetInt("INVENTORY_RETRY_MAX_DELAY_MS"),
+			BreakerFailureThreshold: viper.GetInt("INVENTORY_BREAKER_FAILURE_THRESHOLD"),
+			BreakerOpenSeconds:      viper.GetInt("INVENTORY_BREAKER_OPEN_SECONDS"),
+			HoldTTLSeconds:          viper.GetInt("INVENTORY_HOLD_TTL_SECONDS"),
+		},
+		Outbox: OutboxConfig{
+			Publisher:      viper.GetString("OUTBOX_PUBLISHER"),
+			WebhookURL:     viper.GetString("OUTBOX_WEBHOOK_URL"),
+			WebhookSecret:  viper.GetString("OUTBOX_WEBHOOK_SECRET"),
+			BrokerURL:      viper.GetString("OUTBOX_BROKER_URL"),
+			BrokerTopic:    viper.GetString("OUTBOX_BROKER_TOPIC"),
+			PollIntervalMs: viper.GetInt("OUTBOX_POLL_INTERVAL_MS"),
+			BatchSize:      viper.GetInt("OUTBOX_BATCH_SIZE"),
+			ClaimSeconds:   viper.GetInt("OUTBOX_CLAIM_SECONDS"),
+			RetrySeconds:   viper.GetInt("OUTBOX_RETRY_SECONDS"),
+			MaxAttempts:    viper.GetInt("OUTBOX_MAX_ATTEMPTS"),
+		},
+		NFe: NFeConfig{
+			IssuerCNPJ:      viper.GetString("NFE_ISSUER_CNPJ"),
+			IssuerName:      viper.GetString("NFE_ISSUER_NAME"),
+			IssuerTradeName: viper.GetString("NFE_ISSUER_TRADE_NAME"),
+			IssuerIE:        viper.GetString("NFE_ISSUER_IE"),
+			IssuerCRT:       viper.GetString("NFE_ISSUER_CRT"),
+			IssuerStreet:    viper.GetString("NFE_ISSUER_STREET"),
+			IssuerNumber:    viper.GetString("NFE_ISSUER_NUMBER"),
+			IssuerDistrict:  viper.GetString("NFE_ISSUER_DISTRICT"),
+			IssuerCityCode:  viper.GetString("NFE_ISSUER_CITY_CODE"),
+			IssuerCity:      viper.GetString("NFE_ISSUER_CITY"),
+			IssuerUF:        viper.GetString("NFE_ISSUER_UF"),
+			IssuerCEP:       viper.GetString("NFE_ISSUER_CEP"),
+			Series:          viper.GetInt("NFE_SERIES"),
+			Environment:     viper.GetString("NFE_ENVIRONMENT"),
+			Nature:          viper.GetString("NFE_NATURE"),
+			DefaultNCM:      viper.GetString("NFE_DEFAULT_NCM"),
+			DefaultCFOP:     viper.GetString("NFE_DEFAULT_CFOP"),
+			DefaultUnit:     viper.GetString("NFE_DEFAULT_UNIT"),
+			ICMSRate:        viper.GetString("NFE_ICMS_RATE"),
+			Timezone:        viper.GetString("NFE_TIMEZONE"),
+		},
+		Invoice: InvoiceConfig{
+			CancellationWindowHours: viper.GetInt("INVOICE_CANCELLATION_WINDOW_HOURS"),
+			SeriesCode:              viper.GetString("INVOICE_SERIES_CODE"),
+			SeriesPrefix:            viper.GetString("INVOICE_SERIES_PREFIX"),
+			SeriesPadding:           viper.GetInt("INVOICE_SERIES_PADDING"),
+			SeriesResetYearly:       viper.GetBool("INVOICE_SERIES_RESET_YEARLY"),
+			PaymentTerms:            viper.GetString("INVOICE_PAYMENT_TERMS"),
+			OverdueCheckMinutes:     viper.GetInt("INVOICE_OVERDUE_CHECK_MINUTES"),
+			RecurringPollSeconds:    viper.GetInt("INVOICE_RECURRING_POLL_SECONDS"),
+			RecurringLeaseSeconds:   viper.GetInt("INVOICE_RECURRING_LEASE_SECONDS"),
+			PrintWorkers:            viper.GetInt("INVOICE_PRINT_WORKERS"),
+			PrintPollSeconds:        viper.GetInt("INVOICE_PRINT_POLL_SECONDS"),
+			PrintLeaseSeconds:       viper.GetInt("INVOICE_PRINT_LEASE_SECONDS"),
+			PrintMaxAttempts:        viper.GetInt("INVOICE_PRINT_MAX_ATTEMPTS"),
+		},
+		Tax: TaxConfig{
+			Engine:          engine,
+			ICMSRate:        icmsRate,
+			PISCOFINSRegime: viper.GetString("TAX_PIS_COFINS_REGIME"),
+			VATRate:         viper.GetString("TAX_VAT_RATE"),
+			VATRates:        viper.GetString("TAX_VAT_RATES"),
+		},
+		Pix: PixConfig{
+			Key:          viper.GetString("PIX_KEY"),
+			MerchantName: pixName,
+			MerchantCity: pixCity,
+			LocationURL:  viper.GetString("PIX_LOCATION_URL"),
+		},
+		Boleto: BoletoConfig{
+			BankCode:  viper.GetString("BOLETO_BANK_CODE"),
+			Agency:    viper.GetString("BOLETO_AGENCY"),
+			Account:   viper.GetString("BOLETO_ACCOUNT"),
+			Wallet:    viper.GetString("BOLETO_WALLET"),
+			Agreement: viper.GetString("BOLETO_AGREEMENT"),
+			DueDays:   viper.GetInt("BOLETO_DUE_DAYS"),
+		},
+		Exchange: ExchangeConfig{
+			Provider:     viper.GetString("FX_PROVIDER"),
+			BaseCurrency: viper.GetString("FX_BASE_CURRENCY"),
+			Rates:        viper.GetString("FX_RATES"),
+			RatesFile:    viper.GetString("FX_RATES_FILE"),
+		},
+		Idempotency: IdempotencyConfig{
+			LockSeconds:    viper.GetInt("IDEMPOTENCY_LOCK_SECONDS"),
+			RetentionHours: viper.GetInt("IDEMPOTENCY_RETENTION_HOURS"),
+			CleanupMinutes: viper.GetInt("IDEMPOTENCY_CLEANUP_MINUTES"),
+		},
 	}, nil
 }
@@ -0,0 +1,62 @@
+package boleto
+
+import (
+	"fmt"
+	"strings"
+)
+
+// layout returns the printed "nosso número" and the 25-digit free field of a
+// boleto.
+type layout func(b Beneficiary, sequence int64) (ourNumber string, free string, err error)
+
+var layouts = map[string]layout{
+	"001": bancoDoBrasil,
+	"237": bradesco,
+}
+
+// Supported reports whether boletos can be generated for the bank.
+func Supported(bankCode string) bool {
+	_, ok := layouts[bankCode]
+	return ok
+}
+
+// bancoDoBrasil implements the 7-digit agreement layout: six zeros, the
+// agreement, a 10-digit sequence and the wallet. The nosso número is the
+// agreement followed by the sequence and has no check digit.
+func bancoDoBrasil(b Beneficiary, sequence int64) (string, string, error) {
+	if !isDigits(b.Agreement, 7) || !isDigits(b.Wallet, 2) {
+		return "", "", ErrInvalidBeneficiary
+	}
+	if sequence > 9999999999 {
+		return "", "", ErrSequenceOutOfRange
+	}
+
+	ourNumber := fmt.Sprintf("%s%010d", b.Agreement, sequence)
+	return ourNumber, "000000" + ourNumber + b.Wallet, nil
+}
+
+// bradesco lays out agency, wallet, an 11-digit nosso número, account and a
+// trailing zero. The printed nosso número carries a modulo 11 check digit
+// computed over wallet and number.
+func bradesco(b Beneficiary, sequence int64) (string, string, error) {
+	account := strings.TrimLeft(b.Account, "0")
+	if !isDigits(b.Agency, 4) || !isDigits(b.Wallet, 2) || account == "" || len(account) > 7 || !isDigits(account, len(account)) {
+		return "", "", ErrInvalidBeneficiary
+	}
+	if sequence > 99999999999 {
+		return "", "", ErrSequenceOutOfRange
+	}
+
+	number := fmt.Sprintf("%011d", sequence)
+	dv := "0"
+	switch rest := mod11(b.Wallet+number, 7); rest {
+	case 0:
+	case 1:
+		dv = "P"
+	default:
+		dv = fmt.Sprint(11 - rest)
+	}
+
+	free := fmt.Sprintf("%s%s%s%07s0", b.Agency, b.Wallet, number, account)
+	return b.Wallet + "/" + number + "-" + dv, free, nil
+}
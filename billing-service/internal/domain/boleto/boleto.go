@@ -0,0 +1,177 @@
+// Package boleto computes the barcode and the linha digitável of Brazilian
+// bank slips following the FEBRABAN layout shared by every bank. Only the
+// 25-digit free field differs between banks.
+package boleto
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/vitorwhois/microservice-invoice-billing/money"
+)
+
+var (
+	ErrUnsupportedBank    = errors.New("unsupported boleto bank")
+	ErrInvalidBeneficiary = errors.New("invalid boleto beneficiary data")
+	ErrInvalidDueDate     = errors.New("invalid boleto due date")
+	ErrInvalidAmount      = errors.New("invalid boleto amount")
+	ErrSequenceOutOfRange = errors.New("boleto sequence out of range")
+)
+
+const (
+	currencyReal = "9"
+	maxAmount    = 9999999999
+)
+
+// Beneficiary is the bank account and collection agreement boletos are paid
+// into, as registered with the bank. Which fields are required depends on the
+// bank.
+type Beneficiary struct {
+	BankCode  string
+	Agency    string
+	Account   string
+	Wallet    string
+	Agreement string
+}
+
+// Code is a generated boleto: the "nosso número" identifying it at the bank,
+// the 44-digit barcode and the 47-digit linha digitável typed by the payer.
+type Code struct {
+	OurNumber     string
+	Barcode       string
+	DigitableLine string
+}
+
+// Generate builds the boleto numbered sequence for amount, due on due. The
+// sequence must be unique for the beneficiary.
+func Generate(b Beneficiary, sequence int64, due time.Time, amount money.Money) (Code, error) {
+	layout, ok := layouts[b.BankCode]
+	if !ok {
+		return Code{}, ErrUnsupportedBank
+	}
+	if sequence <= 0 {
+		return Code{}, ErrSequenceOutOfRange
+	}
+	if !amount.IsPositive() || amount.Amount > maxAmount || (amount.Currency != "" && amount.Currency != "BRL") {
+		return Code{}, ErrInvalidAmount
+	}
+
+	factor, err := DueFactor(due)
+	if err != nil {
+		return Code{}, err
+	}
+
+	ourNumber, free, err := layout(b, sequence)
+	if err != nil {
+		return Code{}, err
+	}
+
+	barcode := Barcode(b.BankCode, factor, amount.Amount, free)
+	return Code{
+		OurNumber:     ourNumber,
+		Barcode:       barcode,
+		DigitableLine: DigitableLine(barcode),
+	}, nil
+}
+
+// baseDate is day zero of the due date factor. The factor ran out at 9999 on
+// 2025-02-21 and restarted from 1000 the following day.
+var baseDate = time.Date(1997, time.October, 7, 0, 0, 0, 0, time.UTC)
+
+// DueFactor is the number of days encoded in the barcode for a due date.
+func DueFactor(due time.Time) (int, error) {
+	day := time.Date(due.Year(), due.Month(), due.Day(), 0, 0, 0, 0, time.UTC)
+	days := int(day.Sub(baseDate).Hours() / 24)
+	if days < 1000 {
+		return 0, ErrInvalidDueDate
+	}
+	if days > 9999 {
+		days = (days-10000)%9000 + 1000
+	}
+	return days, nil
+}
+
+// Barcode assembles the 44 digits: bank, currency, general check digit, due
+// factor, amount in cents and the bank's free field.
+func Barcode(bankCode string, factor int, cents int64, free string) string {
+	body := fmt.Sprintf("%s%s%04d%010d%s", bankCode, currencyReal, factor, cents, free)
+	return body[:4] + barcodeDigit(body) + body[4:]
+}
+
+// DigitableLine rearranges a barcode into the five fields of the linha
+// digitável, each of the first three followed by its own check digit.
+func DigitableLine(barcode string) string {
+	field1 := barcode[0:4] + barcode[19:24]
+	field2 := barcode[24:34]
+	field3 := barcode[34:44]
+	return field1 + mod10(field1) +
+		field2 + mod10(field2) +
+		field3 + mod10(field3) +
+		barcode[4:5] +
+		barcode[5:19]
+}
+
+// FormatDigitableLine groups the 47 digits the way they are printed:
+// "00190.00009 01234.567004 00000.001180 1 16100000015075".
+func FormatDigitableLine(line string) string {
+	if len(line) != 47 {
+		return line
+	}
+	return strings.Join([]string{
+		line[0:5] + "." + line[5:10],
+		line[10:15] + "." + line[15:21],
+		line[21:26] + "." + line[26:32],
+		line[32:33],
+		line[33:47],
+	}, " ")
+}
+
+// mod10 is the check digit of the linha digitável fields: digits weighted 2
+// and 1 alternately from the right, with two-digit products summed digit by
+// digit.
+func mod10(digits string) string {
+	sum, weight := 0, 2
+	for i := len(digits) - 1; i >= 0; i-- {
+		p := int(digits[i]-'0') * weight
+		sum += p/10 + p%10
+		weight = 3 - weight
+	}
+	return fmt.Sprint((10 - sum%10) % 10)
+}
+
+// mod11 sums the digits weighted 2 to maxWeight cyclically from the right and
+// returns the remainder of the sum by 11.
+func mod11(digits string, maxWeight int) int {
+	sum, weight := 0, 2
+	for i := len(digits) - 1; i >= 0; i-- {
+		sum += int(digits[i]-'0') * weight
+		weight++
+		if weight > maxWeight {
+			weight = 2
+		}
+	}
+	return sum % 11
+}
+
+// barcodeDigit is the general check digit, which can never be 0.
+func barcodeDigit(body string) string {
+	dv := 11 - mod11(body, 9)
+	if dv == 0 || dv == 10 || dv == 11 {
+		dv = 1
+	}
+	return fmt.Sprint(dv)
+}
+
+func isDigits(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,143 @@
+package customer
+
+import (
+	"errors"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotFound          = errors.New("customer not found")
+	ErrInvalidName       = errors.New("customer name is required")
+	ErrInvalidDocument   = errors.New("invalid CPF/CNPJ")
+	ErrInvalidEmail      = errors.New("invalid email address")
+	ErrInvalidAddress    = errors.New("invalid address")
+	ErrDuplicateDocument = errors.New("a customer with this document already exists")
+	ErrInUse             = errors.New("customer is referenced by invoices")
+)
+
+type DocumentType string
+
+const (
+	DocumentCPF  DocumentType = "CPF"
+	DocumentCNPJ DocumentType = "CNPJ"
+)
+
+type AddressKind string
+
+const (
+	AddressBilling  AddressKind = "BILLING"
+	AddressShipping AddressKind = "SHIPPING"
+)
+
+type Address struct {
+	Kind       AddressKind
+	Street     string
+	Number     string
+	Complement string
+	District   string
+	CityCode   string
+	City       string
+	UF         string
+	CEP        string
+	// Country is the ISO 3166-1 alpha-2 code, BR when empty
+	Country string
+}
+
+type Customer struct {
+	ID                int
+	Name              string
+	Document          string
+	DocumentType      DocumentType
+	Email             string
+	StateRegistration string
+	Addresses         []Address
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+}
+
+func NewCustomer(name, document, email, stateRegistration string, addresses []Address) (*Customer, error) {
+	now := time.Now()
+	c := &Customer{CreatedAt: now}
+	if err := c.Change(name, document, email, stateRegistration, addresses, now); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Change validates and applies new customer data. The document is stored as
+// digits only.
+func (c *Customer) Change(name, document, email, stateRegistration string, addresses []Address, now time.Time) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return ErrInvalidName
+	}
+
+	digits, docType, err := ParseDocument(document)
+	if err != nil {
+		return err
+	}
+
+	email = strings.TrimSpace(email)
+	if email != "" {
+		if _, err := mail.ParseAddress(email); err != nil {
+			return ErrInvalidEmail
+		}
+	}
+
+	for i := range addresses {
+		if err := normalizeAddress(&addresses[i]); err != nil {
+			return err
+		}
+	}
+
+	c.Name = name
+	c.Document = digits
+	c.DocumentType = docType
+	c.Email = email
+	c.StateRegistration = strings.TrimSpace(stateRegistration)
+	c.Addresses = addresses
+	c.UpdatedAt = now
+	return nil
+}
+
+// BillingAddress returns the billing address, falling back to the first one.
+func (c *Customer) BillingAddress() *Address {
+	for i := range c.Addresses {
+		if c.Addresses[i].Kind == AddressBilling {
+			return &c.Addresses[i]
+		}
+	}
+	if len(c.Addresses) > 0 {
+		return &c.Addresses[0]
+	}
+	return nil
+}
+
+func normalizeAddress(a *Address) error {
+	if a.Kind == "" {
+		a.Kind = AddressBilling
+	}
+	if a.Kind != AddressBilling && a.Kind != AddressShipping {
+		return ErrInvalidAddress
+	}
+
+	a.UF = strings.ToUpper(strings.TrimSpace(a.UF))
+	a.CEP = onlyDigits(a.CEP)
+	a.Country = strings.ToUpper(strings.TrimSpace(a.Country))
+	if a.Country == "" {
+		a.Country = "BR"
+	}
+	// Foreign addresses use the EX state, as the NF-e does
+	if a.Country != "BR" && a.UF == "" {
+		a.UF = "EX"
+	}
+	if strings.TrimSpace(a.Street) == "" || strings.TrimSpace(a.City) == "" || len(a.UF) != 2 || len(a.Country) != 2 {
+		return ErrInvalidAddress
+	}
+	if a.CEP != "" && len(a.CEP) != 8 {
+		return ErrInvalidAddress
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package customer
+
+import "strings"
+
+// ParseDocument accepts a CPF or CNPJ, formatted or not, and returns its
+// digits once both check digits have been verified.
+func ParseDocument(document string) (string, DocumentType, error) {
+	digits := onlyDigits(document)
+	switch {
+	case len(digits) == 11 && ValidCPF(digits):
+		return digits, DocumentCPF, nil
+	case len(digits) == 14 && ValidCNPJ(digits):
+		return digits, DocumentCNPJ, nil
+	}
+	return "", "", ErrInvalidDocument
+}
+
+func ValidCPF(cpf string) bool {
+	if len(cpf) != 11 || repeated(cpf) {
+		return false
+	}
+	return checkDigit(cpf[:9], cpfWeights(10)) == int(cpf[9]-'0') &&
+		checkDigit(cpf[:10], cpfWeights(11)) == int(cpf[10]-'0')
+}
+
+func ValidCNPJ(cnpj string) bool {
+	if len(cnpj) != 14 || repeated(cnpj) {
+		return false
+	}
+	first := []int{5, 4, 3, 2, 9, 8, 7, 6, 5, 4, 3, 2}
+	second := append([]int{6}, first...)
+	return checkDigit(cnpj[:12], first) == int(cnpj[12]-'0') &&
+		checkDigit(cnpj[:13], second) == int(cnpj[13]-'0')
+}
+
+// cpfWeights returns the descending weights start, start-1, ..., 2.
+func cpfWeights(start int) []int {
+	weights := make([]int, 0, start-1)
+	for w := start; w >= 2; w-- {
+		weights = append(weights, w)
+	}
+	return weights
+}
+
+// checkDigit computes the modulo 11 check digit shared by CPF and CNPJ.
+func checkDigit(digits string, weights []int) int {
+	sum := 0
+	for i := 0; i < len(digits); i++ {
+		sum += int(digits[i]-'0') * weights[i]
+	}
+	rest := sum % 11
+	if rest < 2 {
+		return 0
+	}
+	return 11 - rest
+}
+
+// repeated rejects sequences such as 111.111.111-11, which pass the check
+// digit algorithm but are not valid documents.
+func repeated(digits string) bool {
+	return strings.Count(digits, digits[:1]) == len(digits)
+}
+
+func onlyDigits(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
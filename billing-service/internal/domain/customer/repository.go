@@ -0,0 +1,11 @@
+package customer
+
+import "context"
+
+type Repository interface {
+	Create(ctx context.Context, customer *Customer) error
+	GetByID(ctx context.Context, id int) (*Customer, error)
+	List(ctx context.Context) ([]*Customer, error)
+	Update(ctx context.Context, customer *Customer) error
+	Delete(ctx context.Context, id int) error
+}
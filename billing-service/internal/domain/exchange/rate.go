@@ -0,0 +1,167 @@
+// Package exchange converts money between currencies.
+package exchange
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vitorwhois/microservice-invoice-billing/money"
+)
+
+var (
+	ErrRateNotFound = errors.New("exchange rate not found")
+	ErrInvalidRate  = errors.New("invalid exchange rate")
+)
+
+// FactorScale is the number of decimal places kept in a Factor. It matches
+// the NUMERIC(18,8) columns used to store exchange rates.
+const FactorScale = 8
+
+// One is the factor of a currency against itself.
+const One Factor = 100000000
+
+// Factor is an exchange rate in units of 10^-FactorScale: 543210000 is 5.4321.
+type Factor int64
+
+// ParseFactor reads a positive decimal literal with at most FactorScale
+// decimal places, such as "5.4321".
+func ParseFactor(s string) (Factor, error) {
+	intPart, fracPart, _ := strings.Cut(strings.TrimSpace(s), ".")
+	if intPart == "" || len(fracPart) > FactorScale || !digits(intPart) || !digits(fracPart) {
+		return 0, ErrInvalidRate
+	}
+	fracPart += strings.Repeat("0", FactorScale-len(fracPart))
+
+	value, err := strconv.ParseInt(intPart+fracPart, 10, 64)
+	if err != nil || value <= 0 {
+		return 0, ErrInvalidRate
+	}
+	return Factor(value), nil
+}
+
+func digits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Div returns f / g rounded half away from zero, the cross rate between two
+// currencies quoted against the same base.
+func (f Factor) Div(g Factor) Factor {
+	return Factor(divRound(big.NewInt(int64(f)), big.NewInt(int64(One)), big.NewInt(int64(g))))
+}
+
+func (f Factor) String() string {
+	s := strconv.FormatInt(int64(f)/int64(One), 10)
+	frac := strings.TrimRight(fmt.Sprintf("%08d", int64(f)%int64(One)), "0")
+	if frac == "" {
+		return s
+	}
+	return s + "." + frac
+}
+
+func (f Factor) MarshalJSON() ([]byte, error) {
+	return []byte(f.String()), nil
+}
+
+func (f *Factor) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+	parsed, err := ParseFactor(s)
+	if err != nil {
+		return err
+	}
+	*f = parsed
+	return nil
+}
+
+// Scan reads NUMERIC columns, which the postgres driver hands over as text.
+func (f *Factor) Scan(src interface{}) error {
+	var s string
+	switch v := src.(type) {
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	default:
+		return fmt.Errorf("exchange: cannot scan %T", src)
+	}
+	parsed, err := ParseFactor(s)
+	if err != nil {
+		return err
+	}
+	*f = parsed
+	return nil
+}
+
+func (f Factor) Value() (driver.Value, error) {
+	return f.String(), nil
+}
+
+// Rate says that one unit of From is worth Value units of To, as quoted by
+// Source at AsOf.
+type Rate struct {
+	From   string
+	To     string
+	Value  Factor
+	Source string
+	AsOf   time.Time
+}
+
+// Identity is the rate of a currency against itself.
+func Identity(currency string) Rate {
+	return Rate{From: currency, To: currency, Value: One, Source: "identity"}
+}
+
+// Convert expresses m, which must be in the From currency, in the To
+// currency, rounding half away from zero. The result is in the minor units of
+// To, which need not match those of From (BRL to JPY).
+func (r Rate) Convert(m money.Money) (money.Money, error) {
+	if m.Currency != r.From {
+		return money.Money{}, fmt.Errorf("%w: %s and %s", money.ErrCurrencyMismatch, m.Currency, r.From)
+	}
+	if r.Value <= 0 {
+		return money.Money{}, ErrInvalidRate
+	}
+
+	num, den := big.NewInt(int64(r.Value)), big.NewInt(int64(One))
+	shift := money.MinorUnits(r.To) - money.MinorUnits(r.From)
+	switch {
+	case shift > 0:
+		num.Mul(num, pow10(shift))
+	case shift < 0:
+		den.Mul(den, pow10(-shift))
+	}
+	return money.New(divRound(big.NewInt(m.Amount), num, den), r.To), nil
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// divRound returns n * num / d rounded half away from zero. It goes through
+// big.Int because amounts times 10^8 overflow int64.
+func divRound(n, num, d *big.Int) int64 {
+	n = new(big.Int).Mul(n, num)
+	q, r := new(big.Int).QuoRem(n, d, new(big.Int))
+	if r.Mul(r.Abs(r), big.NewInt(2)).Cmp(d) >= 0 {
+		q.Add(q, big.NewInt(int64(n.Sign())))
+	}
+	return q.Int64()
+}
+
+// Provider quotes current exchange rates.
+type Provider interface {
+	Rate(ctx context.Context, from, to string) (Rate, error)
+}
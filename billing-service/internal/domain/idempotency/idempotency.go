@@ -0,0 +1,44 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrKeyInProgress = errors.New("a request with this idempotency key is still being processed")
+	ErrKeyReused     = errors.New("idempotency key was already used with a different request")
+	ErrKeyTakenOver  = errors.New("idempotency key was taken over by a retry of the request")
+)
+
+type Record struct {
+	Key         string
+	RequestHash string
+	Completed   bool
+	StatusCode  int
+	ContentType string
+	Body        []byte
+	// LockToken identifies the reservation that holds the key: a retry
+	// taking over a stale reservation gets a new one
+	LockToken   string
+	LockedAt    time.Time
+	CompletedAt *time.Time
+	CreatedAt   time.Time
+}
+
+type Repository interface {
+	// Reserve stores a new in-progress record for key. An in-progress record
+	// for the same request locked before staleBefore was left behind by a
+	// request that never finished and is taken over. Otherwise, when the key
+	// already exists, the stored record is returned and created is false.
+	Reserve(ctx context.Context, key string, requestHash string, staleBefore time.Time) (rec *Record, created bool, err error)
+	// Complete stores the response and Release deletes the key, so the
+	// request can be retried. Both return ErrKeyTakenOver when rec no longer
+	// holds the key.
+	Complete(ctx context.Context, rec *Record) error
+	Release(ctx context.Context, rec *Record) error
+	// Purge deletes the keys completed, or abandoned, before the given time
+	// and reports how many were removed.
+	Purge(ctx context.Context, before time.Time) (int64, error)
+}
@@ -0,0 +1,235 @@
+package invoice
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/tax"
+	"github.com/vitorwhois/microservice-invoice-billing/money"
+)
+
+var (
+	ErrInvalidAdjustment  = errors.New("invalid discount or surcharge")
+	ErrNegativeTotal      = errors.New("discounts cannot exceed the amount they apply to")
+	ErrAdjustmentNotFound = errors.New("discount or surcharge not found")
+)
+
+type AdjustmentKind string
+
+const (
+	KindDiscount  AdjustmentKind = "DISCOUNT"
+	KindSurcharge AdjustmentKind = "SURCHARGE"
+)
+
+// AdjustmentMethod tells whether an adjustment is a percentage of the amount
+// it applies to or a fixed value.
+type AdjustmentMethod string
+
+const (
+	MethodPercent AdjustmentMethod = "PERCENT"
+	MethodFixed   AdjustmentMethod = "FIXED"
+)
+
+// Adjustment is a discount or surcharge on a single item (ItemID set) or on
+// the whole invoice (ItemID zero). Rate is used by percentage adjustments and
+// Amount by fixed ones.
+type Adjustment struct {
+	ID        int
+	InvoiceID int
+	ItemID    int
+	Kind      AdjustmentKind
+	Method    AdjustmentMethod
+	Rate      tax.Rate
+	Amount    money.Money
+	Reason    string
+	CreatedAt time.Time
+}
+
+func NewAdjustment(itemID int, kind AdjustmentKind, method AdjustmentMethod, rate tax.Rate, amount money.Money, reason string) (*Adjustment, error) {
+	if kind != KindDiscount && kind != KindSurcharge {
+		return nil, ErrInvalidAdjustment
+	}
+
+	adj := &Adjustment{
+		ItemID:    itemID,
+		Kind:      kind,
+		Method:    method,
+		Reason:    reason,
+		CreatedAt: time.Now(),
+	}
+
+	switch method {
+	case MethodPercent:
+		if rate <= 0 || (kind == KindDiscount && rate > 10000) {
+			return nil, ErrInvalidAdjustment
+		}
+		adj.Rate = rate
+		adj.Amount = money.Zero(money.DefaultCurrency)
+	case MethodFixed:
+		if !amount.IsPositive() {
+			return nil, ErrInvalidAdjustment
+		}
+		adj.Amount = amount
+	default:
+		return nil, ErrInvalidAdjustment
+	}
+	return adj, nil
+}
+
+// Value is the amount of the adjustment applied to base.
+func (a *Adjustment) Value(base money.Money) money.Money {
+	if a.Method == MethodPercent {
+		return a.Rate.Of(base)
+	}
+	return a.Amount
+}
+
+func (a *Adjustment) IsInvoiceLevel() bool {
+	return a.ItemID == 0
+}
+
+// AddAdjustment attaches a discount or surcharge to the invoice or to one of
+// its items, refusing it when it would take any amount below zero.
+func (i *Invoice) AddAdjustment(adj *Adjustment) error {
+	if err := i.EnsureEditable(); err != nil {
+		return err
+	}
+	if !adj.IsInvoiceLevel() {
+		if _, err := i.FindItem(adj.ItemID); err != nil {
+			return err
+		}
+	}
+
+	// Fixed amounts are expressed in the invoice currency
+	adj.InvoiceID = i.ID
+	adj.Amount.Currency = i.currency()
+	i.Adjustments = append(i.Adjustments, adj)
+	if err := i.CalculateTotal(); err != nil {
+		i.Adjustments = i.Adjustments[:len(i.Adjustments)-1]
+		i.CalculateTotal()
+		return err
+	}
+	return nil
+}
+
+func (i *Invoice) RemoveAdjustment(adjustmentID int) (*Adjustment, error) {
+	if err := i.EnsureEditable(); err != nil {
+		return nil, err
+	}
+
+	for n, adj := range i.Adjustments {
+		if adj.ID == adjustmentID {
+			adjustments := append([]*Adjustment(nil), i.Adjustments...)
+			i.Adjustments = append(i.Adjustments[:n], i.Adjustments[n+1:]...)
+
+			// Dropping a surcharge may leave a discount larger than the rest
+			if err := i.CalculateTotal(); err != nil {
+				i.Adjustments = adjustments
+				i.CalculateTotal()
+				return nil, err
+			}
+			return adj, nil
+		}
+	}
+	return nil, ErrAdjustmentNotFound
+}
+
+// applyAdjustments sets the Discount and Surcharge of every item. Item-level
+// adjustments apply to the item subtotal; invoice-level ones apply to the sum
+// of the adjusted items and are spread across them in proportion to their
+// amounts, so the tax base of each item reflects its share.
+func (i *Invoice) applyAdjustments() error {
+	zero := money.Zero(i.currency())
+	for _, item := range i.Items {
+		item.Discount, item.Surcharge = zero, zero
+		for _, adj := range i.Adjustments {
+			if adj.ItemID != item.ID {
+				continue
+			}
+			if adj.Kind == KindDiscount {
+				item.Discount = item.Discount.Add(adj.Value(item.Subtotal()))
+			} else {
+				item.Surcharge = item.Surcharge.Add(adj.Value(item.Subtotal()))
+			}
+		}
+		if item.Net().IsNegative() {
+			return ErrNegativeTotal
+		}
+	}
+
+	base := zero
+	for _, item := range i.Items {
+		base = base.Add(item.Net())
+	}
+
+	discount, surcharge := zero, zero
+	for _, adj := range i.Adjustments {
+		if !adj.IsInvoiceLevel() {
+			continue
+		}
+		if adj.Kind == KindDiscount {
+			discount = discount.Add(adj.Value(base))
+		} else {
+			surcharge = surcharge.Add(adj.Value(base))
+		}
+	}
+	if discount.Cmp(base.Add(surcharge)) > 0 {
+		return ErrNegativeTotal
+	}
+
+	weights := make([]money.Money, len(i.Items))
+	for n, item := range i.Items {
+		weights[n] = item.Net()
+	}
+	for n, share := range allocate(discount, weights) {
+		i.Items[n].Discount = i.Items[n].Discount.Add(share)
+	}
+	for n, share := range allocate(surcharge, weights) {
+		i.Items[n].Surcharge = i.Items[n].Surcharge.Add(share)
+	}
+
+	for _, item := range i.Items {
+		if item.Net().IsNegative() {
+			return ErrNegativeTotal
+		}
+	}
+	return nil
+}
+
+// allocate splits amount in proportion to weights. Rounding leftovers go to
+// the largest weights first, so the shares always add up to amount.
+func allocate(amount money.Money, weights []money.Money) []money.Money {
+	shares := make([]money.Money, len(weights))
+	var total int64
+	for n, w := range weights {
+		shares[n] = money.Zero(amount.Currency)
+		total += w.Amount
+	}
+	if total <= 0 || amount.IsZero() {
+		return shares
+	}
+
+	allocated := int64(0)
+	for n, w := range weights {
+		shares[n].Amount = amount.Amount * w.Amount / total
+		allocated += shares[n].Amount
+	}
+
+	order := make([]int, len(weights))
+	for n := range order {
+		order[n] = n
+	}
+	sort.SliceStable(order, func(a, b int) bool { return weights[order[a]].Amount > weights[order[b]].Amount })
+
+	for left := amount.Amount - allocated; left > 0; {
+		for _, n := range order {
+			if left == 0 {
+				break
+			}
+			shares[n].Amount++
+			left--
+		}
+	}
+	return shares
+}
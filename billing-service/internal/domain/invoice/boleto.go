@@ -0,0 +1,59 @@
+package invoice
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/boleto"
+	"github.com/vitorwhois/microservice-invoice-billing/money"
+)
+
+// Boleto is a bank slip issued to collect an invoice. Sequence is the number
+// the slip was generated from; OurNumber is how the bank prints it.
+type Boleto struct {
+	ID            int
+	InvoiceID     int
+	BankCode      string
+	Sequence      int64
+	OurNumber     string
+	DueDate       time.Time
+	Amount        money.Money
+	Barcode       string
+	DigitableLine string
+	CreatedAt     time.Time
+}
+
+// IssueBoleto records a boleto generated for amount, the part of the invoice
+// still to be paid. Like payments, boletos are only issued for invoices that
+// were issued and are not fully paid.
+func (i *Invoice) IssueBoleto(bankCode string, sequence int64, code boleto.Code, dueDate time.Time, amount money.Money) (*Boleto, error) {
+	if i.Status == StatusPaid {
+		return nil, ErrAlreadyPaid
+	}
+	if i.Status != StatusIssued && i.Status != StatusPartiallyPaid {
+		return nil, fmt.Errorf("%w: boletos are only issued for issued invoices", ErrInvalidStatus)
+	}
+
+	b := &Boleto{
+		InvoiceID:     i.ID,
+		BankCode:      bankCode,
+		Sequence:      sequence,
+		OurNumber:     code.OurNumber,
+		DueDate:       dueDate,
+		Amount:        amount,
+		Barcode:       code.Barcode,
+		DigitableLine: code.DigitableLine,
+		CreatedAt:     time.Now(),
+	}
+
+	i.record(EventBoletoIssued, struct {
+		InvoiceID     int         `json:"invoice_id"`
+		BankCode      string      `json:"bank_code"`
+		OurNumber     string      `json:"our_number"`
+		DueDate       string      `json:"due_date"`
+		Amount        money.Money `json:"amount"`
+		DigitableLine string      `json:"digitable_line"`
+	}{i.ID, bankCode, code.OurNumber, dueDate.Format("2006-01-02"), amount, code.DigitableLine})
+
+	return b, nil
+}
@@ -0,0 +1,150 @@
+package invoice
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/vitorwhois/microservice-invoice-billing/money"
+)
+
+var (
+	ErrCancellationWindowExpired = errors.New("cancellation window has expired")
+	ErrNothingToCredit           = errors.New("nothing left to credit on this invoice")
+	ErrCreditExceedsInvoice      = errors.New("credited quantity exceeds the invoiced quantity")
+	ErrCreditNoteNotFound        = errors.New("credit note not found")
+)
+
+// CreditNote reverses all or part of an issued invoice. Each line references
+// the invoice item it credits; Returned tracks whether its units have already
+// been put back in inventory.
+type CreditNote struct {
+	ID         int
+	InvoiceID  int
+	Number     string
+	Reason     string
+	Items      []*CreditNoteItem
+	TotalValue money.Money
+	CreatedAt  time.Time
+}
+
+// CreditNoteItem credits Quantity units of an invoice item. Price is the unit
+// price invoiced; Amount is what is given back for the units, their share of
+// what the buyer was charged for the line.
+type CreditNoteItem struct {
+	ID            int
+	CreditNoteID  int
+	InvoiceItemID int
+	ProductID     int
+	Quantity      int
+	Price         money.Money
+	Amount        money.Money
+	Returned      bool
+}
+
+// CreditLine asks for quantity units of an invoice item to be credited.
+type CreditLine struct {
+	InvoiceItemID int
+	Quantity      int
+}
+
+// IssueCreditNote credits the given lines, or everything not credited yet
+// when lines is empty. previous holds the credit notes already issued for the
+// invoice. The note is numbered when it is saved, see CreditNoteIssued.
+func (i *Invoice) IssueCreditNote(lines []CreditLine, reason string, previous []*CreditNote) (*CreditNote, error) {
+	if !i.IsIssued() {
+		return nil, fmt.Errorf("%w: only issued invoices can be credited", ErrInvalidStatus)
+	}
+
+	remaining := make(map[int]int, len(i.Items))
+	for _, item := range i.Items {
+		remaining[item.ID] = item.Quantity
+	}
+	for _, note := range previous {
+		for _, line := range note.Items {
+			remaining[line.InvoiceItemID] -= line.Quantity
+		}
+	}
+
+	if len(lines) == 0 {
+		for _, item := range i.Items {
+			if remaining[item.ID] > 0 {
+				lines = append(lines, CreditLine{InvoiceItemID: item.ID, Quantity: remaining[item.ID]})
+			}
+		}
+		if len(lines) == 0 {
+			return nil, ErrNothingToCredit
+		}
+	}
+
+	now := time.Now()
+	note := &CreditNote{
+		InvoiceID:  i.ID,
+		Reason:     reason,
+		TotalValue: money.Zero(i.TotalValue.Currency),
+		CreatedAt:  now,
+	}
+
+	for _, line := range lines {
+		item, err := i.FindItem(line.InvoiceItemID)
+		if err != nil {
+			return nil, err
+		}
+		if line.Quantity <= 0 || line.Quantity > remaining[item.ID] {
+			return nil, ErrCreditExceedsInvoice
+		}
+		before := item.Quantity - remaining[item.ID]
+		remaining[item.ID] -= line.Quantity
+
+		credited := &CreditNoteItem{
+			InvoiceItemID: item.ID,
+			ProductID:     item.ProductID,
+			Quantity:      line.Quantity,
+			Price:         item.Price,
+			Amount:        creditedShare(item, before, line.Quantity),
+		}
+		note.Items = append(note.Items, credited)
+		note.TotalValue = note.TotalValue.Add(credited.Amount)
+	}
+
+	return note, nil
+}
+
+// CreditNoteIssued numbers note as the seq-th credit note of the invoice and
+// records its issue. It is called once seq has been allocated under a lock on
+// the invoice, so concurrent notes never share a number.
+func (i *Invoice) CreditNoteIssued(note *CreditNote, seq int) {
+	note.Number = fmt.Sprintf("%s-NC%02d", i.Number, seq)
+
+	i.note(fmt.Sprintf("credit note %s issued for %s: %s", note.Number, note.TotalValue, note.Reason))
+	i.record(EventCreditNoteIssued, struct {
+		InvoiceID  int         `json:"invoice_id"`
+		Number     string      `json:"number"`
+		Reason     string      `json:"reason"`
+		TotalValue money.Money `json:"total_value"`
+	}{i.ID, note.Number, note.Reason, note.TotalValue})
+}
+
+// creditedShare is the part of what was charged for item that quantity units
+// are worth, after before units were credited already. Shares are taken from
+// the running total so that crediting every unit gives back exactly the
+// amount charged, whatever the rounding of each note.
+func creditedShare(item *InvoiceItem, before, quantity int) money.Money {
+	charged := item.Charged()
+	total := int64(item.Quantity)
+	upTo := charged.MulRatio(int64(before+quantity), total)
+	return upTo.Sub(charged.MulRatio(int64(before), total))
+}
+
+// EnsureCancellable checks that an issued invoice is still inside the
+// cancellation window. Invoices that were never issued can always be
+// cancelled.
+func (i *Invoice) EnsureCancellable(now time.Time, window time.Duration) error {
+	if i.Status != StatusIssued || i.ClosedAt == nil {
+		return nil
+	}
+	if now.After(i.ClosedAt.Add(window)) {
+		return ErrCancellationWindowExpired
+	}
+	return nil
+}
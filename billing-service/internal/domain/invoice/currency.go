@@ -0,0 +1,86 @@
+package invoice
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/exchange"
+	"github.com/vitorwhois/microservice-invoice-billing/money"
+)
+
+var ErrInvalidCurrency = errors.New("invalid currency")
+
+// SetCurrency denominates the invoice in an ISO 4217 currency, BRL when
+// empty. It is meant for invoices that have no items yet.
+func (i *Invoice) SetCurrency(currency string) error {
+	if currency == "" {
+		currency = money.DefaultCurrency
+	}
+	if !money.ValidCurrency(currency) {
+		return ErrInvalidCurrency
+	}
+	if len(i.Items) > 0 && currency != i.currency() {
+		return fmt.Errorf("%w: the currency of an invoice with items cannot change", ErrInvalidStatus)
+	}
+
+	i.Currency = currency
+	return i.CalculateTotal()
+}
+
+// currency falls back to BRL for invoices created before currencies existed.
+func (i *Invoice) currency() string {
+	if i.Currency == "" {
+		return money.DefaultCurrency
+	}
+	return i.Currency
+}
+
+// ForeignCurrencies lists the currencies, other than the invoice one, its
+// items are quoted in.
+func (i *Invoice) ForeignCurrencies() []string {
+	var currencies []string
+	seen := map[string]bool{i.currency(): true}
+	for _, item := range i.Items {
+		if c := item.BasePrice.Currency; c != "" && !seen[c] {
+			seen[c] = true
+			currencies = append(currencies, c)
+		}
+	}
+	return currencies
+}
+
+// FreezeRates converts the items quoted in a foreign currency at rates and
+// keeps the rates on the invoice. Once it is issued the converted prices are
+// the ones stored, so later rate changes do not move its totals.
+func (i *Invoice) FreezeRates(rates []exchange.Rate) error {
+	if i.IsIssued() {
+		return ErrAlreadyClosed
+	}
+
+	byCurrency := make(map[string]exchange.Rate, len(rates))
+	for _, rate := range rates {
+		if rate.To != i.currency() {
+			return fmt.Errorf("%w: rate from %s to %s on a %s invoice", exchange.ErrInvalidRate, rate.From, rate.To, i.currency())
+		}
+		byCurrency[rate.From] = rate
+	}
+
+	for _, item := range i.Items {
+		if item.BasePrice.Currency == "" || item.BasePrice.Currency == i.currency() {
+			continue
+		}
+		rate, ok := byCurrency[item.BasePrice.Currency]
+		if !ok {
+			return fmt.Errorf("%w: %s to %s", exchange.ErrRateNotFound, item.BasePrice.Currency, i.currency())
+		}
+		price, err := rate.Convert(item.BasePrice)
+		if err != nil {
+			return err
+		}
+		item.Price = price
+	}
+
+	// A fixed discount may not fit prices converted at a lower rate
+	i.ExchangeRates = rates
+	return i.CalculateTotal()
+}
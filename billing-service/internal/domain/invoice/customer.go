@@ -0,0 +1,35 @@
+package invoice
+
+// CustomerSnapshot is the buyer as it was when the invoice was closed. Later
+// edits to the customer record do not change issued invoices.
+type CustomerSnapshot struct {
+	CustomerID        int
+	Name              string
+	Document          string
+	DocumentType      string
+	Email             string
+	StateRegistration string
+	Address           *CustomerAddress
+}
+
+type CustomerAddress struct {
+	Street     string
+	Number     string
+	Complement string
+	District   string
+	CityCode   string
+	City       string
+	UF         string
+	CEP        string
+	// Country is empty in snapshots taken before it was recorded: Brazil
+	Country string
+}
+
+// FreezeCustomer stores the buyer snapshot. It is taken once, when the invoice
+// is closed.
+func (i *Invoice) FreezeCustomer(snapshot *CustomerSnapshot) {
+	if i.Customer != nil {
+		return
+	}
+	i.Customer = snapshot
+}
@@ -0,0 +1,150 @@
+package invoice
+
+import (
+	"time"
+
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/tax"
+	"github.com/vitorwhois/microservice-invoice-billing/money"
+)
+
+const (
+	EventInvoiceCreated           = "InvoiceCreated"
+	EventInvoiceItemAdded         = "InvoiceItemAdded"
+	EventInvoiceItemUpdated       = "InvoiceItemUpdated"
+	EventInvoiceItemRemoved       = "InvoiceItemRemoved"
+	EventInvoiceClosed            = "InvoiceClosed"
+	EventInvoicePrintFailed       = "InvoicePrintFailed"
+	EventInvoiceStatusChanged     = "InvoiceStatusChanged"
+	EventCreditNoteIssued         = "CreditNoteIssued"
+	EventInvoiceAdjustmentAdded   = "InvoiceAdjustmentAdded"
+	EventInvoiceAdjustmentRemoved = "InvoiceAdjustmentRemoved"
+	EventPaymentRecorded          = "PaymentRecorded"
+	EventBoletoIssued             = "BoletoIssued"
+	EventInstallmentOverdue       = "InstallmentOverdue"
+)
+
+// Event is a fact about an invoice. Repositories store events in the outbox
+// within the same transaction as the change that produced them.
+type Event struct {
+	Name       string
+	InvoiceID  int
+	OccurredAt time.Time
+	Payload    interface{}
+}
+
+func (i *Invoice) record(name string, payload interface{}) {
+	i.events = append(i.events, Event{
+		Name:       name,
+		InvoiceID:  i.ID,
+		OccurredAt: time.Now(),
+		Payload:    payload,
+	})
+}
+
+// PullEvents returns the events recorded since the last call and clears them.
+func (i *Invoice) PullEvents() []Event {
+	events := i.events
+	i.events = nil
+	return events
+}
+
+func CreatedEvent(inv *Invoice) Event {
+	return Event{
+		Name:       EventInvoiceCreated,
+		InvoiceID:  inv.ID,
+		OccurredAt: inv.CreatedAt,
+		Payload: struct {
+			InvoiceID int       `json:"invoice_id"`
+			Number    string    `json:"number"`
+			Status    Status    `json:"status"`
+			Currency  string    `json:"currency"`
+			CreatedAt time.Time `json:"created_at"`
+		}{inv.ID, inv.Number, inv.Status, inv.currency(), inv.CreatedAt},
+	}
+}
+
+func ItemAddedEvent(item *InvoiceItem) Event {
+	return Event{
+		Name:       EventInvoiceItemAdded,
+		InvoiceID:  item.InvoiceID,
+		OccurredAt: time.Now(),
+		Payload: struct {
+			InvoiceID int         `json:"invoice_id"`
+			ItemID    int         `json:"item_id"`
+			ProductID int         `json:"product_id"`
+			Quantity  int         `json:"quantity"`
+			Price     money.Money `json:"price"`
+			Name      string      `json:"name"`
+		}{item.InvoiceID, item.ID, item.ProductID, item.Quantity, item.Price, item.Name},
+	}
+}
+
+func ItemUpdatedEvent(item *InvoiceItem, previousQuantity int) Event {
+	return Event{
+		Name:       EventInvoiceItemUpdated,
+		InvoiceID:  item.InvoiceID,
+		OccurredAt: time.Now(),
+		Payload: struct {
+			InvoiceID        int `json:"invoice_id"`
+			ItemID           int `json:"item_id"`
+			ProductID        int `json:"product_id"`
+			PreviousQuantity int `json:"previous_quantity"`
+			Quantity         int `json:"quantity"`
+		}{item.InvoiceID, item.ID, item.ProductID, previousQuantity, item.Quantity},
+	}
+}
+
+func ItemRemovedEvent(item *InvoiceItem) Event {
+	return Event{
+		Name:       EventInvoiceItemRemoved,
+		InvoiceID:  item.InvoiceID,
+		OccurredAt: time.Now(),
+		Payload: struct {
+			InvoiceID int `json:"invoice_id"`
+			ItemID    int `json:"item_id"`
+			ProductID int `json:"product_id"`
+			Quantity  int `json:"quantity"`
+		}{item.InvoiceID, item.ID, item.ProductID, item.Quantity},
+	}
+}
+
+// PrintFailed records that an attempt to print the invoice did not complete.
+func (i *Invoice) PrintFailed(stepReached string, reason string) {
+	i.record(EventInvoicePrintFailed, struct {
+		InvoiceID   int    `json:"invoice_id"`
+		StepReached string `json:"step_reached"`
+		Reason      string `json:"reason"`
+	}{i.ID, stepReached, reason})
+}
+
+func AdjustmentAddedEvent(adj *Adjustment) Event {
+	return Event{
+		Name:       EventInvoiceAdjustmentAdded,
+		InvoiceID:  adj.InvoiceID,
+		OccurredAt: adj.CreatedAt,
+		Payload: struct {
+			InvoiceID    int              `json:"invoice_id"`
+			AdjustmentID int              `json:"adjustment_id"`
+			ItemID       int              `json:"item_id,omitempty"`
+			Kind         AdjustmentKind   `json:"kind"`
+			Method       AdjustmentMethod `json:"method"`
+			Rate         tax.Rate         `json:"rate"`
+			Amount       money.Money      `json:"amount"`
+			Reason       string           `json:"reason"`
+		}{adj.InvoiceID, adj.ID, adj.ItemID, adj.Kind, adj.Method, adj.Rate, adj.Amount, adj.Reason},
+	}
+}
+
+func AdjustmentRemovedEvent(adj *Adjustment) Event {
+	return Event{
+		Name:       EventInvoiceAdjustmentRemoved,
+		InvoiceID:  adj.InvoiceID,
+		OccurredAt: time.Now(),
+		Payload: struct {
+			InvoiceID    int            `json:"invoice_id"`
+			AdjustmentID int            `json:"adjustment_id"`
+			ItemID       int            `json:"item_id,omitempty"`
+			Kind         AdjustmentKind `json:"kind"`
+		}{adj.InvoiceID, adj.ID, adj.ItemID, adj.Kind},
+	}
+}
@@ -0,0 +1,221 @@
+package invoice
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vitorwhois/microservice-invoice-billing/money"
+)
+
+var ErrInvalidTerms = errors.New("invalid payment terms")
+
+const (
+	maxInstallments = 24
+	maxTermDays     = 3650
+)
+
+// PaymentTerms lists, in days after the invoice is issued, when each
+// installment falls due: "30" is net 30, "30/60/90" splits the total into
+// three installments. "0" is due on issue.
+type PaymentTerms []int
+
+func ParsePaymentTerms(s string) (PaymentTerms, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+
+	parts := strings.Split(s, "/")
+	if len(parts) > maxInstallments {
+		return nil, ErrInvalidTerms
+	}
+	terms := make(PaymentTerms, 0, len(parts))
+	for _, part := range parts {
+		days, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || days < 0 || days > maxTermDays {
+			return nil, ErrInvalidTerms
+		}
+		if len(terms) > 0 && days <= terms[len(terms)-1] {
+			return nil, ErrInvalidTerms
+		}
+		terms = append(terms, days)
+	}
+	return terms, nil
+}
+
+func (t PaymentTerms) String() string {
+	parts := make([]string, len(t))
+	for n, days := range t {
+		parts[n] = strconv.Itoa(days)
+	}
+	return strings.Join(parts, "/")
+}
+
+func (t PaymentTerms) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(t.String())), nil
+}
+
+func (t *PaymentTerms) UnmarshalJSON(data []byte) error {
+	s, err := strconv.Unquote(string(data))
+	if err != nil {
+		return ErrInvalidTerms
+	}
+	parsed, err := ParsePaymentTerms(s)
+	if err != nil {
+		return err
+	}
+	*t = parsed
+	return nil
+}
+
+func (t *PaymentTerms) Scan(src interface{}) error {
+	var s string
+	switch v := src.(type) {
+	case nil:
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		return fmt.Errorf("cannot scan %T into PaymentTerms", src)
+	}
+	parsed, err := ParsePaymentTerms(s)
+	if err != nil {
+		return err
+	}
+	*t = parsed
+	return nil
+}
+
+func (t PaymentTerms) Value() (driver.Value, error) {
+	return t.String(), nil
+}
+
+type InstallmentStatus string
+
+const (
+	InstallmentPending InstallmentStatus = "PENDING"
+	InstallmentPaid    InstallmentStatus = "PAID"
+	InstallmentOverdue InstallmentStatus = "OVERDUE"
+)
+
+// Installment is one due-dated part of an issued invoice. Payments settle
+// installments in order of due date.
+type Installment struct {
+	ID        int
+	InvoiceID int
+	Number    int
+	DueDate   time.Time
+	Amount    money.Money
+	Status    InstallmentStatus
+	PaidAt    *time.Time
+}
+
+// SetPaymentTerms changes the terms the installments are generated from when
+// the invoice is issued.
+func (i *Invoice) SetPaymentTerms(terms PaymentTerms) error {
+	if err := i.EnsureEditable(); err != nil {
+		return err
+	}
+	i.PaymentTerms = terms
+	return nil
+}
+
+// scheduleInstallments splits the total into one installment per term, due
+// that many days after issuedAt. Cents that do not divide evenly go to the
+// first installments. Invoices without terms are due on issue.
+func (i *Invoice) scheduleInstallments(issuedAt time.Time) {
+	terms := i.PaymentTerms
+	if len(terms) == 0 {
+		terms = PaymentTerms{0}
+	}
+
+	// An installment is never for nothing: there is no schedule when nothing
+	// is owed, and a total smaller than the number of terms only uses the
+	// first ones
+	i.Installments = nil
+	if !i.TotalValue.IsPositive() {
+		return
+	}
+	if int64(len(terms)) > i.TotalValue.Amount {
+		terms = terms[:i.TotalValue.Amount]
+	}
+
+	day := time.Date(issuedAt.Year(), issuedAt.Month(), issuedAt.Day(), 0, 0, 0, 0, time.UTC)
+	count := int64(len(terms))
+	share := i.TotalValue.Amount / count
+	remainder := i.TotalValue.Amount % count
+
+	i.Installments = make([]*Installment, 0, len(terms))
+	for n, days := range terms {
+		amount := share
+		if int64(n) < remainder {
+			amount++
+		}
+		i.Installments = append(i.Installments, &Installment{
+			InvoiceID: i.ID,
+			Number:    n + 1,
+			DueDate:   day.AddDate(0, 0, days),
+			Amount:    money.Money{Amount: amount, Currency: i.TotalValue.Currency},
+			Status:    InstallmentPending,
+		})
+	}
+}
+
+// settleInstallments marks as paid every installment covered by paid, the
+// total received or credited so far.
+func (i *Invoice) settleInstallments(paid money.Money, at time.Time) {
+	covered := money.Zero(paid.Currency)
+	for _, inst := range i.Installments {
+		covered = covered.Add(inst.Amount)
+		if covered.Cmp(paid) > 0 {
+			return
+		}
+		if inst.Status != InstallmentPaid {
+			inst.Status = InstallmentPaid
+			inst.PaidAt = &at
+		}
+	}
+}
+
+// MarkOverdue flags the pending installments due before asOf's day and
+// returns them. Only invoices still waiting for payment have overdue
+// installments.
+func (i *Invoice) MarkOverdue(asOf time.Time) []*Installment {
+	if i.Status != StatusIssued && i.Status != StatusPartiallyPaid {
+		return nil
+	}
+
+	today := asOf.Format("2006-01-02")
+	var overdue []*Installment
+	for _, inst := range i.Installments {
+		if inst.Status != InstallmentPending || inst.DueDate.Format("2006-01-02") >= today {
+			continue
+		}
+		inst.Status = InstallmentOverdue
+		overdue = append(overdue, inst)
+
+		i.note(fmt.Sprintf("installment %d of %s due on %s is overdue", inst.Number, inst.Amount, inst.DueDate.Format("2006-01-02")))
+		i.record(EventInstallmentOverdue, struct {
+			InvoiceID int         `json:"invoice_id"`
+			Number    int         `json:"installment"`
+			DueDate   string      `json:"due_date"`
+			Amount    money.Money `json:"amount"`
+		}{i.ID, inst.Number, inst.DueDate.Format("2006-01-02"), inst.Amount})
+	}
+	return overdue
+}
+
+// Overdue reports whether any installment is past due and unpaid.
+func (i *Invoice) Overdue() bool {
+	for _, inst := range i.Installments {
+		if inst.Status == InstallmentOverdue {
+			return true
+		}
+	}
+	return false
+}
@@ -4,59 +4,146 @@ import (
 	"errors"
 	"log"
 	"time"
-)
 
-var (
-	ErrInvalidStatus = errors.New("invalid invoice status")
-	ErrAlreadyClosed = errors.New("invoice already closed")
-	ErrEmptyInvoice  = errors.New("invoice has no items")
-	ErrNotFound      = errors.New("invoice not found")
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/exchange"
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/tax"
+	"github.com/vitorwhois/microservice-invoice-billing/money"
 )
 
-type Status string
-
-const (
-	StatusOpen   Status = "OPEN"
-	StatusClosed Status = "CLOSED"
+var (
+	ErrInvalidStatus    = errors.New("invalid invoice status")
+	ErrAlreadyClosed    = errors.New("invoice already closed")
+	ErrEmptyInvoice     = errors.New("invoice has no items")
+	ErrNotFound         = errors.New("invoice not found")
+	ErrItemNotFound     = errors.New("invoice item not found")
+	ErrDuplicateNumber  = errors.New("invoice number already in use")
+	ErrPrintInProgress  = errors.New("invoice is already being printed")
+	ErrConcurrentUpdate = errors.New("invoice was modified concurrently")
 )
 
+// InvoiceItem is a line of the invoice. Price is in the invoice currency;
+// BasePrice is the product price in the currency the inventory quotes it in,
+// from which Price is converted.
 type InvoiceItem struct {
 	ID        int
 	InvoiceID int
 	ProductID int
 	Quantity  int
-	Price     float64
+	Price     money.Money
+	BasePrice money.Money
 	Name      string
+	NCM       string
+	Taxes     []tax.Line
+	Discount  money.Money
+	Surcharge money.Money
+	// ReservationID is the inventory reservation holding the line's stock
+	ReservationID int
+}
+
+func (it *InvoiceItem) Subtotal() money.Money {
+	return it.Price.Mul(int64(it.Quantity))
+}
+
+// Net is the item subtotal after its discounts and surcharges, including its
+// share of the invoice-level ones.
+func (it *InvoiceItem) Net() money.Money {
+	return it.Subtotal().Sub(it.Discount).Add(it.Surcharge)
+}
+
+// Charged is what the buyer pays for the item: its net amount plus the taxes
+// not already included in the price.
+func (it *InvoiceItem) Charged() money.Money {
+	total := it.Net()
+	for _, line := range it.Taxes {
+		if !line.Included {
+			total = total.Add(line.Amount)
+		}
+	}
+	return total
+}
+
+// SetTaxes replaces the item's tax breakdown.
+func (it *InvoiceItem) SetTaxes(ncm string, lines []tax.Line) {
+	it.NCM = ncm
+	it.Taxes = lines
+}
+
+// Tax returns the line of the given kind, if the item is charged it.
+func (it *InvoiceItem) Tax(kind tax.Kind) (tax.Line, bool) {
+	for _, line := range it.Taxes {
+		if line.Kind == kind {
+			return line, true
+		}
+	}
+	return tax.Line{}, false
 }
 
 type Invoice struct {
-	ID         int
-	Number     string
-	Status     Status
-	CreatedAt  time.Time
-	ClosedAt   *time.Time
-	Items      []*InvoiceItem
-	TotalValue float64
+	ID       int
+	Number   string
+	Series   string
+	Period   int
+	Sequence int
+	// DocumentNumber counts the invoices of the series without ever
+	// restarting; it is the NF-e number
+	DocumentNumber int
+	Status         Status
+	Currency       string
+	CreatedAt      time.Time
+	ClosedAt       *time.Time
+	Items          []*InvoiceItem
+	Adjustments    []*Adjustment
+	Subtotal       money.Money
+	DiscountTotal  money.Money
+	SurchargeTotal money.Money
+	TaxTotal       money.Money
+	TotalValue     money.Money
+	CustomerID     int
+	Customer       *CustomerSnapshot
+	PaymentTerms   PaymentTerms
+	Installments   []*Installment
+	ExchangeRates  []exchange.Rate
+	Version        int
+
+	events      []Event
+	transitions []Transition
 }
 
 func NewInvoice(number string) *Invoice {
 	return &Invoice{
-		Number:    number,
-		Status:    StatusOpen,
-		CreatedAt: time.Now(),
-		Items:     make([]*InvoiceItem, 0),
+		Number:         number,
+		Status:         StatusOpen,
+		Currency:       money.DefaultCurrency,
+		CreatedAt:      time.Now(),
+		Version:        1,
+		Items:          make([]*InvoiceItem, 0),
+		Adjustments:    make([]*Adjustment, 0),
+		Subtotal:       money.Zero(money.DefaultCurrency),
+		DiscountTotal:  money.Zero(money.DefaultCurrency),
+		SurchargeTotal: money.Zero(money.DefaultCurrency),
+		TaxTotal:       money.Zero(money.DefaultCurrency),
+		TotalValue:     money.Zero(money.DefaultCurrency),
 	}
 }
 
-func (i *Invoice) AddItem(productID int, quantity int, price float64, name string) *InvoiceItem {
+// NewDraftInvoice creates an invoice that has to be opened before printing.
+func NewDraftInvoice(number string) *Invoice {
+	inv := NewInvoice(number)
+	inv.Status = StatusDraft
+	return inv
+}
+
+func (i *Invoice) AddItem(productID int, quantity int, price money.Money, name string) *InvoiceItem {
 	item := &InvoiceItem{
 		ProductID: productID,
 		Quantity:  quantity,
 		Price:     price,
+		BasePrice: price,
 		Name:      name,
 	}
 	log.Printf("Adicionando item ao invoice: %v", item)
 
+	// A new line only widens what the discounts apply to
 	i.Items = append(i.Items, item)
 
 	i.CalculateTotal()
@@ -64,8 +151,67 @@ func (i *Invoice) AddItem(productID int, quantity int, price float64, name strin
 	return item
 }
 
+func (i *Invoice) FindItem(itemID int) (*InvoiceItem, error) {
+	for _, item := range i.Items {
+		if item.ID == itemID {
+			return item, nil
+		}
+	}
+	return nil, ErrItemNotFound
+}
+
+// UpdateItemQuantity changes the quantity of a line and returns it together
+// with the quantity it had before.
+func (i *Invoice) UpdateItemQuantity(itemID int, quantity int) (*InvoiceItem, int, error) {
+	if err := i.EnsureEditable(); err != nil {
+		return nil, 0, err
+	}
+
+	item, err := i.FindItem(itemID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	previous := item.Quantity
+	item.Quantity = quantity
+
+	// A fixed discount may no longer fit the smaller amount
+	if err := i.CalculateTotal(); err != nil {
+		item.Quantity = previous
+		i.CalculateTotal()
+		return nil, 0, err
+	}
+	return item, previous, nil
+}
+
+func (i *Invoice) RemoveItem(itemID int) (*InvoiceItem, error) {
+	if err := i.EnsureEditable(); err != nil {
+		return nil, err
+	}
+
+	for n, item := range i.Items {
+		if item.ID == itemID {
+			items := append([]*InvoiceItem(nil), i.Items...)
+			adjustments := append([]*Adjustment(nil), i.Adjustments...)
+
+			i.Items = append(i.Items[:n], i.Items[n+1:]...)
+			i.dropItemAdjustments(itemID)
+
+			// A fixed invoice discount may no longer fit the other lines
+			if err := i.CalculateTotal(); err != nil {
+				i.Items, i.Adjustments = items, adjustments
+				i.CalculateTotal()
+				return nil, err
+			}
+			return item, nil
+		}
+	}
+	return nil, ErrItemNotFound
+}
+
+// Close issues an invoice that is being printed.
 func (i *Invoice) Close() error {
-	if i.Status == StatusClosed {
+	if i.IsIssued() {
 		return ErrAlreadyClosed
 	}
 
@@ -73,16 +219,66 @@ func (i *Invoice) Close() error {
 		return ErrEmptyInvoice
 	}
 
-	i.Status = StatusClosed
+	if err := i.transitionTo(StatusIssued, "printed"); err != nil {
+		return err
+	}
 	now := time.Now()
 	i.ClosedAt = &now
+	i.scheduleInstallments(now)
+
+	i.record(EventInvoiceClosed, struct {
+		InvoiceID  int         `json:"invoice_id"`
+		Number     string      `json:"number"`
+		TotalValue money.Money `json:"total_value"`
+		Currency   string      `json:"currency"`
+		ClosedAt   time.Time   `json:"closed_at"`
+	}{i.ID, i.Number, i.TotalValue, i.currency(), now})
 
+	// Nothing to collect: it is settled as soon as it is issued
+	if i.TotalValue.IsZero() {
+		return i.MarkPaid("nothing to pay")
+	}
 	return nil
 }
-func (i *Invoice) CalculateTotal() {
-	total := 0.0
+
+func (i *Invoice) dropItemAdjustments(itemID int) {
+	kept := i.Adjustments[:0]
+	for _, adj := range i.Adjustments {
+		if adj.ItemID != itemID {
+			kept = append(kept, adj)
+		}
+	}
+	i.Adjustments = kept
+}
+
+// CalculateTotal spreads the discounts and surcharges over the items, sums the
+// items into Subtotal, all their taxes into TaxTotal and sets TotalValue to
+// what the buyer pays: the adjusted subtotal plus the taxes not already
+// included in the prices.
+//
+// It fails with ErrNegativeTotal when the discounts no longer fit the amounts
+// they apply to; the totals are summed from the items all the same.
+func (i *Invoice) CalculateTotal() error {
+	err := i.applyAdjustments()
+
+	subtotal := money.Zero(i.currency())
+	discounts := money.Zero(i.currency())
+	surcharges := money.Zero(i.currency())
+	taxes := money.Zero(i.currency())
+	total := money.Zero(i.currency())
 	for _, item := range i.Items {
-		total += float64(item.Quantity) * item.Price
+		subtotal = subtotal.Add(item.Subtotal())
+		discounts = discounts.Add(item.Discount)
+		surcharges = surcharges.Add(item.Surcharge)
+		total = total.Add(item.Charged())
+		for _, line := range item.Taxes {
+			taxes = taxes.Add(line.Amount)
+		}
 	}
+	i.Subtotal = subtotal
+	i.DiscountTotal = discounts
+	i.SurchargeTotal = surcharges
+	i.TaxTotal = taxes
 	i.TotalValue = total
+	return err
 }
@@ -0,0 +1,156 @@
+package invoice
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/vitorwhois/microservice-invoice-billing/money"
+)
+
+var (
+	ErrInvalidPayment = errors.New("invalid payment")
+	ErrAlreadyPaid    = errors.New("invoice is already paid")
+)
+
+type PaymentMethod string
+
+const (
+	PaymentCash     PaymentMethod = "CASH"
+	PaymentCard     PaymentMethod = "CARD"
+	PaymentPix      PaymentMethod = "PIX"
+	PaymentBoleto   PaymentMethod = "BOLETO"
+	PaymentTransfer PaymentMethod = "TRANSFER"
+)
+
+func (m PaymentMethod) Valid() bool {
+	switch m {
+	case PaymentCash, PaymentCard, PaymentPix, PaymentBoleto, PaymentTransfer:
+		return true
+	}
+	return false
+}
+
+// Payment is money received for an issued invoice. Reference holds whatever
+// identifies it on the payer's side: a card authorisation, a PIX end-to-end
+// ID, a bank transfer receipt.
+type Payment struct {
+	ID        int
+	InvoiceID int
+	Method    PaymentMethod
+	Amount    money.Money
+	PaidAt    time.Time
+	Reference string
+	CreatedAt time.Time
+}
+
+// Balance sums up what was paid and credited against an invoice. Outstanding
+// is what is still owed and Overpaid what was received beyond the total less
+// the credits; at most one of them is non-zero.
+type Balance struct {
+	TotalValue  money.Money
+	Credited    money.Money
+	AmountPaid  money.Money
+	Outstanding money.Money
+	Overpaid    money.Money
+}
+
+// Balance computes the invoice balance given all its payments and credit
+// notes.
+func (i *Invoice) Balance(payments []*Payment, credits []*CreditNote) Balance {
+	paid := money.Zero(i.TotalValue.Currency)
+	for _, p := range payments {
+		paid = paid.Add(p.Amount)
+	}
+	credited := money.Zero(i.TotalValue.Currency)
+	for _, note := range credits {
+		credited = credited.Add(note.TotalValue)
+	}
+
+	b := Balance{
+		TotalValue:  i.TotalValue,
+		Credited:    credited,
+		AmountPaid:  paid,
+		Outstanding: money.Zero(i.TotalValue.Currency),
+		Overpaid:    money.Zero(i.TotalValue.Currency),
+	}
+	if diff := i.TotalValue.Sub(credited).Sub(paid); diff.IsPositive() {
+		b.Outstanding = diff
+	} else {
+		b.Overpaid = diff.Neg()
+	}
+	return b
+}
+
+// RecordPayment registers a payment against an issued invoice and settles it:
+// the invoice becomes PAID once the payments cover the total, PARTIALLY_PAID
+// before that. The last payment may exceed the balance; the excess shows up
+// as Overpaid. amount is taken to be in the invoice currency. previous holds
+// the payments already recorded and credits the credit notes issued.
+func (i *Invoice) RecordPayment(method PaymentMethod, amount money.Money, paidAt time.Time, reference string, previous []*Payment, credits []*CreditNote) (*Payment, error) {
+	if !method.Valid() || !amount.IsPositive() || paidAt.IsZero() {
+		return nil, ErrInvalidPayment
+	}
+	if i.Status == StatusPaid {
+		return nil, ErrAlreadyPaid
+	}
+	if i.Status != StatusIssued && i.Status != StatusPartiallyPaid {
+		return nil, fmt.Errorf("%w: payments are only accepted for issued invoices", ErrInvalidStatus)
+	}
+
+	amount.Currency = i.currency()
+	payment := &Payment{
+		InvoiceID: i.ID,
+		Method:    method,
+		Amount:    amount,
+		PaidAt:    paidAt,
+		Reference: reference,
+		CreatedAt: time.Now(),
+	}
+
+	balance := i.Balance(append(append([]*Payment{}, previous...), payment), credits)
+	if err := i.settle(balance, paidAt, fmt.Sprintf("%s payment of %s", method, amount)); err != nil {
+		return nil, err
+	}
+
+	i.record(EventPaymentRecorded, struct {
+		InvoiceID   int           `json:"invoice_id"`
+		Method      PaymentMethod `json:"method"`
+		Amount      money.Money   `json:"amount"`
+		PaidAt      time.Time     `json:"paid_at"`
+		Reference   string        `json:"reference"`
+		Outstanding money.Money   `json:"outstanding"`
+	}{i.ID, method, amount, paidAt, reference, balance.Outstanding})
+
+	return payment, nil
+}
+
+// SettleCredit brings the status of an invoice awaiting payment in line with
+// its balance once a credit note has been issued: crediting what was left to
+// pay settles it.
+func (i *Invoice) SettleCredit(note *CreditNote, payments []*Payment, credits []*CreditNote) error {
+	if i.Status != StatusIssued && i.Status != StatusPartiallyPaid {
+		return nil
+	}
+	balance := i.Balance(payments, credits)
+	if !balance.Outstanding.IsZero() {
+		return nil
+	}
+	return i.settle(balance, note.CreatedAt, "settled by credit note")
+}
+
+// settle derives the status from the balance: PAID once nothing is owed,
+// PARTIALLY_PAID after the first payment. It is the only way an invoice gets
+// paid.
+func (i *Invoice) settle(balance Balance, at time.Time, reason string) error {
+	i.settleInstallments(balance.AmountPaid.Add(balance.Credited), at)
+
+	if balance.Outstanding.IsZero() {
+		return i.MarkPaid(reason)
+	}
+	if i.Status == StatusIssued {
+		return i.MarkPartiallyPaid(reason)
+	}
+	i.note(reason)
+	return nil
+}
@@ -1,11 +1,59 @@
 package invoice
 
-import "context"
+import (
+	"context"
+	"time"
+
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/numbering"
+)
 
 type Repository interface {
-	Create(ctx context.Context, invoice *Invoice) error
+	// Create stores a new invoice in the given series. When the invoice has no
+	// number yet, the next one is taken from the series counter in the same
+	// transaction, so a failed insert does not leave a gap.
+	Create(ctx context.Context, invoice *Invoice, series *numbering.Series) error
 	GetByID(ctx context.Context, id int) (*Invoice, error)
 	Update(ctx context.Context, invoice *Invoice) error
 	List(ctx context.Context) ([]*Invoice, error)
-	AddItem(ctx context.Context, item *InvoiceItem) error
+	// The item and adjustment writes store the change together with the
+	// taxes and totals already recomputed on invoice, in one transaction.
+	// They fail with ErrConcurrentUpdate when the invoice changed since it
+	// was read.
+	AddItem(ctx context.Context, invoice *Invoice, item *InvoiceItem) error
+	UpdateItem(ctx context.Context, invoice *Invoice, item *InvoiceItem, previousQuantity int) error
+	RemoveItem(ctx context.Context, invoice *Invoice, item *InvoiceItem) error
+	AddAdjustment(ctx context.Context, invoice *Invoice, adjustment *Adjustment) error
+	RemoveAdjustment(ctx context.Context, invoice *Invoice, adjustment *Adjustment) error
+	// SaveTotals stores the tax breakdown and adjustment shares of every item
+	// and the invoice totals.
+	SaveTotals(ctx context.Context, invoice *Invoice) error
+	ListTransitions(ctx context.Context, invoiceID int) ([]*Transition, error)
+	// ListPastDue returns the invoices awaiting payment with a pending
+	// installment due before asOf.
+	ListPastDue(ctx context.Context, asOf time.Time) ([]*Invoice, error)
+	ListOverdue(ctx context.Context) ([]*Invoice, error)
+}
+
+type CreditNoteRepository interface {
+	// Create stores the note together with the invoice changes that produced
+	// it, in a single transaction.
+	Create(ctx context.Context, note *CreditNote, inv *Invoice) error
+	ListByInvoice(ctx context.Context, invoiceID int) ([]*CreditNote, error)
+	ListPendingReturns(ctx context.Context) ([]*CreditNote, error)
+	MarkReturned(ctx context.Context, item *CreditNoteItem) error
+}
+
+type PaymentRepository interface {
+	// Create stores the payment together with the status change it caused, in
+	// a single transaction.
+	Create(ctx context.Context, payment *Payment, inv *Invoice) error
+	ListByInvoice(ctx context.Context, invoiceID int) ([]*Payment, error)
+}
+
+type BoletoRepository interface {
+	// NextSequence reserves the number of a new boleto. Numbers are never
+	// reused, even when the boleto is not stored in the end.
+	NextSequence(ctx context.Context) (int64, error)
+	Create(ctx context.Context, boleto *Boleto, inv *Invoice) error
+	ListByInvoice(ctx context.Context, invoiceID int) ([]*Boleto, error)
 }
@@ -0,0 +1,203 @@
+package invoice
+
+import (
+	"fmt"
+	"time"
+)
+
+type Status string
+
+const (
+	StatusDraft         Status = "DRAFT"
+	StatusOpen          Status = "OPEN"
+	StatusPrinting      Status = "PRINTING"
+	StatusIssued        Status = "ISSUED"
+	StatusPartiallyPaid Status = "PARTIALLY_PAID"
+	StatusPaid          Status = "PAID"
+	StatusCancelled     Status = "CANCELLED"
+	StatusVoided        Status = "VOIDED"
+)
+
+// transitions lists, for every status, the statuses it may move to. Anything
+// not listed here is rejected with ErrInvalidStatus.
+var transitions = map[Status][]Status{
+	StatusDraft:         {StatusOpen, StatusCancelled},
+	StatusOpen:          {StatusPrinting, StatusCancelled},
+	StatusPrinting:      {StatusIssued, StatusOpen},
+	StatusIssued:        {StatusPartiallyPaid, StatusPaid, StatusCancelled, StatusVoided},
+	StatusPartiallyPaid: {StatusPaid},
+	StatusPaid:          {},
+	StatusCancelled:     {},
+	StatusVoided:        {},
+}
+
+func (s Status) Valid() bool {
+	_, ok := transitions[s]
+	return ok
+}
+
+func (s Status) CanTransitionTo(to Status) bool {
+	for _, allowed := range transitions[s] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Transition is one entry of an invoice's history. Entries with From equal to
+// To record an event, such as a credit note, that left the status unchanged.
+type Transition struct {
+	ID         int
+	InvoiceID  int
+	From       Status
+	To         Status
+	Reason     string
+	OccurredAt time.Time
+}
+
+// InitialTransition is the history entry written when an invoice is created.
+func InitialTransition(inv *Invoice) Transition {
+	return Transition{
+		InvoiceID:  inv.ID,
+		To:         inv.Status,
+		Reason:     "created",
+		OccurredAt: inv.CreatedAt,
+	}
+}
+
+// transitionTo moves the invoice to a new status, recording the change in its
+// history and as an event. Repositories persist both with the invoice.
+func (i *Invoice) transitionTo(to Status, reason string) error {
+	from := i.Status
+	if !from.CanTransitionTo(to) {
+		return fmt.Errorf("%w: cannot move from %s to %s", ErrInvalidStatus, from, to)
+	}
+
+	now := time.Now()
+	i.Status = to
+	i.transitions = append(i.transitions, Transition{
+		InvoiceID:  i.ID,
+		From:       from,
+		To:         to,
+		Reason:     reason,
+		OccurredAt: now,
+	})
+	i.record(EventInvoiceStatusChanged, struct {
+		InvoiceID int    `json:"invoice_id"`
+		From      Status `json:"from"`
+		To        Status `json:"to"`
+		Reason    string `json:"reason,omitempty"`
+	}{i.ID, from, to, reason})
+
+	return nil
+}
+
+// note adds a history entry that does not change the status.
+func (i *Invoice) note(reason string) {
+	i.transitions = append(i.transitions, Transition{
+		InvoiceID:  i.ID,
+		From:       i.Status,
+		To:         i.Status,
+		Reason:     reason,
+		OccurredAt: time.Now(),
+	})
+}
+
+// PullTransitions returns the transitions made since the last call and clears
+// them.
+func (i *Invoice) PullTransitions() []Transition {
+	pending := i.transitions
+	i.transitions = nil
+	return pending
+}
+
+// IsEditable reports whether items may still be added or changed.
+func (i *Invoice) IsEditable() bool {
+	return i.Status == StatusDraft || i.Status == StatusOpen
+}
+
+// IsIssued reports whether the invoice has been printed and is still in force.
+func (i *Invoice) IsIssued() bool {
+	switch i.Status {
+	case StatusIssued, StatusPartiallyPaid, StatusPaid:
+		return true
+	}
+	return false
+}
+
+// EnsureEditable returns ErrAlreadyClosed for issued invoices and
+// ErrInvalidStatus for any other status that does not accept changes.
+func (i *Invoice) EnsureEditable() error {
+	if i.IsEditable() {
+		return nil
+	}
+	if i.IsIssued() {
+		return ErrAlreadyClosed
+	}
+	return fmt.Errorf("%w: %s invoices cannot be edited", ErrInvalidStatus, i.Status)
+}
+
+func (i *Invoice) Open() error {
+	return i.transitionTo(StatusOpen, "opened")
+}
+
+// StartPrinting locks the invoice for the print process. Only one print may
+// run at a time: repositories reject the update of a stale copy, so of two
+// concurrent attempts only the first to save gets the invoice.
+func (i *Invoice) StartPrinting() error {
+	if i.IsIssued() {
+		return ErrAlreadyClosed
+	}
+	if i.Status == StatusPrinting {
+		return ErrPrintInProgress
+	}
+	if len(i.Items) == 0 {
+		return ErrEmptyInvoice
+	}
+	return i.transitionTo(StatusPrinting, "print started")
+}
+
+// TakeOverPrint records that a print abandoned by another process is being
+// resumed or rolled back. Saving it claims the print like StartPrinting does.
+func (i *Invoice) TakeOverPrint(reason string) {
+	i.note(reason)
+}
+
+// AbortPrinting returns an invoice whose print was rolled back to OPEN. The
+// customer data frozen for the print is dropped, so the invoice follows the
+// customer record again until it is printed.
+func (i *Invoice) AbortPrinting(reason string) error {
+	if err := i.transitionTo(StatusOpen, reason); err != nil {
+		return err
+	}
+	i.Customer = nil
+	return nil
+}
+
+func (i *Invoice) MarkPartiallyPaid(reason string) error {
+	return i.transitionTo(StatusPartiallyPaid, reason)
+}
+
+func (i *Invoice) MarkPaid(reason string) error {
+	if err := i.transitionTo(StatusPaid, reason); err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, inst := range i.Installments {
+		if inst.Status != InstallmentPaid {
+			inst.Status = InstallmentPaid
+			inst.PaidAt = &now
+		}
+	}
+	return nil
+}
+
+func (i *Invoice) Cancel(reason string) error {
+	return i.transitionTo(StatusCancelled, reason)
+}
+
+// Void annuls an issued invoice, e.g. when it was printed by mistake.
+func (i *Invoice) Void(reason string) error {
+	return i.transitionTo(StatusVoided, reason)
+}
@@ -0,0 +1,9 @@
+package numbering
+
+import "context"
+
+type Repository interface {
+	Create(ctx context.Context, series *Series) error
+	Get(ctx context.Context, code string) (*Series, error)
+	List(ctx context.Context) ([]*Series, error)
+}
@@ -0,0 +1,115 @@
+package numbering
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotFound      = errors.New("invoice series not found")
+	ErrInvalidSeries = errors.New("invalid invoice series")
+	ErrInvalidNumber = errors.New("invoice number does not match the series format")
+	// ErrOutOfSequence is an explicit number other than the next one of the
+	// series, which would leave a gap or reuse a number
+	ErrOutOfSequence = errors.New("invoice number is not the next one in the series")
+	ErrDuplicateCode = errors.New("invoice series already exists")
+)
+
+const (
+	maxPadding    = 12
+	maxCodeLength = 20
+)
+
+// Series generates invoice numbers such as "INV-2024-000123": the prefix, the
+// year when the counter restarts every year, and the sequence zero-padded to
+// Padding digits. Each series keeps its own counter.
+type Series struct {
+	Code        string
+	Prefix      string
+	Padding     int
+	ResetYearly bool
+	CreatedAt   time.Time
+}
+
+func NewSeries(code, prefix string, padding int, resetYearly bool) (*Series, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" || len(code) > maxCodeLength || len(prefix) > maxCodeLength {
+		return nil, ErrInvalidSeries
+	}
+	if padding < 1 || padding > maxPadding {
+		return nil, ErrInvalidSeries
+	}
+	// A prefix ending in a digit would make the numbers ambiguous to parse
+	if prefix != "" && isDigit(prefix[len(prefix)-1]) {
+		return nil, ErrInvalidSeries
+	}
+
+	return &Series{
+		Code:        code,
+		Prefix:      prefix,
+		Padding:     padding,
+		ResetYearly: resetYearly,
+		CreatedAt:   time.Now(),
+	}, nil
+}
+
+// Period is the counter a number issued at t belongs to: the year for
+// yearly series, zero otherwise.
+func (s *Series) Period(t time.Time) int {
+	if s.ResetYearly {
+		return t.Year()
+	}
+	return 0
+}
+
+func (s *Series) Format(period int, sequence int) string {
+	if s.ResetYearly {
+		return fmt.Sprintf("%s%04d-%0*d", s.Prefix, period, s.Padding, sequence)
+	}
+	return fmt.Sprintf("%s%0*d", s.Prefix, s.Padding, sequence)
+}
+
+// Parse validates a client supplied number and returns its period and
+// sequence. Only the exact form Format produces is accepted, so one sequence
+// cannot be written as two different numbers.
+func (s *Series) Parse(number string) (period int, sequence int, err error) {
+	rest, ok := strings.CutPrefix(number, s.Prefix)
+	if !ok {
+		return 0, 0, ErrInvalidNumber
+	}
+
+	if s.ResetYearly {
+		year, seq, ok := strings.Cut(rest, "-")
+		if !ok || len(year) != 4 || !allDigits(year) {
+			return 0, 0, ErrInvalidNumber
+		}
+		period, _ = strconv.Atoi(year)
+		rest = seq
+	}
+
+	// Sequences may outgrow the padding, never fall short of it
+	if len(rest) < s.Padding || !allDigits(rest) {
+		return 0, 0, ErrInvalidNumber
+	}
+	sequence, err = strconv.Atoi(rest)
+	if err != nil || sequence == 0 || s.Format(period, sequence) != number {
+		return 0, 0, ErrInvalidNumber
+	}
+	return period, sequence, nil
+}
+
+func allDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !isDigit(s[i]) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
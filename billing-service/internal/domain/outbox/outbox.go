@@ -0,0 +1,40 @@
+package outbox
+
+import (
+	"context"
+	"time"
+)
+
+type Message struct {
+	ID            int64
+	AggregateType string
+	AggregateID   int
+	EventType     string
+	Payload       []byte
+	OccurredAt    time.Time
+	PublishedAt   *time.Time
+	Attempts      int
+	LastError     string
+}
+
+// Publisher delivers an outbox message to the outside world. Delivery is
+// at-least-once, so consumers must tolerate duplicates.
+type Publisher interface {
+	Publish(ctx context.Context, msg Message) error
+}
+
+type Repository interface {
+	// Claim takes up to limit unpublished messages until claimedUntil and
+	// commits the claim, so publishing happens outside any transaction. A
+	// message is only claimed once every earlier pending message of its
+	// aggregate has been published, dead-lettered or claimed alongside it.
+	// Messages are returned in id order.
+	Claim(ctx context.Context, limit int, now, claimedUntil time.Time) ([]Message, error)
+	MarkPublished(ctx context.Context, id int64, at time.Time) error
+	// MarkFailed records a failed attempt. The message is retried from
+	// retryAt, holding back its aggregate meanwhile, unless it has reached
+	// maxAttempts: then it is dead-lettered and MarkFailed reports true.
+	MarkFailed(ctx context.Context, id int64, cause error, retryAt time.Time, maxAttempts int) (bool, error)
+	// Release gives back claimed messages that were not attempted.
+	Release(ctx context.Context, ids []int64) error
+}
@@ -0,0 +1,115 @@
+// Package printjob tracks invoice prints run in the background.
+package printjob
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrNotFound      = errors.New("print job not found")
+	ErrAlreadyQueued = errors.New("a print of the invoice is already queued or running")
+)
+
+type Status string
+
+const (
+	StatusQueued    Status = "QUEUED"
+	StatusRunning   Status = "RUNNING"
+	StatusSucceeded Status = "SUCCEEDED"
+	StatusFailed    Status = "FAILED"
+)
+
+// Recovery describes the compensation of a failed print: which reservations
+// were cancelled and whether all of them could be.
+type Recovery struct {
+	Attempted  bool
+	Successful bool
+	Message    string
+	Details    []string
+}
+
+// Job is a request to print an invoice, executed by a worker after the HTTP
+// request that queued it has returned. A running job is held by the worker
+// that claimed it until LeaseUntil; Attempts numbers the claims. A queued job
+// is not claimed before AvailableAt.
+type Job struct {
+	ID           int
+	InvoiceID    int
+	Status       Status
+	StepReached  string
+	FailedReason string
+	Recovery     Recovery
+	Attempts     int
+	LeaseUntil   *time.Time
+	AvailableAt  time.Time
+	CreatedAt    time.Time
+	StartedAt    *time.Time
+	FinishedAt   *time.Time
+}
+
+func NewJob(invoiceID int) *Job {
+	now := time.Now()
+	return &Job{
+		InvoiceID:   invoiceID,
+		Status:      StatusQueued,
+		AvailableAt: now,
+		CreatedAt:   now,
+	}
+}
+
+func (j *Job) Finished() bool {
+	return j.Status == StatusSucceeded || j.Status == StatusFailed
+}
+
+func (j *Job) Succeed(stepReached string) {
+	j.StepReached = stepReached
+	j.FailedReason = ""
+	j.finish(StatusSucceeded)
+}
+
+func (j *Job) Fail(stepReached, reason string, recovery Recovery) {
+	j.StepReached = stepReached
+	j.FailedReason = reason
+	j.Recovery = recovery
+	j.finish(StatusFailed)
+}
+
+// Retry queues the job again from at, keeping why the last attempt did not
+// finish. It is used while the saga of the print is still to be settled.
+func (j *Job) Retry(stepReached, reason string, at time.Time) {
+	j.StepReached = stepReached
+	j.FailedReason = reason
+	j.Status = StatusQueued
+	j.AvailableAt = at
+}
+
+func (j *Job) finish(status Status) {
+	now := time.Now()
+	j.Status = status
+	j.FinishedAt = &now
+}
+
+type Repository interface {
+	// Create returns ErrAlreadyQueued when the invoice already has a queued
+	// or running job.
+	Create(ctx context.Context, job *Job) error
+	Get(ctx context.Context, id int) (*Job, error)
+	// FindUnfinishedByInvoice returns the queued or running job of the
+	// invoice, or ErrNotFound.
+	FindUnfinishedByInvoice(ctx context.Context, invoiceID int) (*Job, error)
+	// Claim marks the oldest available queued job RUNNING, leased until
+	// leaseUntil, and returns it, skipping jobs claimed by other workers. It
+	// returns ErrNotFound when none is available.
+	Claim(ctx context.Context, now, leaseUntil time.Time) (*Job, error)
+	// RenewLease extends the lease of the attempt-th claim of job id. It
+	// returns ErrNotFound when that claim no longer holds the job.
+	RenewLease(ctx context.Context, id, attempt int, until time.Time) error
+	// Release records the outcome of a job the caller holds, finished or
+	// queued again, and gives up its lease.
+	Release(ctx context.Context, job *Job) error
+	// RequeueExpired queues again the running jobs whose lease expired
+	// before now, their worker having died.
+	RequeueExpired(ctx context.Context, now time.Time) (int, error)
+}
@@ -0,0 +1,135 @@
+package recurring
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronExpr is a standard five-field cron expression: minute, hour, day of
+// month, month and day of week (0 or 7 is Sunday). Fields accept "*", lists,
+// ranges and steps such as "*/15" or "1-5".
+type cronExpr struct {
+	minutes, hours, days, months, weekdays uint64
+	// Like cron, when both day fields are restricted a day matching either
+	// one runs.
+	anyDay, anyWeekday bool
+}
+
+var cronFields = []struct{ min, max int }{
+	{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7},
+}
+
+func parseCron(expr string) (*cronExpr, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != len(cronFields) {
+		return nil, ErrInvalidCadence
+	}
+
+	sets := make([]uint64, len(fields))
+	for n, field := range fields {
+		set, err := parseCronField(field, cronFields[n].min, cronFields[n].max)
+		if err != nil {
+			return nil, err
+		}
+		sets[n] = set
+	}
+
+	// Sunday may be written as 7
+	weekdays := sets[4]
+	if weekdays&(1<<7) != 0 {
+		weekdays |= 1
+	}
+
+	return &cronExpr{
+		minutes:    sets[0],
+		hours:      sets[1],
+		days:       sets[2],
+		months:     sets[3],
+		weekdays:   weekdays,
+		anyDay:     strings.HasPrefix(fields[2], "*"),
+		anyWeekday: strings.HasPrefix(fields[4], "*"),
+	}, nil
+}
+
+func parseCronField(field string, min, max int) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if base, s, ok := strings.Cut(part, "/"); ok {
+			n, err := strconv.Atoi(s)
+			if err != nil || n < 1 {
+				return 0, ErrInvalidCadence
+			}
+			part, step = base, n
+		}
+
+		lo, hi := min, max
+		if part != "*" {
+			from, to, isRange := strings.Cut(part, "-")
+			var err error
+			if lo, err = strconv.Atoi(from); err != nil {
+				return 0, ErrInvalidCadence
+			}
+			hi = lo
+			if isRange {
+				if hi, err = strconv.Atoi(to); err != nil {
+					return 0, ErrInvalidCadence
+				}
+			} else if step > 1 {
+				// "5/15" means from 5 to the end in steps of 15
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, ErrInvalidCadence
+		}
+
+		for v := lo; v <= hi; v += step {
+			set |= 1 << uint(v)
+		}
+	}
+	return set, nil
+}
+
+// next returns the first time after t the expression matches, or the zero
+// time when there is none within five years (e.g. "0 0 30 2 *").
+func (c *cronExpr) next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if c.months&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.hours&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.minutes&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (c *cronExpr) matchDay(t time.Time) bool {
+	day := c.days&(1<<uint(t.Day())) != 0
+	weekday := c.weekdays&(1<<uint(t.Weekday())) != 0
+	switch {
+	case c.anyDay && c.anyWeekday:
+		return true
+	case c.anyDay:
+		return weekday
+	case c.anyWeekday:
+		return day
+	}
+	return day || weekday
+}
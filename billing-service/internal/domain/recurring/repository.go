@@ -0,0 +1,28 @@
+package recurring
+
+import (
+	"context"
+	"time"
+)
+
+type Repository interface {
+	Create(ctx context.Context, schedule *Schedule) error
+	Get(ctx context.Context, id int) (*Schedule, error)
+	List(ctx context.Context) ([]*Schedule, error)
+	Update(ctx context.Context, schedule *Schedule) error
+	// ClaimDue locks an active schedule due at now, advances it and opens a
+	// run for the occurrence, leased for lease, in a single transaction, so
+	// an occurrence is never invoiced twice. It returns ErrNotFound when
+	// nothing is due.
+	ClaimDue(ctx context.Context, now time.Time, lease time.Duration) (*Schedule, *Run, error)
+	// RenewRun extends the lease of a run still RUNNING to until. It returns
+	// ErrRunLost when the run was abandoned in the meantime.
+	RenewRun(ctx context.Context, runID int, until time.Time) error
+	// FinishRun records the outcome of a run still RUNNING, returning
+	// ErrRunLost when it was abandoned in the meantime.
+	FinishRun(ctx context.Context, run *Run) error
+	ListRuns(ctx context.Context, scheduleID int) ([]*Run, error)
+	// AbandonRuns fails the runs still RUNNING whose lease expired before
+	// now: the process working them is gone.
+	AbandonRuns(ctx context.Context, now time.Time, reason string) (int, error)
+}
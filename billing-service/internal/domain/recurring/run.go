@@ -0,0 +1,64 @@
+package recurring
+
+import (
+	"time"
+)
+
+type RunStatus string
+
+const (
+	RunRunning   RunStatus = "RUNNING"
+	RunSucceeded RunStatus = "SUCCEEDED"
+	RunFailed    RunStatus = "FAILED"
+)
+
+// Steps of a run, recorded with a failure to tell how far it got.
+const (
+	StepCreate = "create"
+	StepItems  = "items"
+	StepPrint  = "print"
+)
+
+// Run is the outcome of invoicing one occurrence of a schedule.
+type Run struct {
+	ID           int
+	ScheduleID   int
+	ScheduledFor time.Time
+	Status       RunStatus
+	InvoiceID    int
+	Printed      bool
+	Step         string
+	Error        string
+	StartedAt    time.Time
+	FinishedAt   *time.Time
+	// LeaseUntil is how long the run stays with the process working it
+	// without being renewed
+	LeaseUntil time.Time
+}
+
+func NewRun(scheduleID int, scheduledFor time.Time, lease time.Duration) *Run {
+	now := time.Now()
+	return &Run{
+		ScheduleID:   scheduleID,
+		ScheduledFor: scheduledFor,
+		Status:       RunRunning,
+		StartedAt:    now,
+		LeaseUntil:   now.Add(lease),
+	}
+}
+
+func (r *Run) Succeed() {
+	r.finish(RunSucceeded)
+}
+
+func (r *Run) Fail(step string, err error) {
+	r.Step = step
+	r.Error = err.Error()
+	r.finish(RunFailed)
+}
+
+func (r *Run) finish(status RunStatus) {
+	now := time.Now()
+	r.Status = status
+	r.FinishedAt = &now
+}
@@ -0,0 +1,187 @@
+// Package recurring describes invoices billed on a schedule: the same basket
+// of products, for the same customer, every week, month or cron occurrence.
+package recurring
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/invoice"
+)
+
+var (
+	ErrNotFound        = errors.New("recurring invoice schedule not found")
+	ErrInvalidSchedule = errors.New("invalid recurring invoice schedule")
+	ErrInvalidCadence  = errors.New("invalid recurring invoice cadence")
+	ErrInactive        = errors.New("recurring invoice schedule is inactive")
+	ErrRunLost         = errors.New("recurring invoice run is no longer running")
+)
+
+const maxNameLength = 100
+
+type Cadence string
+
+const (
+	CadenceWeekly  Cadence = "WEEKLY"
+	CadenceMonthly Cadence = "MONTHLY"
+	CadenceCron    Cadence = "CRON"
+)
+
+type Item struct {
+	ProductID int
+	Quantity  int
+}
+
+// Definition is what is configured on a schedule. Occurrences fall on StartAt
+// and then every week or month after it, on the same weekday or day of month
+// (the last day for shorter months); CRON schedules follow the Cron
+// expression, evaluated in UTC, from StartAt on. Nothing runs after EndAt.
+type Definition struct {
+	Name         string
+	CustomerID   int
+	Series       string
+	PaymentTerms invoice.PaymentTerms
+	Items        []Item
+	Cadence      Cadence
+	Cron         string
+	StartAt      time.Time
+	EndAt        *time.Time
+	AutoPrint    bool
+}
+
+// Schedule is a recurring invoice. NextRunAt is the occurrence still to be
+// invoiced, nil once the schedule has ended.
+type Schedule struct {
+	ID int
+	Definition
+	Active    bool
+	NextRunAt *time.Time
+	CreatedAt time.Time
+
+	cron *cronExpr
+}
+
+func NewSchedule(def Definition, now time.Time) (*Schedule, error) {
+	s := &Schedule{Active: true, CreatedAt: now}
+	if err := s.Redefine(def, now); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Redefine replaces the definition. Occurrences before now are not billed; a
+// zero StartAt starts now.
+func (s *Schedule) Redefine(def Definition, now time.Time) error {
+	if def.StartAt.IsZero() {
+		def.StartAt = now
+	}
+	def.Name = strings.TrimSpace(def.Name)
+	def.Series = strings.ToUpper(strings.TrimSpace(def.Series))
+	def.Cron = strings.TrimSpace(def.Cron)
+	if def.Name == "" || len(def.Name) > maxNameLength || len(def.Items) == 0 {
+		return ErrInvalidSchedule
+	}
+	if def.EndAt != nil && def.EndAt.Before(def.StartAt) {
+		return ErrInvalidSchedule
+	}
+	for _, item := range def.Items {
+		if item.ProductID <= 0 || item.Quantity <= 0 {
+			return ErrInvalidSchedule
+		}
+	}
+
+	var cron *cronExpr
+	switch def.Cadence {
+	case CadenceWeekly, CadenceMonthly:
+		if def.Cron != "" {
+			return ErrInvalidCadence
+		}
+	case CadenceCron:
+		parsed, err := parseCron(def.Cron)
+		if err != nil {
+			return err
+		}
+		cron = parsed
+	default:
+		return ErrInvalidCadence
+	}
+
+	s.Definition = def
+	s.cron = cron
+
+	// The first occurrence at or after now
+	s.NextRunAt = s.occurrenceAfter(now.Add(-time.Nanosecond))
+	return nil
+}
+
+// Deactivate stops the schedule; it is kept with its run history.
+func (s *Schedule) Deactivate() {
+	s.Active = false
+}
+
+// Advance moves past the occurrence that is being invoiced. Occurrences missed
+// while the scheduler was down are not skipped: each one is invoiced in turn.
+func (s *Schedule) Advance() {
+	if s.NextRunAt == nil {
+		return
+	}
+	s.NextRunAt = s.occurrenceAfter(*s.NextRunAt)
+}
+
+// occurrenceAfter returns the first occurrence strictly after t, or nil when
+// the schedule ends before it.
+func (s *Schedule) occurrenceAfter(t time.Time) *time.Time {
+	var next time.Time
+	start := s.StartAt.UTC()
+	switch {
+	case t.Before(start) && s.Cadence != CadenceCron:
+		next = start
+	case s.Cadence == CadenceWeekly:
+		weeks := int(t.Sub(start)/(7*24*time.Hour)) + 1
+		next = start.AddDate(0, 0, 7*weeks)
+	case s.Cadence == CadenceMonthly:
+		months := (t.Year()-start.Year())*12 + int(t.Month()-start.Month())
+		for next = addMonths(start, months); !next.After(t); next = addMonths(start, months) {
+			months++
+		}
+	case s.Cadence == CadenceCron:
+		from := t.UTC()
+		if from.Before(start) {
+			from = start.Add(-time.Minute)
+		}
+		next = s.cron.next(from)
+	}
+
+	if next.IsZero() || (s.EndAt != nil && next.After(*s.EndAt)) {
+		return nil
+	}
+	return &next
+}
+
+// addMonths moves start n months forward, keeping its day of month or using
+// the last day of months that are shorter.
+func addMonths(start time.Time, n int) time.Time {
+	first := time.Date(start.Year(), start.Month()+time.Month(n), 1,
+		start.Hour(), start.Minute(), start.Second(), start.Nanosecond(), start.Location())
+	last := first.AddDate(0, 1, -1).Day()
+	day := start.Day()
+	if day > last {
+		day = last
+	}
+	return first.AddDate(0, 0, day-1)
+}
+
+// Restore rebuilds what is derived from the definition after loading a
+// schedule from storage.
+func (s *Schedule) Restore() error {
+	if s.Cadence != CadenceCron {
+		return nil
+	}
+	cron, err := parseCron(s.Cron)
+	if err != nil {
+		return err
+	}
+	s.cron = cron
+	return nil
+}
@@ -0,0 +1,11 @@
+package saga
+
+import "context"
+
+type Repository interface {
+	Create(ctx context.Context, saga *Saga) error
+	Update(ctx context.Context, saga *Saga) error
+	UpdateStep(ctx context.Context, step *Step) error
+	FindUnfinishedByInvoice(ctx context.Context, invoiceID int) (*Saga, error)
+	ListUnfinished(ctx context.Context) ([]*Saga, error)
+}
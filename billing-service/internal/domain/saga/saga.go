@@ -0,0 +1,158 @@
+package saga
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrNotFound = errors.New("saga not found")
+
+type Status string
+
+const (
+	StatusRunning     Status = "RUNNING"
+	StatusCompleted   Status = "COMPLETED"
+	StatusCompensated Status = "COMPENSATED"
+)
+
+type StepStatus string
+
+const (
+	StepPending     StepStatus = "PENDING"
+	StepStarted     StepStatus = "STARTED"
+	StepDone        StepStatus = "DONE"
+	StepFailed      StepStatus = "FAILED"
+	StepCompensated StepStatus = "COMPENSATED"
+)
+
+type Action string
+
+const (
+	ActionReserveBatch Action = "reserve_batch"
+	ActionConfirmBatch Action = "confirm_batch"
+	ActionCloseInvoice Action = "close_invoice"
+	ActionCancelBatch  Action = "cancel_batch"
+)
+
+// Phase returns the name reported as step_reached to API clients.
+func (a Action) Phase() string {
+	switch a {
+	case ActionReserveBatch:
+		return "stock_reservation"
+	case ActionConfirmBatch:
+		return "stock_confirmation"
+	case ActionCloseInvoice:
+		return "invoice_closing"
+	}
+	return string(a)
+}
+
+type Step struct {
+	ID           int
+	SagaID       int
+	Seq          int
+	Action       Action
+	Compensation Action
+	ProductID    int
+	Quantity     int
+	// ReservationID is the inventory reservation batch the step took
+	ReservationID int
+	Status        StepStatus
+	Error         string
+	UpdatedAt     time.Time
+}
+
+type Saga struct {
+	ID           int
+	InvoiceID    int
+	Status       Status
+	StepReached  string
+	FailedReason string
+	Steps        []*Step
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// NewPrintSaga plans every step of the print flow up front so that a crashed
+// run can be resumed or compensated from the persisted log alone. The stock of
+// every line is reserved in a single inventory batch, which takes over the
+// holds placed while the invoice was edited.
+func NewPrintSaga(invoiceID int) *Saga {
+	s := &Saga{
+		InvoiceID:   invoiceID,
+		Status:      StatusRunning,
+		StepReached: "started",
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+
+	s.addStep(ActionReserveBatch, ActionCancelBatch)
+	s.addStep(ActionConfirmBatch, "")
+	s.addStep(ActionCloseInvoice, "")
+
+	return s
+}
+
+func (s *Saga) addStep(action, compensation Action) {
+	s.Steps = append(s.Steps, &Step{
+		Seq:          len(s.Steps) + 1,
+		Action:       action,
+		Compensation: compensation,
+		Status:       StepPending,
+	})
+}
+
+// BatchID returns the reservation batch taken by the saga, zero before it
+// was reserved.
+func (s *Saga) BatchID() int {
+	for _, step := range s.Steps {
+		if step.Action == ActionReserveBatch {
+			return step.ReservationID
+		}
+	}
+	return 0
+}
+
+// PastPivot reports whether stock has already been consumed. From that point
+// the saga can only move forward.
+func (s *Saga) PastPivot() bool {
+	for _, step := range s.Steps {
+		if step.Action == ActionConfirmBatch && step.Status == StepDone {
+			return true
+		}
+	}
+	return false
+}
+
+// PendingCompensations returns the completed steps that still hold a
+// compensable effect, in reverse order. Nothing is left to undo once the
+// batch was confirmed.
+func (s *Saga) PendingCompensations() []*Step {
+	if s.PastPivot() {
+		return nil
+	}
+	var steps []*Step
+	for i := len(s.Steps) - 1; i >= 0; i-- {
+		step := s.Steps[i]
+		if step.Status == StepDone && step.Compensation != "" {
+			steps = append(steps, step)
+		}
+	}
+	return steps
+}
+
+// LastActivity is when the saga or one of its steps was last saved. A saga
+// that has been quiet for long was abandoned by the process running it.
+func (s *Saga) LastActivity() time.Time {
+	last := s.UpdatedAt
+	for _, step := range s.Steps {
+		if step.UpdatedAt.After(last) {
+			last = step.UpdatedAt
+		}
+	}
+	return last
+}
+
+func (s *Saga) Finished() bool {
+	return s.Status != StatusRunning
+}
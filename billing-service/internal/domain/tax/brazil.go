@@ -0,0 +1,75 @@
+package tax
+
+import "github.com/vitorwhois/microservice-invoice-billing/money"
+
+// ICMS is charged inside the price. Sales within the issuer's state use the
+// internal rate; interstate sales use the 7% or 12% rates set by Senate
+// Resolution 22/1989, and exports are immune.
+type ICMS struct {
+	InternalRate Rate
+}
+
+func (s ICMS) Apply(in Input) []Line {
+	if in.Class.Exempt || !in.Destination.Domestic() {
+		return nil
+	}
+
+	rate := s.InternalRate
+	if in.Destination.UF != in.Origin.UF {
+		rate = interstateRate(in.Origin.UF, in.Destination.UF)
+	}
+	return []Line{included(KindICMS, in.Base(), rate)}
+}
+
+// Goods shipped from the South and Southeast (except Espírito Santo) to the
+// North, Northeast, Center-West and Espírito Santo pay 7%; everything else
+// pays 12%.
+func interstateRate(from, to string) Rate {
+	if southSoutheast[from] && !southSoutheast[to] {
+		return 700
+	}
+	return 1200
+}
+
+var southSoutheast = map[string]bool{
+	"SP": true, "RJ": true, "MG": true, "PR": true, "SC": true, "RS": true,
+}
+
+// IPI is added on top of the price for products whose class carries a rate.
+type IPI struct{}
+
+func (IPI) Apply(in Input) []Line {
+	if in.Class.Exempt || in.Class.IPIRate == 0 {
+		return nil
+	}
+	base := in.Base()
+	return []Line{{Kind: KindIPI, Base: base, Rate: in.Class.IPIRate, Amount: in.Class.IPIRate.Of(base)}}
+}
+
+// PISCOFINS charges both contributions inside the price.
+type PISCOFINS struct {
+	PISRate    Rate
+	COFINSRate Rate
+}
+
+// Rates of the non-cumulative (lucro real) and cumulative (lucro presumido)
+// regimes.
+var (
+	NonCumulative = PISCOFINS{PISRate: 165, COFINSRate: 760}
+	Cumulative    = PISCOFINS{PISRate: 65, COFINSRate: 300}
+)
+
+func (s PISCOFINS) Apply(in Input) []Line {
+	if in.Class.Exempt || !in.Destination.Domestic() {
+		return nil
+	}
+	base := in.Base()
+	return []Line{
+		included(KindPIS, base, s.PISRate),
+		included(KindCOFINS, base, s.COFINSRate),
+	}
+}
+
+func included(kind Kind, base money.Money, rate Rate) Line {
+	return Line{Kind: kind, Base: base, Rate: rate, Amount: rate.Of(base), Included: true}
+}
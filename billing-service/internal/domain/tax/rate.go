@@ -0,0 +1,68 @@
+package tax
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+
+	"github.com/vitorwhois/microservice-invoice-billing/money"
+)
+
+// Rate is a percentage in hundredths: 1800 is 18.00%.
+type Rate int64
+
+func ParseRate(s string) (Rate, error) {
+	m, err := money.Parse(s, "")
+	if err != nil || m.IsNegative() {
+		return 0, ErrInvalidRate
+	}
+	return Rate(m.Amount), nil
+}
+
+// MustParseRate is meant for rates written in the code.
+func MustParseRate(s string) Rate {
+	r, err := ParseRate(s)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// Of applies the rate to base, rounding half away from zero.
+func (r Rate) Of(base money.Money) money.Money {
+	return base.MulRatio(int64(r), 10000)
+}
+
+func (r Rate) String() string {
+	return fmt.Sprintf("%d.%02d", r/100, r%100)
+}
+
+func (r Rate) MarshalJSON() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+func (r *Rate) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+	parsed, err := ParseRate(s)
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
+
+func (r *Rate) Scan(src interface{}) error {
+	var m money.Money
+	if err := m.Scan(src); err != nil {
+		return err
+	}
+	*r = Rate(m.Amount)
+	return nil
+}
+
+func (r Rate) Value() (driver.Value, error) {
+	return r.String(), nil
+}
@@ -0,0 +1,124 @@
+package tax
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/vitorwhois/microservice-invoice-billing/money"
+)
+
+var (
+	ErrClassNotFound = errors.New("tax class not found")
+	ErrInvalidRate   = errors.New("invalid tax rate")
+	ErrInvalidNCM    = errors.New("NCM must have 8 digits")
+)
+
+type Kind string
+
+const (
+	KindICMS   Kind = "ICMS"
+	KindIPI    Kind = "IPI"
+	KindPIS    Kind = "PIS"
+	KindCOFINS Kind = "COFINS"
+	KindVAT    Kind = "VAT"
+)
+
+// Line is one tax charged on an invoice item. Included taxes are already part
+// of the item price (ICMS, PIS and COFINS in Brazil); the others are added on
+// top of it.
+type Line struct {
+	Kind     Kind
+	Base     money.Money
+	Rate     Rate
+	Amount   money.Money
+	Included bool
+}
+
+// Class is the tax classification of a product.
+type Class struct {
+	ProductID int
+	NCM       string
+	IPIRate   Rate
+	Exempt    bool
+	UpdatedAt time.Time
+}
+
+func NewClass(productID int, ncm string, ipiRate Rate, exempt bool) (*Class, error) {
+	if len(ncm) != 8 || strings.Trim(ncm, "0123456789") != "" {
+		return nil, ErrInvalidNCM
+	}
+	if ipiRate < 0 {
+		return nil, ErrInvalidRate
+	}
+	return &Class{
+		ProductID: productID,
+		NCM:       ncm,
+		IPIRate:   ipiRate,
+		Exempt:    exempt,
+		UpdatedAt: time.Now(),
+	}, nil
+}
+
+// Location identifies where goods leave from or are delivered to. An empty
+// Country means Brazil.
+type Location struct {
+	UF      string
+	Country string
+}
+
+func (l Location) Domestic() bool {
+	return l.Country == "" || l.Country == "BR"
+}
+
+// Input describes an invoice item for the strategies. Discount and Surcharge
+// hold the item's adjustments, including its share of the invoice-level ones.
+type Input struct {
+	Price       money.Money
+	Quantity    int
+	Discount    money.Money
+	Surcharge   money.Money
+	Class       Class
+	Origin      Location
+	Destination Location
+}
+
+// Base is the taxable amount: discounts lower it, surcharges raise it.
+func (in Input) Base() money.Money {
+	return in.Price.Mul(int64(in.Quantity)).Sub(in.Discount).Add(in.Surcharge)
+}
+
+// Strategy computes the lines of one tax, or none when it does not apply.
+type Strategy interface {
+	Apply(in Input) []Line
+}
+
+type ClassRepository interface {
+	Get(ctx context.Context, productID int) (*Class, error)
+	Save(ctx context.Context, class *Class) error
+}
+
+// Engine runs a fixed set of strategies for every item.
+type Engine struct {
+	origin     Location
+	strategies []Strategy
+}
+
+func NewEngine(origin Location, strategies ...Strategy) *Engine {
+	return &Engine{origin: origin, strategies: strategies}
+}
+
+func (e *Engine) Compute(in Input) []Line {
+	in.Origin = e.origin
+	if in.Destination.UF == "" && in.Destination.Domestic() {
+		// Sales without a known buyer are treated as local
+		in.Destination.UF = e.origin.UF
+	}
+
+	lines := make([]Line, 0)
+	for _, s := range e.strategies {
+		lines = append(lines, s.Apply(in)...)
+	}
+	return lines
+}
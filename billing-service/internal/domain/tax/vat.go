@@ -0,0 +1,27 @@
+package tax
+
+// VAT is a generic sales tax added on top of the price. Rates are looked up by
+// destination country, then by state, falling back to DefaultRate.
+type VAT struct {
+	DefaultRate Rate
+	Rates       map[string]Rate
+}
+
+func (s VAT) Apply(in Input) []Line {
+	if in.Class.Exempt {
+		return nil
+	}
+
+	rate, ok := s.Rates[in.Destination.Country]
+	if !ok {
+		if rate, ok = s.Rates[in.Destination.UF]; !ok {
+			rate = s.DefaultRate
+		}
+	}
+	if rate == 0 {
+		return nil
+	}
+
+	base := in.Base()
+	return []Line{{Kind: KindVAT, Base: base, Rate: rate, Amount: rate.Of(base)}}
+}
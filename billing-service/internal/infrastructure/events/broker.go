@@ -0,0 +1,40 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/outbox"
+)
+
+// Producer is the minimal surface a message broker client (Kafka, RabbitMQ,
+// NATS...) must offer to be plugged in as an outbox publisher.
+type Producer interface {
+	Produce(ctx context.Context, topic string, key []byte, value []byte, headers map[string]string) error
+}
+
+// BrokerPublisher sends events to a broker topic keyed by invoice, so a
+// partitioned broker keeps the events of one invoice in order.
+type BrokerPublisher struct {
+	producer Producer
+	topic    string
+}
+
+func NewBrokerPublisher(producer Producer, topic string) *BrokerPublisher {
+	return &BrokerPublisher{producer: producer, topic: topic}
+}
+
+func (p *BrokerPublisher) Publish(ctx context.Context, msg outbox.Message) error {
+	value, err := json.Marshal(envelope(msg))
+	if err != nil {
+		return err
+	}
+
+	key := []byte(msg.AggregateType + "-" + strconv.Itoa(msg.AggregateID))
+	headers := map[string]string{
+		"event_type": msg.EventType,
+		"event_id":   strconv.FormatInt(msg.ID, 10),
+	}
+	return p.producer.Produce(ctx, p.topic, key, value, headers)
+}
@@ -0,0 +1,46 @@
+package events
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/outbox"
+)
+
+type Handler func(ctx context.Context, msg outbox.Message) error
+
+// InProcessBus delivers events to handlers registered in the same process.
+// Handlers subscribed to "*" receive every event.
+type InProcessBus struct {
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+}
+
+func NewInProcessBus() *InProcessBus {
+	return &InProcessBus{handlers: make(map[string][]Handler)}
+}
+
+func (b *InProcessBus) Subscribe(eventType string, h Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[eventType] = append(b.handlers[eventType], h)
+}
+
+func (b *InProcessBus) Publish(ctx context.Context, msg outbox.Message) error {
+	b.mu.RLock()
+	handlers := append(append([]Handler{}, b.handlers[msg.EventType]...), b.handlers["*"]...)
+	b.mu.RUnlock()
+
+	for _, h := range handlers {
+		if err := h(ctx, msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func LogHandler(ctx context.Context, msg outbox.Message) error {
+	log.Printf("Evento %s da fatura %d: %s", msg.EventType, msg.AggregateID, msg.Payload)
+	return nil
+}
@@ -0,0 +1,83 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/outbox"
+)
+
+// WebhookPublisher POSTs each event as JSON to a fixed URL. When a secret is
+// configured the body is signed with HMAC-SHA256 in X-Signature.
+type WebhookPublisher struct {
+	url    string
+	secret string
+	client *http.Client
+}
+
+func NewWebhookPublisher(url string, secret string, timeout time.Duration) *WebhookPublisher {
+	return &WebhookPublisher{
+		url:    url,
+		secret: secret,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+func (p *WebhookPublisher) Publish(ctx context.Context, msg outbox.Message) error {
+	body, err := json.Marshal(envelope(msg))
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event-Type", msg.EventType)
+	req.Header.Set("X-Event-ID", strconv.FormatInt(msg.ID, 10))
+	if p.secret != "" {
+		mac := hmac.New(sha256.New, []byte(p.secret))
+		mac.Write(body)
+		req.Header.Set("X-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook answered with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+type eventEnvelope struct {
+	ID            int64           `json:"id"`
+	Type          string          `json:"type"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   int             `json:"aggregate_id"`
+	OccurredAt    time.Time       `json:"occurred_at"`
+	Data          json.RawMessage `json:"data"`
+}
+
+func envelope(msg outbox.Message) eventEnvelope {
+	return eventEnvelope{
+		ID:            msg.ID,
+		Type:          msg.EventType,
+		AggregateType: msg.AggregateType,
+		AggregateID:   msg.AggregateID,
+		OccurredAt:    msg.OccurredAt,
+		Data:          json.RawMessage(msg.Payload),
+	}
+}
@@ -0,0 +1,110 @@
+package exchange
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/exchange"
+)
+
+// FileProvider quotes the rates of a JSON file such as
+//
+//	{"base": "BRL", "as_of": "2024-05-02T00:00:00Z", "rates": {"USD": 5.1234, "EUR": "5.4821"}}
+//
+// The file is read again whenever its modification time changes, so rates are
+// refreshed by replacing it. If a new version cannot be read the previous
+// rates stay in use.
+type FileProvider struct {
+	path string
+
+	mu      sync.Mutex
+	modTime time.Time
+	table   *Table
+}
+
+type rateFile struct {
+	Base  string                     `json:"base"`
+	AsOf  time.Time                  `json:"as_of"`
+	Rates map[string]exchange.Factor `json:"rates"`
+}
+
+// NewFileProvider reads the file once so a missing or broken file is reported
+// at startup.
+func NewFileProvider(path string) (*FileProvider, error) {
+	p := &FileProvider{path: path}
+	if _, err := p.current(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *FileProvider) Rate(ctx context.Context, from, to string) (exchange.Rate, error) {
+	table, err := p.current()
+	if err != nil {
+		return exchange.Rate{}, err
+	}
+	return table.Rate(from, to)
+}
+
+func (p *FileProvider) current() (*Table, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	info, err := os.Stat(p.path)
+	if err != nil {
+		if p.table != nil {
+			log.Printf("Arquivo de câmbio %s indisponível, mantendo as cotações anteriores: %v", p.path, err)
+			return p.table, nil
+		}
+		return nil, err
+	}
+	if p.table != nil && info.ModTime().Equal(p.modTime) {
+		return p.table, nil
+	}
+
+	table, err := readTable(p.path)
+	if err != nil {
+		if p.table != nil {
+			log.Printf("Arquivo de câmbio %s inválido, mantendo as cotações anteriores: %v", p.path, err)
+			return p.table, nil
+		}
+		return nil, err
+	}
+
+	p.table, p.modTime = table, info.ModTime()
+	log.Printf("Cotações de câmbio carregadas de %s (base %s, %d moedas)", p.path, table.Base, len(table.Rates))
+	return table, nil
+}
+
+func readTable(path string) (*Table, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file rateFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%w: %v", exchange.ErrInvalidRate, err)
+	}
+
+	rates := make(map[string]exchange.Factor, len(file.Rates))
+	for currency, f := range file.Rates {
+		rates[strings.ToUpper(currency)] = f
+	}
+	table := &Table{
+		Base:   strings.ToUpper(file.Base),
+		Rates:  rates,
+		Source: "file",
+		AsOf:   file.AsOf,
+	}
+	if err := table.validate(); err != nil {
+		return nil, err
+	}
+	return table, nil
+}
@@ -0,0 +1,39 @@
+package exchange
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/exchange"
+)
+
+// StaticProvider quotes the fixed rates it was configured with.
+type StaticProvider struct {
+	table Table
+}
+
+// NewStaticProvider quotes the currencies in spec, a list such as
+// "USD=5.43,EUR=5.90", against base. An empty spec only converts a currency
+// to itself.
+func NewStaticProvider(base, spec string) (*StaticProvider, error) {
+	rates, err := ParseRates(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &StaticProvider{table: Table{
+		Base:   strings.ToUpper(base),
+		Rates:  rates,
+		Source: "static",
+		AsOf:   time.Now().UTC(),
+	}}
+	if err := p.table.validate(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *StaticProvider) Rate(ctx context.Context, from, to string) (exchange.Rate, error) {
+	return p.table.Rate(from, to)
+}
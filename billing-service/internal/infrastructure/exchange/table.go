@@ -0,0 +1,88 @@
+// Package exchange provides exchange rate sources that work offline: a static
+// table taken from the configuration and a JSON file that can be replaced
+// while the service runs.
+package exchange
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/exchange"
+	"github.com/vitorwhois/microservice-invoice-billing/money"
+)
+
+// Table quotes every currency against Base: Rates["USD"] is how many units of
+// Base one dollar is worth. Rates between two other currencies are crossed
+// through the base.
+type Table struct {
+	Base   string
+	Rates  map[string]exchange.Factor
+	Source string
+	AsOf   time.Time
+}
+
+func (t *Table) Rate(from, to string) (exchange.Rate, error) {
+	if from == to {
+		return exchange.Identity(from), nil
+	}
+
+	fromValue, ok := t.value(from)
+	if !ok {
+		return exchange.Rate{}, fmt.Errorf("%w: %s to %s", exchange.ErrRateNotFound, from, to)
+	}
+	toValue, ok := t.value(to)
+	if !ok {
+		return exchange.Rate{}, fmt.Errorf("%w: %s to %s", exchange.ErrRateNotFound, from, to)
+	}
+
+	return exchange.Rate{
+		From:   from,
+		To:     to,
+		Value:  fromValue.Div(toValue),
+		Source: t.Source,
+		AsOf:   t.AsOf,
+	}, nil
+}
+
+func (t *Table) value(currency string) (exchange.Factor, bool) {
+	if currency == t.Base {
+		return exchange.One, true
+	}
+	f, ok := t.Rates[currency]
+	return f, ok && f > 0
+}
+
+// validate checks the currency codes of the table.
+func (t *Table) validate() error {
+	if !money.ValidCurrency(t.Base) {
+		return fmt.Errorf("%w: base currency %q", exchange.ErrInvalidRate, t.Base)
+	}
+	for currency, f := range t.Rates {
+		if !money.ValidCurrency(currency) || f <= 0 {
+			return fmt.Errorf("%w: %s", exchange.ErrInvalidRate, currency)
+		}
+	}
+	return nil
+}
+
+// ParseRates reads a list such as "USD=5.43,EUR=5.90".
+func ParseRates(spec string) (map[string]exchange.Factor, error) {
+	rates := make(map[string]exchange.Factor)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		currency, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", exchange.ErrInvalidRate, entry)
+		}
+		f, err := exchange.ParseFactor(value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q", exchange.ErrInvalidRate, entry)
+		}
+		rates[strings.ToUpper(strings.TrimSpace(currency))] = f
+	}
+	return rates, nil
+}
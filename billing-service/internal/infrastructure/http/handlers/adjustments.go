@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	appinvoice "github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/application/invoice"
+	domaininvoice "github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/invoice"
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/tax"
+	"github.com/vitorwhois/microservice-invoice-billing/money"
+
+	"github.com/gorilla/mux"
+)
+
+func (h *InvoiceHandler) AddAdjustment(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid invoice ID", http.StatusBadRequest)
+		return
+	}
+
+	var request struct {
+		ItemID int           `json:"item_id"`
+		Kind   string        `json:"kind"`
+		Method string        `json:"method"`
+		Rate   tax.Rate      `json:"rate"`
+		Amount money.Literal `json:"amount"`
+		Reason string        `json:"reason"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	adj, err := h.service.AddAdjustment(r.Context(), id, appinvoice.AdjustmentInput{
+		ItemID: request.ItemID,
+		Kind:   domaininvoice.AdjustmentKind(request.Kind),
+		Method: domaininvoice.AdjustmentMethod(request.Method),
+		Rate:   request.Rate,
+		Amount: request.Amount,
+		Reason: request.Reason,
+	})
+	if err != nil {
+		writeAdjustmentError(w, err)
+		return
+	}
+
+	inv, _ := h.service.GetInvoiceByID(r.Context(), id)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(struct {
+		Adjustment *domaininvoice.Adjustment `json:"adjustment"`
+		Invoice    *domaininvoice.Invoice    `json:"invoice"`
+	}{adj, inv})
+}
+
+func (h *InvoiceHandler) RemoveAdjustment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid invoice ID", http.StatusBadRequest)
+		return
+	}
+	adjustmentID, err := strconv.Atoi(vars["adjustmentId"])
+	if err != nil {
+		http.Error(w, "Invalid adjustment ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.RemoveAdjustment(r.Context(), id, adjustmentID); err != nil {
+		writeAdjustmentError(w, err)
+		return
+	}
+
+	inv, _ := h.service.GetInvoiceByID(r.Context(), id)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(inv)
+}
+
+func writeAdjustmentError(w http.ResponseWriter, err error) {
+	switch {
+	case err == domaininvoice.ErrNotFound:
+		http.Error(w, "Invoice not found", http.StatusNotFound)
+	case err == domaininvoice.ErrItemNotFound:
+		http.Error(w, "Item not found", http.StatusNotFound)
+	case err == domaininvoice.ErrAdjustmentNotFound:
+		http.Error(w, "Adjustment not found", http.StatusNotFound)
+	case err == domaininvoice.ErrInvalidAdjustment:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case err == domaininvoice.ErrNegativeTotal:
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	case errors.Is(err, domaininvoice.ErrInvalidStatus), errors.Is(err, domaininvoice.ErrConcurrentUpdate):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	appinvoice "github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/application/invoice"
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/boleto"
+	domaininvoice "github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/invoice"
+	"github.com/vitorwhois/microservice-invoice-billing/money"
+
+	"github.com/gorilla/mux"
+)
+
+type boletoResponse struct {
+	ID            int         `json:"id"`
+	InvoiceID     int         `json:"invoice_id"`
+	BankCode      string      `json:"bank_code"`
+	OurNumber     string      `json:"our_number"`
+	DueDate       string      `json:"due_date"`
+	Amount        money.Money `json:"amount"`
+	Barcode       string      `json:"barcode"`
+	DigitableLine string      `json:"digitable_line"`
+	CreatedAt     time.Time   `json:"created_at"`
+}
+
+func newBoletoResponse(b *domaininvoice.Boleto) boletoResponse {
+	return boletoResponse{
+		ID:            b.ID,
+		InvoiceID:     b.InvoiceID,
+		BankCode:      b.BankCode,
+		OurNumber:     b.OurNumber,
+		DueDate:       b.DueDate.Format("2006-01-02"),
+		Amount:        b.Amount,
+		Barcode:       b.Barcode,
+		DigitableLine: boleto.FormatDigitableLine(b.DigitableLine),
+		CreatedAt:     b.CreatedAt,
+	}
+}
+
+func (h *InvoiceHandler) IssueBoleto(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid invoice ID", http.StatusBadRequest)
+		return
+	}
+
+	// due_date (YYYY-MM-DD) defaults to the configured term
+	var request struct {
+		DueDate string `json:"due_date"`
+	}
+
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	var dueDate time.Time
+	if request.DueDate != "" {
+		if dueDate, err = time.Parse("2006-01-02", request.DueDate); err != nil {
+			http.Error(w, "Invalid due date", http.StatusBadRequest)
+			return
+		}
+	}
+
+	b, err := h.service.IssueBoleto(r.Context(), id, dueDate)
+	if err != nil {
+		switch {
+		case err == appinvoice.ErrBoletoDisabled:
+			http.Error(w, err.Error(), http.StatusNotImplemented)
+		case err == domaininvoice.ErrNotFound:
+			http.Error(w, "Invoice not found", http.StatusNotFound)
+		case err == boleto.ErrInvalidDueDate:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case err == domaininvoice.ErrAlreadyPaid, errors.Is(err, domaininvoice.ErrInvalidStatus), errors.Is(err, domaininvoice.ErrConcurrentUpdate):
+			http.Error(w, err.Error(), http.StatusConflict)
+		case err == boleto.ErrInvalidAmount:
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(newBoletoResponse(b))
+}
+
+func (h *InvoiceHandler) ListBoletos(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid invoice ID", http.StatusBadRequest)
+		return
+	}
+
+	boletos, err := h.service.ListBoletos(r.Context(), id)
+	if err != nil {
+		if err == domaininvoice.ErrNotFound {
+			http.Error(w, "Invoice not found", http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	response := make([]boletoResponse, 0, len(boletos))
+	for _, b := range boletos {
+		response = append(response, newBoletoResponse(b))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
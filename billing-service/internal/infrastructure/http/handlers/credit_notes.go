@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	domaininvoice "github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/invoice"
+
+	"github.com/gorilla/mux"
+)
+
+func (h *InvoiceHandler) IssueCreditNote(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid invoice ID", http.StatusBadRequest)
+		return
+	}
+
+	// An empty items list credits everything not credited yet
+	var request struct {
+		Reason string `json:"reason"`
+		Items  []struct {
+			ItemID   int `json:"item_id"`
+			Quantity int `json:"quantity"`
+		} `json:"items"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	lines := make([]domaininvoice.CreditLine, 0, len(request.Items))
+	for _, item := range request.Items {
+		lines = append(lines, domaininvoice.CreditLine{InvoiceItemID: item.ItemID, Quantity: item.Quantity})
+	}
+
+	note, err := h.service.IssueCreditNote(r.Context(), id, lines, request.Reason)
+	if err != nil {
+		switch {
+		case err == domaininvoice.ErrNotFound:
+			http.Error(w, "Invoice not found", http.StatusNotFound)
+		case err == domaininvoice.ErrItemNotFound:
+			http.Error(w, "Item not found", http.StatusNotFound)
+		case err == domaininvoice.ErrCreditExceedsInvoice:
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		case err == domaininvoice.ErrNothingToCredit, errors.Is(err, domaininvoice.ErrInvalidStatus), errors.Is(err, domaininvoice.ErrConcurrentUpdate):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(note)
+}
+
+func (h *InvoiceHandler) ListCreditNotes(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid invoice ID", http.StatusBadRequest)
+		return
+	}
+
+	notes, err := h.service.ListCreditNotes(r.Context(), id)
+	if err != nil {
+		if err == domaininvoice.ErrNotFound {
+			http.Error(w, "Invoice not found", http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(notes)
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	appcustomer "github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/application/customer"
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/customer"
+
+	"github.com/gorilla/mux"
+)
+
+type CustomerHandler struct {
+	service *appcustomer.Service
+}
+
+func NewCustomerHandler(service *appcustomer.Service) *CustomerHandler {
+	return &CustomerHandler{service: service}
+}
+
+type customerRequest struct {
+	Name              string `json:"name"`
+	Document          string `json:"document"`
+	Email             string `json:"email"`
+	StateRegistration string `json:"state_registration"`
+	Addresses         []struct {
+		Kind       string `json:"kind"`
+		Street     string `json:"street"`
+		Number     string `json:"number"`
+		Complement string `json:"complement"`
+		District   string `json:"district"`
+		CityCode   string `json:"city_code"`
+		City       string `json:"city"`
+		UF         string `json:"uf"`
+		CEP        string `json:"cep"`
+		Country    string `json:"country"`
+	} `json:"addresses"`
+}
+
+func (req customerRequest) input() appcustomer.Input {
+	in := appcustomer.Input{
+		Name:              req.Name,
+		Document:          req.Document,
+		Email:             req.Email,
+		StateRegistration: req.StateRegistration,
+		Addresses:         make([]customer.Address, 0, len(req.Addresses)),
+	}
+	for _, a := range req.Addresses {
+		in.Addresses = append(in.Addresses, customer.Address{
+			Kind:       customer.AddressKind(a.Kind),
+			Street:     a.Street,
+			Number:     a.Number,
+			Complement: a.Complement,
+			District:   a.District,
+			CityCode:   a.CityCode,
+			City:       a.City,
+			UF:         a.UF,
+			CEP:        a.CEP,
+			Country:    a.Country,
+		})
+	}
+	return in
+}
+
+func (h *CustomerHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var request customerRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	c, err := h.service.CreateCustomer(r.Context(), request.input())
+	if err != nil {
+		writeCustomerError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(c)
+}
+
+func (h *CustomerHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid customer ID", http.StatusBadRequest)
+		return
+	}
+
+	c, err := h.service.GetCustomer(r.Context(), id)
+	if err != nil {
+		writeCustomerError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(c)
+}
+
+func (h *CustomerHandler) List(w http.ResponseWriter, r *http.Request) {
+	customers, err := h.service.ListCustomers(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(customers)
+}
+
+func (h *CustomerHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid customer ID", http.StatusBadRequest)
+		return
+	}
+
+	var request customerRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	c, err := h.service.UpdateCustomer(r.Context(), id, request.input())
+	if err != nil {
+		writeCustomerError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(c)
+}
+
+func (h *CustomerHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid customer ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteCustomer(r.Context(), id); err != nil {
+		writeCustomerError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeCustomerError(w http.ResponseWriter, err error) {
+	switch err {
+	case customer.ErrNotFound:
+		http.Error(w, "Customer not found", http.StatusNotFound)
+	case customer.ErrDuplicateDocument, customer.ErrInUse:
+		http.Error(w, err.Error(), http.StatusConflict)
+	case customer.ErrInvalidName, customer.ErrInvalidDocument, customer.ErrInvalidEmail, customer.ErrInvalidAddress:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
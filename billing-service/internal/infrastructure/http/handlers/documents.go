@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	domaininvoice "github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/invoice"
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/infrastructure/nfe"
+
+	"github.com/gorilla/mux"
+)
+
+func (h *InvoiceHandler) GetInvoiceNFe(w http.ResponseWriter, r *http.Request) {
+	h.serveDocument(w, r, "application/xml; charset=utf-8", "nfe-%s.xml", h.nfe.Export)
+}
+
+func (h *InvoiceHandler) GetInvoicePDF(w http.ResponseWriter, r *http.Request) {
+	render := func(inv *domaininvoice.Invoice, payments []*domaininvoice.Payment) ([]byte, error) {
+		return h.nfe.RenderDANFE(inv, payments, h.pixPayload(r.Context(), inv.ID))
+	}
+	h.serveDocument(w, r, "application/pdf", "danfe-%s.pdf", render)
+}
+
+func (h *InvoiceHandler) serveDocument(w http.ResponseWriter, r *http.Request, contentType, filename string, render func(*domaininvoice.Invoice, []*domaininvoice.Payment) ([]byte, error)) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid invoice ID", http.StatusBadRequest)
+		return
+	}
+
+	inv, err := h.service.GetInvoiceByID(r.Context(), id)
+	if err != nil {
+		if err == domaininvoice.ErrNotFound {
+			http.Error(w, "Invoice not found", http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	summary, err := h.service.ListPayments(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	doc, err := render(inv, summary.Payments)
+	if err != nil {
+		if err == nfe.ErrInvoiceNotIssued {
+			http.Error(w, err.Error(), http.StatusConflict)
+		} else if err == nfe.ErrNotInBRL {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		} else {
+			log.Printf("Erro ao gerar documento da fatura %d: %v", id, err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=\""+filename+"\"", safeFilename(inv.Number)))
+	w.Write(doc)
+}
+
+// safeFilename keeps the invoice number usable inside a quoted header value:
+// anything but letters, digits, dots, dashes and underscores becomes an
+// underscore.
+func safeFilename(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			return r
+		}
+		return '_'
+	}, s)
+}
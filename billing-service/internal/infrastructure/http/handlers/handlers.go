@@ -2,27 +2,47 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
 
 	appinvoice "github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/application/invoice"
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/customer"
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/exchange"
 	domaininvoice "github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/invoice"
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/numbering"
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/infrastructure/nfe"
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/infrastructure/pix"
 
 	"github.com/gorilla/mux"
 )
 
+// inventoryRetryAfter is the Retry-After hint, in seconds, sent while the
+// inventory service cannot be reached.
+const inventoryRetryAfter = "5"
+
 type InvoiceHandler struct {
-	service *appinvoice.Service
+	service    *appinvoice.Service
+	printQueue *appinvoice.PrintQueue
+	nfe        *nfe.Exporter
+	pix        *pix.Receiver
 }
 
-func NewInvoiceHandler(service *appinvoice.Service) *InvoiceHandler {
-	return &InvoiceHandler{service: service}
+// NewInvoiceHandler builds the invoice handler. pixReceiver may be nil when
+// invoices cannot be paid through PIX.
+func NewInvoiceHandler(service *appinvoice.Service, printQueue *appinvoice.PrintQueue, nfeExporter *nfe.Exporter, pixReceiver *pix.Receiver) *InvoiceHandler {
+	return &InvoiceHandler{service: service, printQueue: printQueue, nfe: nfeExporter, pix: pixReceiver}
 }
 
 func (h *InvoiceHandler) CreateInvoice(w http.ResponseWriter, r *http.Request) {
 	var request struct {
-		Number string `json:"number"`
+		Number       string `json:"number"`
+		Series       string `json:"series"`
+		Draft        bool   `json:"draft"`
+		CustomerID   int    `json:"customer_id"`
+		Currency     string `json:"currency"`
+		PaymentTerms string `json:"payment_terms"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
@@ -30,9 +50,33 @@ func (h *InvoiceHandler) CreateInvoice(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	inv, err := h.service.CreateInvoice(r.Context(), request.Number)
+	terms, err := domaininvoice.ParsePaymentTerms(request.PaymentTerms)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	inv, err := h.service.CreateInvoice(r.Context(), appinvoice.CreateInput{
+		Number:       request.Number,
+		Series:       request.Series,
+		Draft:        request.Draft,
+		CustomerID:   request.CustomerID,
+		Currency:     request.Currency,
+		PaymentTerms: terms,
+	})
+	if err != nil {
+		switch err {
+		case customer.ErrNotFound:
+			http.Error(w, "Customer not found", http.StatusUnprocessableEntity)
+		case numbering.ErrNotFound:
+			http.Error(w, "Invoice series not found", http.StatusUnprocessableEntity)
+		case numbering.ErrInvalidNumber, domaininvoice.ErrInvalidCurrency:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case domaininvoice.ErrDuplicateNumber, numbering.ErrOutOfSequence:
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
 		return
 	}
 
@@ -64,8 +108,25 @@ func (h *InvoiceHandler) GetInvoice(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(inv)
 }
 
+// ListInvoices returns every invoice, or with ?overdue=true only those with
+// an overdue installment.
 func (h *InvoiceHandler) ListInvoices(w http.ResponseWriter, r *http.Request) {
-	invoices, err := h.service.ListInvoices(r.Context())
+	overdue := false
+	if value := r.URL.Query().Get("overdue"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			http.Error(w, "Invalid overdue filter", http.StatusBadRequest)
+			return
+		}
+		overdue = parsed
+	}
+
+	list := h.service.ListInvoices
+	if overdue {
+		list = h.service.ListOverdueInvoices
+	}
+
+	invoices, err := list(r.Context())
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -98,8 +159,17 @@ func (h *InvoiceHandler) AddInvoiceItem(w http.ResponseWriter, r *http.Request)
 	if err != nil {
 		if err == domaininvoice.ErrAlreadyClosed {
 			http.Error(w, "Invoice is already closed", http.StatusConflict)
+		} else if errors.Is(err, domaininvoice.ErrInvalidStatus) || errors.Is(err, domaininvoice.ErrConcurrentUpdate) {
+			http.Error(w, err.Error(), http.StatusConflict)
 		} else if err == appinvoice.ErrProductNotFound {
 			http.Error(w, "Product not found", http.StatusNotFound)
+		} else if err == appinvoice.ErrInsufficientStock || err == appinvoice.ErrStockReservation {
+			http.Error(w, err.Error(), http.StatusConflict)
+		} else if err == appinvoice.ErrInventoryService {
+			w.Header().Set("Retry-After", inventoryRetryAfter)
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		} else if errors.Is(err, exchange.ErrRateNotFound) {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
 		} else {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 		}
@@ -123,6 +193,11 @@ func (h *InvoiceHandler) PrintInvoice(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if r.URL.Query().Get("async") == "true" {
+		h.printAsync(w, r, id)
+		return
+	}
+
 	result, err := h.service.PrintInvoice(r.Context(), id)
 	if err != nil {
 		// We still have the result object with recovery details
@@ -132,8 +207,13 @@ func (h *InvoiceHandler) PrintInvoice(w http.ResponseWriter, r *http.Request) {
 
 			if err == domaininvoice.ErrAlreadyClosed {
 				statusCode = http.StatusConflict
+			} else if errors.Is(err, domaininvoice.ErrConcurrentUpdate) {
+				statusCode = http.StatusConflict
 			} else if err == appinvoice.ErrStockReservation {
 				statusCode = http.StatusConflict
+			} else if err == appinvoice.ErrInventoryService {
+				w.Header().Set("Retry-After", inventoryRetryAfter)
+				statusCode = http.StatusServiceUnavailable
 			}
 
 			// Return the detailed result even though there was an error
@@ -146,8 +226,19 @@ func (h *InvoiceHandler) PrintInvoice(w http.ResponseWriter, r *http.Request) {
 		// Handle the case where we don't even have a result object
 		if err == domaininvoice.ErrAlreadyClosed {
 			http.Error(w, "Invoice is already closed", http.StatusConflict)
+		} else if err == domaininvoice.ErrPrintInProgress {
+			http.Error(w, "Invoice is already being printed", http.StatusConflict)
+		} else if errors.Is(err, domaininvoice.ErrInvalidStatus) || errors.Is(err, domaininvoice.ErrConcurrentUpdate) {
+			http.Error(w, err.Error(), http.StatusConflict)
+		} else if err == domaininvoice.ErrEmptyInvoice {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		} else if err == domaininvoice.ErrNotFound {
+			http.Error(w, "Invoice not found", http.StatusNotFound)
 		} else if err == appinvoice.ErrStockReservation {
 			http.Error(w, "Insufficient stock for one or more products", http.StatusConflict)
+		} else if err == appinvoice.ErrInventoryService {
+			w.Header().Set("Retry-After", inventoryRetryAfter)
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
 		} else {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 		}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	domaininvoice "github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/invoice"
+
+	"github.com/gorilla/mux"
+)
+
+// SetPaymentTerms replaces the terms an unissued invoice will be split by,
+// e.g. {"payment_terms": "30/60/90"}.
+func (h *InvoiceHandler) SetPaymentTerms(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid invoice ID", http.StatusBadRequest)
+		return
+	}
+
+	var request struct {
+		PaymentTerms string `json:"payment_terms"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	terms, err := domaininvoice.ParsePaymentTerms(request.PaymentTerms)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	inv, err := h.service.SetPaymentTerms(r.Context(), id, terms)
+	if err != nil {
+		switch {
+		case err == domaininvoice.ErrNotFound:
+			http.Error(w, "Invoice not found", http.StatusNotFound)
+		case errors.Is(err, domaininvoice.ErrInvalidStatus), errors.Is(err, domaininvoice.ErrConcurrentUpdate), err == domaininvoice.ErrAlreadyClosed:
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(inv)
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	appinvoice "github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/application/invoice"
+	domaininvoice "github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/invoice"
+
+	"github.com/gorilla/mux"
+)
+
+func (h *InvoiceHandler) UpdateInvoiceItem(w http.ResponseWriter, r *http.Request) {
+	id, itemID, ok := itemVars(w, r)
+	if !ok {
+		return
+	}
+
+	var request struct {
+		Quantity int `json:"quantity"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.UpdateInvoiceItem(r.Context(), id, itemID, request.Quantity); err != nil {
+		writeItemError(w, err)
+		return
+	}
+
+	inv, _ := h.service.GetInvoiceByID(r.Context(), id)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(inv)
+}
+
+func (h *InvoiceHandler) RemoveInvoiceItem(w http.ResponseWriter, r *http.Request) {
+	id, itemID, ok := itemVars(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.RemoveInvoiceItem(r.Context(), id, itemID); err != nil {
+		writeItemError(w, err)
+		return
+	}
+
+	inv, _ := h.service.GetInvoiceByID(r.Context(), id)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(inv)
+}
+
+func itemVars(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid invoice ID", http.StatusBadRequest)
+		return 0, 0, false
+	}
+	itemID, err := strconv.Atoi(vars["itemId"])
+	if err != nil {
+		http.Error(w, "Invalid item ID", http.StatusBadRequest)
+		return 0, 0, false
+	}
+	return id, itemID, true
+}
+
+func writeItemError(w http.ResponseWriter, err error) {
+	switch {
+	case err == domaininvoice.ErrNotFound:
+		http.Error(w, "Invoice not found", http.StatusNotFound)
+	case err == domaininvoice.ErrItemNotFound:
+		http.Error(w, "Item not found", http.StatusNotFound)
+	case err == domaininvoice.ErrAlreadyClosed:
+		http.Error(w, "Invoice is already closed", http.StatusConflict)
+	case errors.Is(err, domaininvoice.ErrInvalidStatus), errors.Is(err, domaininvoice.ErrConcurrentUpdate):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case err == domaininvoice.ErrNegativeTotal:
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	case err == appinvoice.ErrInvalidQuantity:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case err == appinvoice.ErrStockReservation:
+		http.Error(w, err.Error(), http.StatusConflict)
+	case err == appinvoice.ErrInventoryService:
+		w.Header().Set("Retry-After", inventoryRetryAfter)
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
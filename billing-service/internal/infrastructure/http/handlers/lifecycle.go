@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	domaininvoice "github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/invoice"
+
+	"github.com/gorilla/mux"
+)
+
+func (h *InvoiceHandler) OpenInvoice(w http.ResponseWriter, r *http.Request) {
+	h.applyTransition(w, r, func(id int, _ string) (*domaininvoice.Invoice, error) {
+		return h.service.OpenInvoice(r.Context(), id)
+	})
+}
+
+func (h *InvoiceHandler) CancelInvoice(w http.ResponseWriter, r *http.Request) {
+	h.applyTransition(w, r, func(id int, reason string) (*domaininvoice.Invoice, error) {
+		return h.service.CancelInvoice(r.Context(), id, reason)
+	})
+}
+
+func (h *InvoiceHandler) VoidInvoice(w http.ResponseWriter, r *http.Request) {
+	h.applyTransition(w, r, func(id int, reason string) (*domaininvoice.Invoice, error) {
+		return h.service.VoidInvoice(r.Context(), id, reason)
+	})
+}
+
+func (h *InvoiceHandler) ListTransitions(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid invoice ID", http.StatusBadRequest)
+		return
+	}
+
+	transitions, err := h.service.ListTransitions(r.Context(), id)
+	if err != nil {
+		if err == domaininvoice.ErrNotFound {
+			http.Error(w, "Invoice not found", http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transitions)
+}
+
+// applyTransition handles the status endpoints, which share an optional
+// {"reason": "..."} body and the same error mapping.
+func (h *InvoiceHandler) applyTransition(w http.ResponseWriter, r *http.Request, apply func(id int, reason string) (*domaininvoice.Invoice, error)) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid invoice ID", http.StatusBadRequest)
+		return
+	}
+
+	var request struct {
+		Reason string `json:"reason"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	inv, err := apply(id, request.Reason)
+	if err != nil {
+		if err == domaininvoice.ErrNotFound {
+			http.Error(w, "Invoice not found", http.StatusNotFound)
+		} else if errors.Is(err, domaininvoice.ErrInvalidStatus) || errors.Is(err, domaininvoice.ErrConcurrentUpdate) {
+			http.Error(w, err.Error(), http.StatusConflict)
+		} else if err == domaininvoice.ErrCancellationWindowExpired {
+			http.Error(w, err.Error(), http.StatusConflict)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(inv)
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	domaininvoice "github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/invoice"
+	"github.com/vitorwhois/microservice-invoice-billing/money"
+
+	"github.com/gorilla/mux"
+)
+
+func (h *InvoiceHandler) RecordPayment(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid invoice ID", http.StatusBadRequest)
+		return
+	}
+
+	// paid_at defaults to now
+	var request struct {
+		Method    string        `json:"method"`
+		Amount    money.Literal `json:"amount"`
+		PaidAt    *time.Time    `json:"paid_at"`
+		Reference string        `json:"reference"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var paidAt time.Time
+	if request.PaidAt != nil {
+		paidAt = *request.PaidAt
+	}
+
+	summary, err := h.service.RecordPayment(r.Context(), id,
+		domaininvoice.PaymentMethod(request.Method), request.Amount, paidAt, request.Reference)
+	if err != nil {
+		switch {
+		case err == domaininvoice.ErrNotFound:
+			http.Error(w, "Invoice not found", http.StatusNotFound)
+		case err == domaininvoice.ErrInvalidPayment:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case err == domaininvoice.ErrAlreadyPaid, errors.Is(err, domaininvoice.ErrInvalidStatus), errors.Is(err, domaininvoice.ErrConcurrentUpdate):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(summary)
+}
+
+func (h *InvoiceHandler) ListPayments(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid invoice ID", http.StatusBadRequest)
+		return
+	}
+
+	summary, err := h.service.ListPayments(r.Context(), id)
+	if err != nil {
+		if err == domaininvoice.ErrNotFound {
+			http.Error(w, "Invoice not found", http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summary)
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	domaininvoice "github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/invoice"
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/infrastructure/pix"
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/infrastructure/qrcode"
+	"github.com/vitorwhois/microservice-invoice-billing/money"
+
+	"github.com/gorilla/mux"
+)
+
+// pixQRScale is the size of a QR code module, in pixels.
+const pixQRScale = 6
+
+type pixResponse struct {
+	InvoiceID int         `json:"invoice_id"`
+	TxID      string      `json:"txid"`
+	Amount    money.Money `json:"amount"`
+	Dynamic   bool        `json:"dynamic"`
+	Payload   string      `json:"payload"`
+	QRCode    string      `json:"qr_code"`
+}
+
+// GetPix returns the PIX "copia e cola" code charging the outstanding balance
+// of the invoice, with its QR code as a PNG data URI.
+func (h *InvoiceHandler) GetPix(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid invoice ID", http.StatusBadRequest)
+		return
+	}
+
+	charge, payload, err := h.pixCharge(r.Context(), id)
+	if err != nil {
+		writePixError(w, id, err)
+		return
+	}
+
+	png, err := pixQRCode(payload)
+	if err != nil {
+		writePixError(w, id, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pixResponse{
+		InvoiceID: id,
+		TxID:      charge.TxID,
+		Amount:    charge.Amount,
+		Dynamic:   charge.Dynamic(),
+		Payload:   payload,
+		QRCode:    "data:image/png;base64," + base64.StdEncoding.EncodeToString(png),
+	})
+}
+
+// GetPixQRCode returns the QR code of the invoice's PIX charge as a PNG image.
+func (h *InvoiceHandler) GetPixQRCode(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid invoice ID", http.StatusBadRequest)
+		return
+	}
+
+	_, payload, err := h.pixCharge(r.Context(), id)
+	if err != nil {
+		writePixError(w, id, err)
+		return
+	}
+
+	png, err := pixQRCode(payload)
+	if err != nil {
+		writePixError(w, id, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Write(png)
+}
+
+var errPixDisabled = errors.New("PIX is not configured")
+
+func (h *InvoiceHandler) pixCharge(ctx context.Context, id int) (pix.Charge, string, error) {
+	if h.pix == nil {
+		return pix.Charge{}, "", errPixDisabled
+	}
+
+	inv, due, err := h.service.AmountDue(ctx, id)
+	if err != nil {
+		return pix.Charge{}, "", err
+	}
+
+	charge := h.pix.Charge(inv.Number, due, "Fatura "+inv.Number)
+	payload, err := charge.Payload()
+	if err != nil {
+		return pix.Charge{}, "", err
+	}
+	return charge, payload, nil
+}
+
+// pixPayload is the code printed on documents, empty when the invoice cannot
+// be paid through PIX.
+func (h *InvoiceHandler) pixPayload(ctx context.Context, id int) string {
+	_, payload, err := h.pixCharge(ctx, id)
+	if err != nil {
+		if err != errPixDisabled && err != domaininvoice.ErrAlreadyPaid && !errors.Is(err, domaininvoice.ErrInvalidStatus) {
+			log.Printf("PIX omitido do documento da fatura %d: %v", id, err)
+		}
+		return ""
+	}
+	return payload
+}
+
+func pixQRCode(payload string) ([]byte, error) {
+	code, err := qrcode.Encode([]byte(payload), qrcode.Medium)
+	if err != nil {
+		return nil, err
+	}
+	return code.PNG(pixQRScale)
+}
+
+func writePixError(w http.ResponseWriter, id int, err error) {
+	switch {
+	case err == errPixDisabled:
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+	case err == domaininvoice.ErrNotFound:
+		http.Error(w, "Invoice not found", http.StatusNotFound)
+	case err == domaininvoice.ErrAlreadyPaid, errors.Is(err, domaininvoice.ErrInvalidStatus), errors.Is(err, domaininvoice.ErrConcurrentUpdate):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, pix.ErrInvalidCharge):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	default:
+		log.Printf("Erro ao gerar PIX da fatura %d: %v", id, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
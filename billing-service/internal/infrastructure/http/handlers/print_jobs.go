@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	appinvoice "github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/application/invoice"
+	domaininvoice "github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/invoice"
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/printjob"
+
+	"github.com/gorilla/mux"
+)
+
+type printJobResponse struct {
+	ID           int                        `json:"id"`
+	InvoiceID    int                        `json:"invoice_id"`
+	Status       printjob.Status            `json:"status"`
+	StepReached  string                     `json:"step_reached,omitempty"`
+	FailedReason string                     `json:"failed_reason,omitempty"`
+	Recovery     appinvoice.RecoveryDetails `json:"recovery"`
+	Attempts     int                        `json:"attempts"`
+	CreatedAt    time.Time                  `json:"created_at"`
+	StartedAt    *time.Time                 `json:"started_at,omitempty"`
+	FinishedAt   *time.Time                 `json:"finished_at,omitempty"`
+}
+
+func newPrintJobResponse(job *printjob.Job) printJobResponse {
+	return printJobResponse{
+		ID:           job.ID,
+		InvoiceID:    job.InvoiceID,
+		Status:       job.Status,
+		StepReached:  job.StepReached,
+		FailedReason: job.FailedReason,
+		Recovery: appinvoice.RecoveryDetails{
+			Attempted:  job.Recovery.Attempted,
+			Successful: job.Recovery.Successful,
+			Message:    job.Recovery.Message,
+			Details:    job.Recovery.Details,
+		},
+		Attempts:   job.Attempts,
+		CreatedAt:  job.CreatedAt,
+		StartedAt:  job.StartedAt,
+		FinishedAt: job.FinishedAt,
+	}
+}
+
+// printAsync queues the print and answers 202 with the job to poll.
+func (h *InvoiceHandler) printAsync(w http.ResponseWriter, r *http.Request, id int) {
+	job, err := h.printQueue.Submit(r.Context(), id)
+	if err != nil {
+		switch err {
+		case domaininvoice.ErrNotFound:
+			http.Error(w, "Invoice not found", http.StatusNotFound)
+		case domaininvoice.ErrAlreadyClosed:
+			http.Error(w, "Invoice is already closed", http.StatusConflict)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", fmt.Sprintf("/print-jobs/%d", job.ID))
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(newPrintJobResponse(job))
+}
+
+func (h *InvoiceHandler) GetPrintJob(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid print job ID", http.StatusBadRequest)
+		return
+	}
+
+	job, err := h.printQueue.Get(r.Context(), id)
+	if err != nil {
+		if err == printjob.ErrNotFound {
+			http.Error(w, "Print job not found", http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newPrintJobResponse(job))
+}
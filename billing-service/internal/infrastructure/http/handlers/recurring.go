@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	apprecurring "github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/application/recurring"
+	domaininvoice "github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/invoice"
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/recurring"
+
+	"github.com/gorilla/mux"
+)
+
+type RecurringHandler struct {
+	service *apprecurring.Service
+}
+
+func NewRecurringHandler(service *apprecurring.Service) *RecurringHandler {
+	return &RecurringHandler{service: service}
+}
+
+// scheduleRequest is the body of create and update. cadence is WEEKLY,
+// MONTHLY or CRON, the latter with a five-field cron expression in UTC.
+type scheduleRequest struct {
+	Name         string     `json:"name"`
+	CustomerID   int        `json:"customer_id"`
+	Series       string     `json:"series"`
+	PaymentTerms string     `json:"payment_terms"`
+	Cadence      string     `json:"cadence"`
+	Cron         string     `json:"cron"`
+	StartAt      time.Time  `json:"start_at"`
+	EndAt        *time.Time `json:"end_at"`
+	AutoPrint    bool       `json:"auto_print"`
+	Items        []struct {
+		ProductID int `json:"product_id"`
+		Quantity  int `json:"quantity"`
+	} `json:"items"`
+}
+
+func (req *scheduleRequest) definition() (recurring.Definition, error) {
+	terms, err := domaininvoice.ParsePaymentTerms(req.PaymentTerms)
+	if err != nil {
+		return recurring.Definition{}, err
+	}
+
+	def := recurring.Definition{
+		Name:         req.Name,
+		CustomerID:   req.CustomerID,
+		Series:       req.Series,
+		PaymentTerms: terms,
+		Cadence:      recurring.Cadence(req.Cadence),
+		Cron:         req.Cron,
+		StartAt:      req.StartAt,
+		EndAt:        req.EndAt,
+		AutoPrint:    req.AutoPrint,
+	}
+	for _, item := range req.Items {
+		def.Items = append(def.Items, recurring.Item{ProductID: item.ProductID, Quantity: item.Quantity})
+	}
+	return def, nil
+}
+
+func (h *RecurringHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var request scheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	def, err := request.definition()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	schedule, err := h.service.CreateSchedule(r.Context(), def)
+	if err != nil {
+		writeRecurringError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(schedule)
+}
+
+func (h *RecurringHandler) List(w http.ResponseWriter, r *http.Request) {
+	schedules, err := h.service.ListSchedules(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(schedules)
+}
+
+func (h *RecurringHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid schedule ID", http.StatusBadRequest)
+		return
+	}
+
+	schedule, err := h.service.GetSchedule(r.Context(), id)
+	if err != nil {
+		writeRecurringError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(schedule)
+}
+
+func (h *RecurringHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid schedule ID", http.StatusBadRequest)
+		return
+	}
+
+	var request scheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	def, err := request.definition()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	schedule, err := h.service.UpdateSchedule(r.Context(), id, def)
+	if err != nil {
+		writeRecurringError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(schedule)
+}
+
+// Delete deactivates the schedule. It is kept, with its runs, for reference.
+func (h *RecurringHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid schedule ID", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := h.service.DeactivateSchedule(r.Context(), id); err != nil {
+		writeRecurringError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *RecurringHandler) ListRuns(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid schedule ID", http.StatusBadRequest)
+		return
+	}
+
+	runs, err := h.service.ListRuns(r.Context(), id)
+	if err != nil {
+		writeRecurringError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(runs)
+}
+
+func writeRecurringError(w http.ResponseWriter, err error) {
+	switch err {
+	case recurring.ErrNotFound:
+		http.Error(w, "Recurring invoice schedule not found", http.StatusNotFound)
+	case recurring.ErrInvalidSchedule, recurring.ErrInvalidCadence:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case recurring.ErrInactive:
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
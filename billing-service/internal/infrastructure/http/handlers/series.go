@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	appnumbering "github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/application/numbering"
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/numbering"
+
+	"github.com/gorilla/mux"
+)
+
+type SeriesHandler struct {
+	service *appnumbering.Service
+}
+
+func NewSeriesHandler(service *appnumbering.Service) *SeriesHandler {
+	return &SeriesHandler{service: service}
+}
+
+func (h *SeriesHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Code        string `json:"code"`
+		Prefix      string `json:"prefix"`
+		Padding     int    `json:"padding"`
+		ResetYearly bool   `json:"reset_yearly"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	series, err := h.service.CreateSeries(r.Context(), request.Code, request.Prefix, request.Padding, request.ResetYearly)
+	if err != nil {
+		writeSeriesError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(series)
+}
+
+func (h *SeriesHandler) Get(w http.ResponseWriter, r *http.Request) {
+	series, err := h.service.GetSeries(r.Context(), mux.Vars(r)["code"])
+	if err != nil {
+		writeSeriesError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(series)
+}
+
+func (h *SeriesHandler) List(w http.ResponseWriter, r *http.Request) {
+	series, err := h.service.ListSeries(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(series)
+}
+
+func writeSeriesError(w http.ResponseWriter, err error) {
+	switch err {
+	case numbering.ErrNotFound:
+		http.Error(w, "Invoice series not found", http.StatusNotFound)
+	case numbering.ErrDuplicateCode:
+		http.Error(w, err.Error(), http.StatusConflict)
+	case numbering.ErrInvalidSeries:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
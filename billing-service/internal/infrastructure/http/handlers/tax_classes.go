@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	apptax "github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/application/tax"
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/tax"
+
+	"github.com/gorilla/mux"
+)
+
+type TaxClassHandler struct {
+	service *apptax.Service
+}
+
+func NewTaxClassHandler(service *apptax.Service) *TaxClassHandler {
+	return &TaxClassHandler{service: service}
+}
+
+func (h *TaxClassHandler) Get(w http.ResponseWriter, r *http.Request) {
+	productID, err := strconv.Atoi(mux.Vars(r)["productId"])
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+
+	class, err := h.service.GetClass(r.Context(), productID)
+	if err != nil {
+		writeTaxClassError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(class)
+}
+
+func (h *TaxClassHandler) Save(w http.ResponseWriter, r *http.Request) {
+	productID, err := strconv.Atoi(mux.Vars(r)["productId"])
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+
+	var request struct {
+		NCM     string   `json:"ncm"`
+		IPIRate tax.Rate `json:"ipi_rate"`
+		Exempt  bool     `json:"exempt"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	class, err := h.service.SaveClass(r.Context(), productID, request.NCM, request.IPIRate, request.Exempt)
+	if err != nil {
+		writeTaxClassError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(class)
+}
+
+func writeTaxClassError(w http.ResponseWriter, err error) {
+	switch err {
+	case tax.ErrClassNotFound:
+		http.Error(w, "Tax class not found", http.StatusNotFound)
+	case tax.ErrInvalidNCM, tax.ErrInvalidRate:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
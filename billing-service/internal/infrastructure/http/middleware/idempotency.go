@@ -0,0 +1,165 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/idempotency"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotentReplayedHeader  = "Idempotent-Replayed"
+	maxIdempotencyKeyLength   = 255
+	maxIdempotentRequestBytes = 1 << 20
+)
+
+type Idempotency struct {
+	repo        idempotency.Repository
+	lockTimeout time.Duration
+	retention   time.Duration
+}
+
+// NewIdempotency stores responses for retention. A request still running
+// after lockTimeout is presumed dead, and a retry with the same key and
+// payload runs the handler again.
+func NewIdempotency(repo idempotency.Repository, lockTimeout, retention time.Duration) *Idempotency {
+	return &Idempotency{repo: repo, lockTimeout: lockTimeout, retention: retention}
+}
+
+// Wrap makes next safe to retry: the first response for a given
+// Idempotency-Key is stored and replayed for every later request carrying the
+// same key and payload. Requests without the header pass straight through.
+func (m *Idempotency) Wrap(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" {
+			next(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			http.Error(w, "Idempotency-Key is too long", http.StatusBadRequest)
+			return
+		}
+
+		// Read one byte past the limit so an oversize body is told apart from
+		// one that fits exactly
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxIdempotentRequestBytes+1))
+		if err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if len(body) > maxIdempotentRequestBytes {
+			http.Error(w, "Request body is too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		hash := fingerprint(r, body)
+		rec, created, err := m.repo.Reserve(r.Context(), key, hash, time.Now().Add(-m.lockTimeout))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if !created {
+			switch {
+			case rec.RequestHash != hash:
+				http.Error(w, idempotency.ErrKeyReused.Error(), http.StatusUnprocessableEntity)
+			case !rec.Completed:
+				http.Error(w, idempotency.ErrKeyInProgress.Error(), http.StatusConflict)
+			default:
+				replay(w, rec)
+			}
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next(recorder, r)
+
+		// The outcome must be stored even if the client already hung up
+		ctx := context.WithoutCancel(r.Context())
+		if recorder.status >= http.StatusInternalServerError {
+			// Server errors are not final: let the client retry with the same key
+			if err := m.repo.Release(ctx, rec); err != nil {
+				log.Printf("Falha ao liberar chave de idempotência %s: %v", key, err)
+			}
+			return
+		}
+
+		rec.StatusCode = recorder.status
+		rec.ContentType = recorder.Header().Get("Content-Type")
+		rec.Body = recorder.body.Bytes()
+		if err := m.repo.Complete(ctx, rec); err != nil {
+			log.Printf("Falha ao registrar resposta para chave de idempotência %s: %v", key, err)
+		}
+	}
+}
+
+// RunCleanup purges the keys past their retention every interval until ctx
+// is done.
+func (m *Idempotency) RunCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := m.repo.Purge(ctx, time.Now().Add(-m.retention))
+		if err != nil {
+			log.Printf("Falha ao remover chaves de idempotência expiradas: %v", err)
+		} else if n > 0 {
+			log.Printf("%d chave(s) de idempotência expirada(s) removida(s)", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func fingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method))
+	h.Write([]byte{0})
+	h.Write([]byte(r.URL.RequestURI()))
+	h.Write([]byte{0})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func replay(w http.ResponseWriter, rec *idempotency.Record) {
+	if rec.ContentType != "" {
+		w.Header().Set("Content-Type", rec.ContentType)
+	}
+	w.Header().Set(IdempotentReplayedHeader, "true")
+	w.WriteHeader(rec.StatusCode)
+	w.Write(rec.Body)
+}
+
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
@@ -0,0 +1,354 @@
+package inventory
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/vitorwhois/microservice-invoice-billing/money"
+)
+
+type Config struct {
+	BaseURL          string
+	Timeout          time.Duration
+	MaxRetries       int
+	RetryBaseDelay   time.Duration
+	RetryMaxDelay    time.Duration
+	BreakerThreshold int
+	BreakerOpenFor   time.Duration
+	// HoldTTL is how long the stock of an invoice line stays reserved
+	HoldTTL time.Duration
+}
+
+// Product is priced in Currency, BRL for products created before the
+// inventory quoted other currencies.
+type Product struct {
+	ID       int         `json:"id"`
+	Name     string      `json:"name"`
+	Price    money.Money `json:"price"`
+	Currency string      `json:"currency"`
+	Stock    int         `json:"stock"`
+}
+
+// Client talks to the inventory service. Every attempt runs under its own
+// deadline derived from the caller's context; only idempotent calls are
+// retried, and all calls share one circuit breaker.
+type Client struct {
+	cfg     Config
+	http    *http.Client
+	breaker *breaker
+}
+
+func NewClient(cfg Config) *Client {
+	return &Client{
+		cfg:     cfg,
+		http:    &http.Client{},
+		breaker: newBreaker(cfg.BreakerThreshold, cfg.BreakerOpenFor),
+	}
+}
+
+func (c *Client) GetProduct(ctx context.Context, productID int) (*Product, error) {
+	// The price is read as text since its minor units depend on the currency
+	var body struct {
+		ID       int           `json:"id"`
+		Name     string        `json:"name"`
+		Price    money.Literal `json:"price"`
+		Currency string        `json:"currency"`
+		Stock    int           `json:"stock"`
+	}
+	path := fmt.Sprintf("/products/%d", productID)
+	if err := c.do(ctx, "get product", http.MethodGet, path, nil, &body, true); err != nil {
+		return nil, err
+	}
+
+	product := &Product{ID: body.ID, Name: body.Name, Currency: body.Currency, Stock: body.Stock}
+	if product.Currency == "" {
+		product.Currency = money.DefaultCurrency
+	}
+	price, err := body.Price.In(product.Currency)
+	if err != nil {
+		return nil, fmt.Errorf("get product %d: %w", productID, err)
+	}
+	product.Price = price
+	return product, nil
+}
+
+// Reservation is a hold on stock in the inventory service, addressed by ID
+// so that only its owner can confirm, resize or cancel it.
+type Reservation struct {
+	ID        int
+	ProductID int
+	Quantity  int
+	Status    string
+	ExpiresAt time.Time
+}
+
+const (
+	ReservationConfirmed = "CONFIRMED"
+	ReservationCancelled = "CANCELLED"
+	ReservationExpired   = "EXPIRED"
+)
+
+// Reserve holds quantity units of the product for owner. A zero ttl uses the
+// inventory default. It is not retried: a lost response would leave a second
+// reservation behind.
+func (c *Client) Reserve(ctx context.Context, productID int, quantity int, owner string, ttl time.Duration) (*Reservation, error) {
+	in := map[string]interface{}{
+		"product_id":  productID,
+		"quantity":    quantity,
+		"owner":       owner,
+		"ttl_seconds": int(ttl / time.Second),
+	}
+	var res Reservation
+	if err := c.do(ctx, "reserve stock", http.MethodPost, "/reservations", in, &res, false); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// Hold reserves stock for an invoice line for the configured hold TTL.
+func (c *Client) Hold(ctx context.Context, productID int, quantity int, owner string) (*Reservation, error) {
+	return c.Reserve(ctx, productID, quantity, owner, c.cfg.HoldTTL)
+}
+
+func (c *Client) GetReservation(ctx context.Context, id int) (*Reservation, error) {
+	var res Reservation
+	path := fmt.Sprintf("/reservations/%d", id)
+	if err := c.do(ctx, "get reservation", http.MethodGet, path, nil, &res, true); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// ConfirmReservation consumes the reserved stock. Confirming an already
+// confirmed reservation succeeds, so the call is safe to retry.
+func (c *Client) ConfirmReservation(ctx context.Context, id int) error {
+	path := fmt.Sprintf("/reservations/%d/confirm", id)
+	err := c.do(ctx, "confirm reservation", http.MethodPost, path, nil, nil, true)
+	return c.settled(ctx, id, err, ReservationConfirmed)
+}
+
+// CancelReservation releases the reserved stock. A reservation that is already
+// cancelled or expired no longer holds anything, so that counts as success.
+func (c *Client) CancelReservation(ctx context.Context, id int) error {
+	path := fmt.Sprintf("/reservations/%d/cancel", id)
+	err := c.do(ctx, "cancel reservation", http.MethodPost, path, nil, nil, true)
+	return c.settled(ctx, id, err, ReservationCancelled, ReservationExpired)
+}
+
+// ResizeReservation changes the quantity held from expected to quantity.
+// ErrConflict means somebody else resized it first; ErrReservationInactive
+// that it no longer holds stock.
+func (c *Client) ResizeReservation(ctx context.Context, id int, quantity, expected int) error {
+	path := fmt.Sprintf("/reservations/%d/resize", id)
+	in := map[string]int{"quantity": quantity, "expected_quantity": expected}
+	return c.do(ctx, "resize reservation", http.MethodPost, path, in, nil, false)
+}
+
+// settled turns the refusal to move a reservation that already reached one
+// of the wanted statuses into success.
+func (c *Client) settled(ctx context.Context, id int, err error, wanted ...string) error {
+	if !errors.Is(err, ErrReservationInactive) {
+		return err
+	}
+	res, gerr := c.GetReservation(ctx, id)
+	if gerr != nil {
+		return err
+	}
+	for _, status := range wanted {
+		if res.Status == status {
+			return nil
+		}
+	}
+	return err
+}
+
+// BatchLine asks for quantity units of a product. HoldID names the
+// reservation already holding them, whose units the batch takes over.
+type BatchLine struct {
+	ProductID int `json:"product_id"`
+	Quantity  int `json:"quantity"`
+	HoldID    int `json:"hold_id,omitempty"`
+}
+
+// ReservationBatch reserves the stock of several products at once: every
+// line is reserved, confirmed or cancelled together.
+type ReservationBatch struct {
+	ID        int
+	Status    string
+	ExpiresAt time.Time
+}
+
+// ReserveBatch reserves every line for owner or none of them. A zero ttl uses
+// the inventory default. Like Reserve it is not retried.
+func (c *Client) ReserveBatch(ctx context.Context, lines []BatchLine, owner string, ttl time.Duration) (*ReservationBatch, error) {
+	in := map[string]interface{}{
+		"owner":       owner,
+		"ttl_seconds": int(ttl / time.Second),
+		"lines":       lines,
+	}
+	var b ReservationBatch
+	if err := c.do(ctx, "reserve batch", http.MethodPost, "/reservation-batches", in, &b, false); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+func (c *Client) GetBatch(ctx context.Context, id int) (*ReservationBatch, error) {
+	var b ReservationBatch
+	path := fmt.Sprintf("/reservation-batches/%d", id)
+	if err := c.do(ctx, "get reservation batch", http.MethodGet, path, nil, &b, true); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// ConfirmBatch consumes the stock of every line of the batch. Confirming an
+// already confirmed batch succeeds.
+func (c *Client) ConfirmBatch(ctx context.Context, id int) error {
+	path := fmt.Sprintf("/reservation-batches/%d/confirm", id)
+	err := c.do(ctx, "confirm reservation batch", http.MethodPost, path, nil, nil, true)
+	return c.batchSettled(ctx, id, err, ReservationConfirmed)
+}
+
+// CancelBatch releases the stock of every line of the batch. A batch already
+// cancelled or expired counts as success.
+func (c *Client) CancelBatch(ctx context.Context, id int) error {
+	path := fmt.Sprintf("/reservation-batches/%d/cancel", id)
+	err := c.do(ctx, "cancel reservation batch", http.MethodPost, path, nil, nil, true)
+	return c.batchSettled(ctx, id, err, ReservationCancelled, ReservationExpired)
+}
+
+// batchSettled is settled for batches.
+func (c *Client) batchSettled(ctx context.Context, id int, err error, wanted ...string) error {
+	if !errors.Is(err, ErrReservationInactive) {
+		return err
+	}
+	b, gerr := c.GetBatch(ctx, id)
+	if gerr != nil {
+		return err
+	}
+	for _, status := range wanted {
+		if b.Status == status {
+			return nil
+		}
+	}
+	return err
+}
+
+// Restock returns units to inventory. The inventory applies each key once,
+// so the call is retried and can be repeated after a lost response.
+func (c *Client) Restock(ctx context.Context, productID int, quantity int, key string) error {
+	path := fmt.Sprintf("/products/%d/restock", productID)
+	in := map[string]interface{}{"quantity": quantity, "idempotency_key": key}
+	return c.do(ctx, "restock", http.MethodPost, path, in, nil, true)
+}
+
+func (c *Client) do(ctx context.Context, op, method, path string, in, out interface{}, idempotent bool) error {
+	var payload []byte
+	if in != nil {
+		var err error
+		if payload, err = json.Marshal(in); err != nil {
+			return err
+		}
+	}
+
+	attempts := 1
+	if idempotent {
+		attempts += c.cfg.MaxRetries
+	}
+
+	var err error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			log.Printf("Retentativa %d de %s no inventário após erro: %v", attempt, op, err)
+			if werr := c.wait(ctx, attempt); werr != nil {
+				return err
+			}
+		}
+
+		if berr := c.breaker.allow(); berr != nil {
+			return berr
+		}
+
+		err = c.attempt(ctx, op, method, path, payload, out)
+		if !retryable(err) {
+			// 4xx answers prove the service is up, so they do not trip the breaker
+			c.breaker.success()
+			return err
+		}
+		c.breaker.failure()
+	}
+	return err
+}
+
+func (c *Client) attempt(ctx context.Context, op, method, path string, payload []byte, out interface{}) error {
+	if c.cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.cfg.Timeout)
+		defer cancel()
+	}
+
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.cfg.BaseURL+path, body)
+	if err != nil {
+		return err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return &StatusError{Op: op, Body: err.Error(), Err: ErrUnavailable}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		raw, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		text := strings.TrimSpace(string(raw))
+		return &StatusError{Op: op, StatusCode: resp.StatusCode, Body: text, Err: errorForStatus(resp.StatusCode, text)}
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("%s: decoding response: %w", op, err)
+	}
+	return nil
+}
+
+// wait sleeps for an exponential backoff with full jitter before the given
+// retry attempt, returning early if ctx is done.
+func (c *Client) wait(ctx context.Context, attempt int) error {
+	delay := c.cfg.RetryBaseDelay << uint(attempt-1)
+	if c.cfg.RetryMaxDelay > 0 && (delay > c.cfg.RetryMaxDelay || delay <= 0) {
+		delay = c.cfg.RetryMaxDelay
+	}
+	if delay > 0 {
+		delay = time.Duration(rand.Int63n(int64(delay))) + 1
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,57 @@
+package inventory
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+var (
+	ErrNotFound          = errors.New("inventory: resource not found")
+	ErrInsufficientStock = errors.New("inventory: insufficient stock")
+	ErrConflict          = errors.New("inventory: conflicting update")
+	// ErrReservationInactive is a reservation already confirmed, cancelled
+	// or expired
+	ErrReservationInactive = errors.New("inventory: reservation no longer active")
+	ErrBadRequest          = errors.New("inventory: request rejected")
+	ErrUnavailable         = errors.New("inventory: service unavailable")
+	ErrCircuitOpen         = errors.New("inventory: circuit breaker open")
+)
+
+// StatusError describes a non-2xx answer from the inventory service. It wraps
+// one of the sentinel errors above so callers can use errors.Is.
+type StatusError struct {
+	Op         string
+	StatusCode int
+	Body       string
+	Err        error
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s: status %d: %s", e.Op, e.StatusCode, e.Body)
+}
+
+func (e *StatusError) Unwrap() error {
+	return e.Err
+}
+
+func errorForStatus(status int, body string) error {
+	switch {
+	case status == http.StatusNotFound:
+		return ErrNotFound
+	case status == http.StatusConflict && body == "insufficient stock":
+		return ErrInsufficientStock
+	case status == http.StatusConflict && body == "reservation is no longer active", status == http.StatusGone:
+		return ErrReservationInactive
+	case status == http.StatusConflict:
+		return ErrConflict
+	case status >= 500, status == http.StatusTooManyRequests:
+		return ErrUnavailable
+	}
+	return ErrBadRequest
+}
+
+// retryable reports whether err is a transient failure worth another attempt.
+func retryable(err error) bool {
+	return err != nil && errors.Is(err, ErrUnavailable)
+}
@@ -0,0 +1,409 @@
+package nfe
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/invoice"
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/infrastructure/pdf"
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/infrastructure/qrcode"
+)
+
+// DANFE layout, in points. Every page repeats the issuer header and the item
+// table header; the customer and totals blocks only appear on the first one.
+const (
+	margin       = 20.0
+	contentWidth = pdf.PageWidth - 2*margin
+	headerHeight = 118.0
+	blockHeight  = 46.0
+	rowHeight    = 14.0
+	footerHeight = 24.0
+	pixHeight    = 120.0
+	labelSize    = 6.0
+	valueSize    = 8.5
+)
+
+type column struct {
+	title string
+	width float64
+	right bool
+}
+
+var itemColumns = []column{
+	{title: "CÓDIGO", width: 50},
+	{title: "DESCRIÇÃO DO PRODUTO", width: 205},
+	{title: "NCM", width: 50},
+	{title: "CFOP", width: 32},
+	{title: "UN", width: 26},
+	{title: "QUANT.", width: 55, right: true},
+	{title: "VALOR UNIT.", width: 68, right: true},
+	{title: "VALOR TOTAL", width: contentWidth - 486, right: true},
+}
+
+// RenderDANFE renders the invoice as a DANFE-style PDF. It is built from the
+// same document Export produces, so both always carry the same access key,
+// numbers and totals. A non-empty pixPayload is printed on the first page as
+// a QR code the customer can pay with.
+func (e *Exporter) RenderDANFE(inv *invoice.Invoice, payments []*invoice.Payment, pixPayload string) ([]byte, error) {
+	doc, err := e.Build(inv, payments)
+	if err != nil {
+		return nil, err
+	}
+	out, err := renderDANFE(doc, pixPayload)
+	if err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+func renderDANFE(doc *NFe, pixPayload string) (*pdf.Document, error) {
+	inf := doc.InfNFe
+	out := pdf.New("DANFE " + inf.Ide.Serie + "-" + inf.Ide.NNF)
+
+	var pixCode *qrcode.Code
+	firstTop := margin + headerHeight + 3*blockHeight
+	if pixPayload != "" {
+		code, err := qrcode.Encode([]byte(pixPayload), qrcode.Medium)
+		if err != nil {
+			return nil, err
+		}
+		pixCode = code
+		firstTop += pixHeight
+	}
+	firstRows := rowsFitting(firstTop)
+	otherRows := rowsFitting(margin + headerHeight)
+
+	pages := 1
+	if remaining := len(inf.Det) - firstRows; remaining > 0 {
+		pages += (remaining + otherRows - 1) / otherRows
+	}
+
+	items := inf.Det
+	for n := 1; n <= pages; n++ {
+		page := out.AddPage()
+		drawHeader(page, &inf, n, pages)
+
+		top := margin + headerHeight
+		rows := otherRows
+		if n == 1 {
+			drawNature(page, top, &inf)
+			drawCustomer(page, top+blockHeight, &inf)
+			drawTotals(page, top+2*blockHeight, &inf.Total.ICMSTot)
+			if pixCode != nil {
+				drawPix(page, top+3*blockHeight, pixCode, pixPayload)
+			}
+			top = firstTop
+			rows = firstRows
+		}
+
+		if rows > len(items) {
+			rows = len(items)
+		}
+		drawItems(page, top, items[:rows])
+		items = items[rows:]
+
+		drawFooter(page, &inf)
+	}
+	return out, nil
+}
+
+func rowsFitting(top float64) int {
+	available := pdf.PageHeight - margin - footerHeight - top - 2*rowHeight
+	return int(available / rowHeight)
+}
+
+func drawHeader(page *pdf.Page, inf *InfNFe, n, pages int) {
+	y := margin
+	issuerWidth := 250.0
+	danfeWidth := 110.0
+	keyX := margin + issuerWidth + danfeWidth
+	keyWidth := contentWidth - issuerWidth - danfeWidth
+
+	page.Rect(margin, y, issuerWidth, headerHeight, 0.8)
+	page.Rect(margin+issuerWidth, y, danfeWidth, headerHeight, 0.8)
+	page.Rect(keyX, y, keyWidth, headerHeight, 0.8)
+
+	// Issuer
+	emit := inf.Emit
+	addr := emit.EnderEmit
+	page.Text(margin+6, y+12, pdf.Helvetica, labelSize, "IDENTIFICAÇÃO DO EMITENTE")
+	page.Text(margin+6, y+32, pdf.HelveticaBold, 11, pdf.HelveticaBold.Fit(emit.XNome, 11, issuerWidth-12))
+	line := y + 48
+	if emit.XFant != "" {
+		page.Text(margin+6, line, pdf.Helvetica, valueSize, pdf.Helvetica.Fit(emit.XFant, valueSize, issuerWidth-12))
+		line += 12
+	}
+	page.Text(margin+6, line, pdf.Helvetica, valueSize, pdf.Helvetica.Fit(joinNonEmpty(", ", addr.XLgr, addr.Nro), valueSize, issuerWidth-12))
+	page.Text(margin+6, line+12, pdf.Helvetica, valueSize, pdf.Helvetica.Fit(joinNonEmpty(" - ", addr.XBairro, formatCEP(addr.CEP)), valueSize, issuerWidth-12))
+	page.Text(margin+6, line+24, pdf.Helvetica, valueSize, joinNonEmpty(" - ", addr.XMun, addr.UF))
+
+	// DANFE identification
+	cx := margin + issuerWidth + danfeWidth/2
+	page.TextCenter(cx, y+20, pdf.HelveticaBold, 14, "DANFE")
+	page.TextCenter(cx, y+32, pdf.Helvetica, labelSize, "Documento Auxiliar da")
+	page.TextCenter(cx, y+40, pdf.Helvetica, labelSize, "Nota Fiscal Eletrônica")
+	page.Text(margin+issuerWidth+10, y+56, pdf.Helvetica, labelSize, "0 - ENTRADA")
+	page.Text(margin+issuerWidth+10, y+64, pdf.Helvetica, labelSize, "1 - SAÍDA")
+	page.Rect(margin+issuerWidth+72, y+51, 16, 16, 0.8)
+	page.TextCenter(margin+issuerWidth+80, y+63, pdf.HelveticaBold, 10, inf.Ide.TpNF)
+	page.TextCenter(cx, y+84, pdf.HelveticaBold, valueSize, "Nº "+formatNumber(inf.Ide.NNF))
+	page.TextCenter(cx, y+96, pdf.HelveticaBold, valueSize, "SÉRIE "+inf.Ide.Serie)
+	page.TextCenter(cx, y+108, pdf.Helvetica, valueSize, fmt.Sprintf("FOLHA %d/%d", n, pages))
+
+	// Access key
+	key := strings.TrimPrefix(inf.ID, "NFe")
+	page.Text(keyX+6, y+12, pdf.Helvetica, labelSize, "CHAVE DE ACESSO")
+	page.Text(keyX+6, y+26, pdf.HelveticaBold, 7.5, formatKey(key))
+	page.Text(keyX+6, y+46, pdf.Helvetica, labelSize, "CNPJ")
+	page.Text(keyX+6, y+58, pdf.HelveticaBold, valueSize, formatCNPJ(emit.CNPJ))
+	page.Text(keyX+keyWidth/2, y+46, pdf.Helvetica, labelSize, "INSCRIÇÃO ESTADUAL")
+	page.Text(keyX+keyWidth/2, y+58, pdf.HelveticaBold, valueSize, emit.IE)
+	page.Text(keyX+6, y+78, pdf.Helvetica, labelSize, "Consulta de autenticidade no portal nacional da NF-e")
+	page.Text(keyX+6, y+86, pdf.Helvetica, labelSize, "www.nfe.fazenda.gov.br/portal")
+	if inf.Ide.TpAmb != "1" {
+		page.Text(keyX+6, y+106, pdf.HelveticaBold, valueSize, "SEM VALOR FISCAL - HOMOLOGAÇÃO")
+	}
+}
+
+func drawNature(page *pdf.Page, y float64, inf *InfNFe) {
+	page.Rect(margin, y+6, contentWidth, blockHeight-12, 0.8)
+	field(page, margin, y+6, contentWidth*0.7, "NATUREZA DA OPERAÇÃO", inf.Ide.NatOp)
+	field(page, margin+contentWidth*0.7, y+6, contentWidth*0.3, "DATA DE EMISSÃO", formatIssueDate(inf.Ide.DhEmi))
+}
+
+func drawCustomer(page *pdf.Page, y float64, inf *InfNFe) {
+	page.Text(margin, y+4, pdf.HelveticaBold, labelSize, "DESTINATÁRIO / REMETENTE")
+	page.Rect(margin, y+6, contentWidth, blockHeight-12, 0.8)
+
+	name, document := "CONSUMIDOR NÃO IDENTIFICADO", ""
+	if dest := inf.Dest; dest != nil {
+		name = dest.XNome
+		switch {
+		case dest.CNPJ != "":
+			document = formatCNPJ(dest.CNPJ)
+		case dest.CPF != "":
+			document = formatCPF(dest.CPF)
+		}
+	}
+
+	field(page, margin, y+6, contentWidth*0.7, "NOME / RAZÃO SOCIAL", name)
+	field(page, margin+contentWidth*0.7, y+6, contentWidth*0.3, "CNPJ / CPF", document)
+}
+
+func drawTotals(page *pdf.Page, y float64, tot *ICMSTot) {
+	page.Text(margin, y+4, pdf.HelveticaBold, labelSize, "CÁLCULO DO IMPOSTO")
+	page.Rect(margin, y+6, contentWidth, blockHeight-12, 0.8)
+
+	values := []struct{ label, value string }{
+		{"BASE DE CÁLCULO DO ICMS", tot.VBC},
+		{"VALOR DO ICMS", tot.VICMS},
+		{"VALOR TOTAL DOS PRODUTOS", tot.VProd},
+		{"VALOR DO IPI", tot.VIPI},
+		{"VALOR DO DESCONTO", tot.VDesc},
+		{"VALOR TOTAL DA NOTA", tot.VNF},
+	}
+	width := contentWidth / float64(len(values))
+	for i, v := range values {
+		x := margin + float64(i)*width
+		if i > 0 {
+			page.Line(x, y+6, x, y+blockHeight-6, 0.5)
+		}
+		page.Text(x+4, y+15, pdf.Helvetica, labelSize, v.label)
+		font := pdf.Helvetica
+		if i == len(values)-1 {
+			font = pdf.HelveticaBold
+		}
+		page.TextRight(x+width-4, y+32, font, valueSize, formatDecimal(v.value))
+	}
+}
+
+func drawPix(page *pdf.Page, y float64, code *qrcode.Code, payload string) {
+	page.Text(margin, y+4, pdf.HelveticaBold, labelSize, "PAGAMENTO VIA PIX")
+	page.Rect(margin, y+6, contentWidth, pixHeight-12, 0.8)
+
+	// The white quiet zone around the symbol is part of the square
+	side := pixHeight - 16
+	module := side / float64(code.Size+2*qrcode.QuietZone)
+	qrX := margin + 4 + module*qrcode.QuietZone
+	qrY := y + 8 + module*qrcode.QuietZone
+	for row := 0; row < code.Size; row++ {
+		for col := 0; col < code.Size; {
+			if !code.Dark(col, row) {
+				col++
+				continue
+			}
+			run := col
+			for run < code.Size && code.Dark(run, row) {
+				run++
+			}
+			page.FillRect(qrX+float64(col)*module, qrY+float64(row)*module, float64(run-col)*module, module, 0)
+			col = run
+		}
+	}
+
+	x := margin + side + 12
+	width := margin + contentWidth - 8 - x
+	page.Text(x, y+20, pdf.Helvetica, valueSize,
+		pdf.Helvetica.Fit("Pague com o aplicativo do seu banco lendo o QR Code ou usando o PIX copia e cola:", valueSize, width))
+	line := y + 34
+	for _, text := range wrap(payload, pdf.Helvetica, 7, width) {
+		page.Text(x, line, pdf.Helvetica, 7, text)
+		line += 9
+	}
+}
+
+// wrap breaks s into lines at most width wide, splitting anywhere: the PIX
+// code has no spaces to break on.
+func wrap(s string, font pdf.Font, size, width float64) []string {
+	var lines []string
+	start := 0
+	for i := 1; i <= len(s); i++ {
+		if font.Width(s[start:i], size) > width {
+			lines = append(lines, s[start:i-1])
+			start = i - 1
+		}
+	}
+	if start < len(s) {
+		lines = append(lines, s[start:])
+	}
+	return lines
+}
+
+func drawItems(page *pdf.Page, y float64, items []Det) {
+	page.Text(margin, y+4, pdf.HelveticaBold, labelSize, "DADOS DOS PRODUTOS / SERVIÇOS")
+	y += 6
+
+	page.FillRect(margin, y, contentWidth, rowHeight, 0.9)
+	page.Rect(margin, y, contentWidth, rowHeight*float64(len(items)+1), 0.8)
+
+	x := margin
+	for i, col := range itemColumns {
+		if i > 0 {
+			page.Line(x, y, x, y+rowHeight*float64(len(items)+1), 0.5)
+		}
+		drawCell(page, x, y, col, pdf.HelveticaBold, labelSize, col.title)
+		x += col.width
+	}
+
+	for _, det := range items {
+		y += rowHeight
+		page.Line(margin, y, margin+contentWidth, y, 0.3)
+
+		p := det.Prod
+		values := []string{
+			p.CProd, p.XProd, p.NCM, p.CFOP, p.UCom,
+			formatDecimal(p.QCom), formatDecimal(p.VUnCom), formatDecimal(p.VProd),
+		}
+		x := margin
+		for i, col := range itemColumns {
+			drawCell(page, x, y, col, pdf.Helvetica, 7.5, values[i])
+			x += col.width
+		}
+	}
+}
+
+func drawCell(page *pdf.Page, x, y float64, col column, font pdf.Font, size float64, text string) {
+	text = font.Fit(text, size, col.width-6)
+	baseline := y + rowHeight - 4
+	if col.right {
+		page.TextRight(x+col.width-3, baseline, font, size, text)
+		return
+	}
+	page.Text(x+3, baseline, font, size, text)
+}
+
+func drawFooter(page *pdf.Page, inf *InfNFe) {
+	y := pdf.PageHeight - margin - footerHeight
+	page.Line(margin, y, margin+contentWidth, y, 0.5)
+	page.Text(margin, y+12, pdf.Helvetica, labelSize, "Documento gerado a partir do XML da NF-e não assinado. "+inf.Ide.VerProc)
+}
+
+func field(page *pdf.Page, x, y, width float64, label, value string) {
+	page.Text(x+4, y+9, pdf.Helvetica, labelSize, label)
+	page.Text(x+4, y+25, pdf.Helvetica, valueSize, pdf.Helvetica.Fit(value, valueSize, width-8))
+}
+
+func formatIssueDate(dhEmi string) string {
+	t, err := time.Parse("2006-01-02T15:04:05-07:00", dhEmi)
+	if err != nil {
+		return dhEmi
+	}
+	return t.Format("02/01/2006 15:04:05")
+}
+
+// formatDecimal turns "2800.00" into "2.800,00".
+func formatDecimal(s string) string {
+	if s == "" {
+		s = "0.00"
+	}
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	intPart, fracPart, hasFrac := strings.Cut(s, ".")
+
+	var b strings.Builder
+	for i, c := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteByte('.')
+		}
+		b.WriteRune(c)
+	}
+	if hasFrac {
+		b.WriteByte(',')
+		b.WriteString(fracPart)
+	}
+	return sign + b.String()
+}
+
+// formatNumber pads nNF to nine digits grouped as 000.000.000.
+func formatNumber(nNF string) string {
+	n, err := strconv.Atoi(nNF)
+	if err != nil {
+		return nNF
+	}
+	s := fmt.Sprintf("%09d", n)
+	return s[0:3] + "." + s[3:6] + "." + s[6:9]
+}
+
+func formatKey(key string) string {
+	var groups []string
+	for len(key) > 4 {
+		groups = append(groups, key[:4])
+		key = key[4:]
+	}
+	return strings.Join(append(groups, key), " ")
+}
+
+func formatCNPJ(cnpj string) string {
+	if len(cnpj) != 14 {
+		return cnpj
+	}
+	return cnpj[0:2] + "." + cnpj[2:5] + "." + cnpj[5:8] + "/" + cnpj[8:12] + "-" + cnpj[12:14]
+}
+
+func formatCPF(cpf string) string {
+	if len(cpf) != 11 {
+		return cpf
+	}
+	return cpf[0:3] + "." + cpf[3:6] + "." + cpf[6:9] + "-" + cpf[9:11]
+}
+
+func formatCEP(cep string) string {
+	if len(cep) != 8 {
+		return cep
+	}
+	return "CEP " + cep[0:5] + "-" + cep[5:8]
+}
+
+func joinNonEmpty(sep string, parts ...string) string {
+	kept := parts[:0:0]
+	for _, p := range parts {
+		if p != "" {
+			kept = append(kept, p)
+		}
+	}
+	return strings.Join(kept, sep)
+}
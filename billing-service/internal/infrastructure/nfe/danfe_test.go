@@ -0,0 +1,78 @@
+package nfe
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/invoice"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func golden(t *testing.T, name string, got []byte) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("%s differs from the golden file; run go test -update if the change is intended", name)
+	}
+}
+
+const testPixPayload = "00020126580014br.gov.bcb.pix0136123e4567-e12b-12d1-a456-4266554400005204000053039865802BR5913Fulano de Tal6008BRASILIA62070503***63041D3D"
+
+func TestDANFEMatchesGolden(t *testing.T) {
+	manyItems := func() *invoice.Invoice {
+		inv := testInvoice()
+		item := inv.Items[1]
+		inv.Items = nil
+		for i := 0; i < 60; i++ {
+			line := *item
+			line.ProductID = 100 + i
+			line.Name = fmt.Sprintf("Cabo USB tipo C %02d", i+1)
+			inv.Items = append(inv.Items, &line)
+		}
+		return inv
+	}
+
+	tests := []struct {
+		name     string
+		invoice  func() *invoice.Invoice
+		payments []*invoice.Payment
+		pix      string
+	}{
+		{"danfe", testInvoice, testPayments(), ""},
+		{"danfe_pix", testInvoice, nil, testPixPayload},
+		{"danfe_pages", manyItems, nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := NewExporter(testIssuer("3")).RenderDANFE(tt.invoice(), tt.payments, tt.pix)
+			if err != nil {
+				t.Fatal(err)
+			}
+			golden(t, tt.name, out)
+		})
+	}
+}
+
+func TestExportMatchesGolden(t *testing.T) {
+	doc, err := NewExporter(testIssuer("3")).Export(testInvoice(), testPayments())
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden(t, "nfe.xml", doc)
+}
@@ -0,0 +1,233 @@
+package nfe
+
+import "encoding/xml"
+
+// The types below mirror the subset of the NF-e 4.00 layout (leiauteNFe_v4.00)
+// emitted by the exporter. Element order matters to the XSD and follows it.
+
+const Namespace = "http://www.portalfiscal.inf.br/nfe"
+
+type NFe struct {
+	XMLName xml.Name `xml:"NFe"`
+	Xmlns   string   `xml:"xmlns,attr"`
+	InfNFe  InfNFe   `xml:"infNFe"`
+}
+
+type InfNFe struct {
+	Versao string `xml:"versao,attr"`
+	ID     string `xml:"Id,attr"`
+	Ide    Ide    `xml:"ide"`
+	Emit   Emit   `xml:"emit"`
+	Dest   *Dest  `xml:"dest,omitempty"`
+	Det    []Det  `xml:"det"`
+	Total  Total  `xml:"total"`
+	Transp Transp `xml:"transp"`
+	Cobr   *Cobr  `xml:"cobr,omitempty"`
+	Pag    Pag    `xml:"pag"`
+}
+
+type Ide struct {
+	CUF      string `xml:"cUF"`
+	CNF      string `xml:"cNF"`
+	NatOp    string `xml:"natOp"`
+	Mod      string `xml:"mod"`
+	Serie    string `xml:"serie"`
+	NNF      string `xml:"nNF"`
+	DhEmi    string `xml:"dhEmi"`
+	TpNF     string `xml:"tpNF"`
+	IdDest   string `xml:"idDest"`
+	CMunFG   string `xml:"cMunFG"`
+	TpImp    string `xml:"tpImp"`
+	TpEmis   string `xml:"tpEmis"`
+	CDV      string `xml:"cDV"`
+	TpAmb    string `xml:"tpAmb"`
+	FinNFe   string `xml:"finNFe"`
+	IndFinal string `xml:"indFinal"`
+	IndPres  string `xml:"indPres"`
+	ProcEmi  string `xml:"procEmi"`
+	VerProc  string `xml:"verProc"`
+}
+
+type Address struct {
+	XLgr    string `xml:"xLgr"`
+	Nro     string `xml:"nro"`
+	XCpl    string `xml:"xCpl,omitempty"`
+	XBairro string `xml:"xBairro"`
+	CMun    string `xml:"cMun"`
+	XMun    string `xml:"xMun"`
+	UF      string `xml:"UF"`
+	CEP     string `xml:"CEP,omitempty"`
+	CPais   string `xml:"cPais,omitempty"`
+	XPais   string `xml:"xPais,omitempty"`
+}
+
+type Emit struct {
+	CNPJ      string  `xml:"CNPJ"`
+	XNome     string  `xml:"xNome"`
+	XFant     string  `xml:"xFant,omitempty"`
+	EnderEmit Address `xml:"enderEmit"`
+	IE        string  `xml:"IE"`
+	CRT       string  `xml:"CRT"`
+}
+
+type Dest struct {
+	CNPJ      string   `xml:"CNPJ,omitempty"`
+	CPF       string   `xml:"CPF,omitempty"`
+	XNome     string   `xml:"xNome,omitempty"`
+	EnderDest *Address `xml:"enderDest,omitempty"`
+	IndIEDest string   `xml:"indIEDest"`
+	IE        string   `xml:"IE,omitempty"`
+	Email     string   `xml:"email,omitempty"`
+}
+
+type Det struct {
+	NItem   int     `xml:"nItem,attr"`
+	Prod    Prod    `xml:"prod"`
+	Imposto Imposto `xml:"imposto"`
+}
+
+type Prod struct {
+	CProd    string `xml:"cProd"`
+	CEAN     string `xml:"cEAN"`
+	XProd    string `xml:"xProd"`
+	NCM      string `xml:"NCM"`
+	CFOP     string `xml:"CFOP"`
+	UCom     string `xml:"uCom"`
+	QCom     string `xml:"qCom"`
+	VUnCom   string `xml:"vUnCom"`
+	VProd    string `xml:"vProd"`
+	CEANTrib string `xml:"cEANTrib"`
+	UTrib    string `xml:"uTrib"`
+	QTrib    string `xml:"qTrib"`
+	VUnTrib  string `xml:"vUnTrib"`
+	VDesc    string `xml:"vDesc,omitempty"`
+	VOutro   string `xml:"vOutro,omitempty"`
+	IndTot   string `xml:"indTot"`
+}
+
+type Imposto struct {
+	ICMS   ICMS   `xml:"ICMS"`
+	IPI    *IPI   `xml:"IPI,omitempty"`
+	PIS    PIS    `xml:"PIS"`
+	COFINS COFINS `xml:"COFINS"`
+}
+
+type ICMS struct {
+	ICMS00    *ICMS00    `xml:"ICMS00,omitempty"`
+	ICMSSN102 *ICMSSN102 `xml:"ICMSSN102,omitempty"`
+}
+
+type ICMS00 struct {
+	Orig  string `xml:"orig"`
+	CST   string `xml:"CST"`
+	ModBC string `xml:"modBC"`
+	VBC   string `xml:"vBC"`
+	PICMS string `xml:"pICMS"`
+	VICMS string `xml:"vICMS"`
+}
+
+type ICMSSN102 struct {
+	Orig  string `xml:"orig"`
+	CSOSN string `xml:"CSOSN"`
+}
+
+type IPI struct {
+	CEnq    string   `xml:"cEnq"`
+	IPITrib *IPITrib `xml:"IPITrib,omitempty"`
+}
+
+type IPITrib struct {
+	CST  string `xml:"CST"`
+	VBC  string `xml:"vBC"`
+	PIPI string `xml:"pIPI"`
+	VIPI string `xml:"vIPI"`
+}
+
+type PIS struct {
+	PISAliq *Aliq `xml:"PISAliq,omitempty"`
+	PISNT   *NT   `xml:"PISNT,omitempty"`
+}
+
+type COFINS struct {
+	COFINSAliq *AliqCOFINS `xml:"COFINSAliq,omitempty"`
+	COFINSNT   *NT         `xml:"COFINSNT,omitempty"`
+}
+
+type Aliq struct {
+	CST  string `xml:"CST"`
+	VBC  string `xml:"vBC"`
+	PPIS string `xml:"pPIS"`
+	VPIS string `xml:"vPIS"`
+}
+
+type AliqCOFINS struct {
+	CST     string `xml:"CST"`
+	VBC     string `xml:"vBC"`
+	PCOFINS string `xml:"pCOFINS"`
+	VCOFINS string `xml:"vCOFINS"`
+}
+
+type NT struct {
+	CST string `xml:"CST"`
+}
+
+type Total struct {
+	ICMSTot ICMSTot `xml:"ICMSTot"`
+}
+
+type ICMSTot struct {
+	VBC        string `xml:"vBC"`
+	VICMS      string `xml:"vICMS"`
+	VICMSDeson string `xml:"vICMSDeson"`
+	VFCP       string `xml:"vFCP"`
+	VBCST      string `xml:"vBCST"`
+	VST        string `xml:"vST"`
+	VFCPST     string `xml:"vFCPST"`
+	VFCPSTRet  string `xml:"vFCPSTRet"`
+	VProd      string `xml:"vProd"`
+	VFrete     string `xml:"vFrete"`
+	VSeg       string `xml:"vSeg"`
+	VDesc      string `xml:"vDesc"`
+	VII        string `xml:"vII"`
+	VIPI       string `xml:"vIPI"`
+	VIPIDevol  string `xml:"vIPIDevol"`
+	VPIS       string `xml:"vPIS"`
+	VCOFINS    string `xml:"vCOFINS"`
+	VOutro     string `xml:"vOutro"`
+	VNF        string `xml:"vNF"`
+}
+
+type Transp struct {
+	ModFrete string `xml:"modFrete"`
+}
+
+// Cobr lists the installments (duplicatas) the invoice is paid in.
+type Cobr struct {
+	Fat Fat   `xml:"fat"`
+	Dup []Dup `xml:"dup"`
+}
+
+type Fat struct {
+	NFat  string `xml:"nFat"`
+	VOrig string `xml:"vOrig"`
+	VDesc string `xml:"vDesc"`
+	VLiq  string `xml:"vLiq"`
+}
+
+type Dup struct {
+	NDup  string `xml:"nDup"`
+	DVenc string `xml:"dVenc"`
+	VDup  string `xml:"vDup"`
+}
+
+type Pag struct {
+	DetPag []DetPag `xml:"detPag"`
+	VTroco string   `xml:"vTroco,omitempty"`
+}
+
+type DetPag struct {
+	IndPag string `xml:"indPag,omitempty"`
+	TPag   string `xml:"tPag"`
+	XPag   string `xml:"xPag,omitempty"`
+	VPag   string `xml:"vPag"`
+}
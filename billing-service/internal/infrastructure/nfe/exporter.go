@@ -0,0 +1,526 @@
+package nfe
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/invoice"
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/tax"
+	"github.com/vitorwhois/microservice-invoice-billing/money"
+)
+
+var (
+	ErrInvoiceNotIssued = errors.New("only closed invoices can be exported as NF-e")
+	ErrInvalidIssuer    = errors.New("invalid NF-e issuer configuration")
+	ErrNotInBRL         = errors.New("only invoices in BRL can be exported as NF-e")
+)
+
+const (
+	modelNFe        = "55"
+	layoutVersion   = "4.00"
+	emissionNormal  = "1"
+	crtSimples      = "1"
+	withoutGTIN     = "SEM GTIN"
+	freightNone     = "9"
+	paymentNone     = "90"
+	paymentOther    = "99"
+	paidUpFront     = "0"
+	paidOnCredit    = "1"
+	processVersion  = "billing-service 1.0"
+	quantityDecimal = 4
+)
+
+// Issuer holds the emitente data and the fiscal defaults applied to every
+// document.
+type Issuer struct {
+	CNPJ        string
+	Name        string
+	TradeName   string
+	IE          string
+	CRT         string
+	Street      string
+	Number      string
+	District    string
+	CityCode    string
+	City        string
+	UF          string
+	CEP         string
+	Series      int
+	Environment string
+	Nature      string
+	DefaultNCM  string
+	DefaultCFOP string
+	DefaultUnit string
+	ICMSRate    string
+	Location    *time.Location
+}
+
+// Exporter builds unsigned NF-e 4.00 documents. Signing and transmission to
+// SEFAZ happen downstream.
+type Exporter struct {
+	issuer Issuer
+}
+
+func NewExporter(issuer Issuer) *Exporter {
+	if issuer.Location == nil {
+		issuer.Location = time.Local
+	}
+	return &Exporter{issuer: issuer}
+}
+
+// Export renders the NF-e of an invoice. payments are those recorded so far;
+// whatever they leave unpaid is declared as paid on credit.
+func (e *Exporter) Export(inv *invoice.Invoice, payments []*invoice.Payment) ([]byte, error) {
+	doc, err := e.Build(inv, payments)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), out...), nil
+}
+
+func (e *Exporter) Build(inv *invoice.Invoice, payments []*invoice.Payment) (*NFe, error) {
+	if !inv.IsIssued() || inv.ClosedAt == nil {
+		return nil, ErrInvoiceNotIssued
+	}
+	if inv.Currency != "" && inv.Currency != money.DefaultCurrency {
+		return nil, ErrNotInBRL
+	}
+
+	cUF, ok := ufCodes[e.issuer.UF]
+	if !ok || len(onlyDigits(e.issuer.CNPJ)) != 14 {
+		return nil, ErrInvalidIssuer
+	}
+
+	issuedAt := inv.ClosedAt.In(e.issuer.Location)
+	nNF := documentNumber(inv)
+	cNF := randomCode(inv, nNF)
+	key := AccessKey(cUF, issuedAt, onlyDigits(e.issuer.CNPJ), modelNFe, e.issuer.Series, nNF, emissionNormal, cNF)
+
+	doc := &NFe{
+		Xmlns: Namespace,
+		InfNFe: InfNFe{
+			Versao: layoutVersion,
+			ID:     "NFe" + key,
+			Ide: Ide{
+				CUF:      cUF,
+				CNF:      fmt.Sprintf("%08d", cNF),
+				NatOp:    e.issuer.Nature,
+				Mod:      modelNFe,
+				Serie:    strconv.Itoa(e.issuer.Series),
+				NNF:      strconv.Itoa(nNF),
+				DhEmi:    issuedAt.Format("2006-01-02T15:04:05-07:00"),
+				TpNF:     "1",
+				IdDest:   "1",
+				CMunFG:   e.issuer.CityCode,
+				TpImp:    "1",
+				TpEmis:   emissionNormal,
+				CDV:      key[len(key)-1:],
+				TpAmb:    e.issuer.Environment,
+				FinNFe:   "1",
+				IndFinal: "1",
+				IndPres:  "1",
+				ProcEmi:  "0",
+				VerProc:  processVersion,
+			},
+			Emit: Emit{
+				CNPJ:  onlyDigits(e.issuer.CNPJ),
+				XNome: e.issuer.Name,
+				XFant: e.issuer.TradeName,
+				EnderEmit: Address{
+					XLgr:    e.issuer.Street,
+					Nro:     e.issuer.Number,
+					XBairro: e.issuer.District,
+					CMun:    e.issuer.CityCode,
+					XMun:    e.issuer.City,
+					UF:      e.issuer.UF,
+					CEP:     onlyDigits(e.issuer.CEP),
+					CPais:   "1058",
+					XPais:   "BRASIL",
+				},
+				IE:  onlyDigits(e.issuer.IE),
+				CRT: e.issuer.CRT,
+			},
+			Transp: Transp{ModFrete: freightNone},
+		},
+	}
+
+	if inv.Customer != nil {
+		doc.InfNFe.Dest = buildDest(inv.Customer)
+		if a := inv.Customer.Address; a != nil && a.UF != "" && a.UF != e.issuer.UF {
+			doc.InfNFe.Ide.IdDest = "2"
+		}
+	}
+
+	totals := newTotals()
+	for i, item := range inv.Items {
+		det := e.buildItem(i+1, item)
+		totals.add(det, item)
+		doc.InfNFe.Det = append(doc.InfNFe.Det, det)
+	}
+
+	doc.InfNFe.Total = Total{ICMSTot: totals.icmsTot()}
+	doc.InfNFe.Cobr = billing(inv)
+	doc.InfNFe.Pag = payment(totals.documentValue(), payments)
+
+	return doc, nil
+}
+
+// billing lists the installments of an invoice sold on credit.
+func billing(inv *invoice.Invoice) *Cobr {
+	if len(inv.Installments) == 0 {
+		return nil
+	}
+
+	cobr := &Cobr{Fat: Fat{
+		NFat:  inv.Number,
+		VOrig: inv.TotalValue.Decimal(),
+		VDesc: money.Zero(inv.TotalValue.Currency).Decimal(),
+		VLiq:  inv.TotalValue.Decimal(),
+	}}
+	for _, inst := range inv.Installments {
+		cobr.Dup = append(cobr.Dup, Dup{
+			NDup:  fmt.Sprintf("%03d", inst.Number),
+			DVenc: inst.DueDate.Format("2006-01-02"),
+			VDup:  inst.Amount.Decimal(),
+		})
+	}
+	return cobr
+}
+
+// paymentCodes maps payment methods to tPag.
+var paymentCodes = map[invoice.PaymentMethod]string{
+	invoice.PaymentCash:     "01",
+	invoice.PaymentCard:     "03",
+	invoice.PaymentBoleto:   "15",
+	invoice.PaymentPix:      "17",
+	invoice.PaymentTransfer: "18",
+}
+
+// payment declares what was received, one detPag per method, and whatever
+// is still owed as paid on credit. Money received beyond vNF is change
+// (vTroco).
+func payment(total money.Money, payments []*invoice.Payment) Pag {
+	var methods []invoice.PaymentMethod
+	received := make(map[invoice.PaymentMethod]money.Money)
+	paid := money.Zero(total.Currency)
+	for _, p := range payments {
+		paid = paid.Add(p.Amount)
+		if sum, ok := received[p.Method]; ok {
+			received[p.Method] = sum.Add(p.Amount)
+			continue
+		}
+		methods = append(methods, p.Method)
+		received[p.Method] = p.Amount
+	}
+
+	var pag Pag
+	for _, m := range methods {
+		det := DetPag{IndPag: paidUpFront, TPag: paymentOther, XPag: string(m), VPag: received[m].Decimal()}
+		if code, ok := paymentCodes[m]; ok {
+			det.TPag, det.XPag = code, ""
+		}
+		pag.DetPag = append(pag.DetPag, det)
+	}
+
+	if owed := total.Sub(paid); owed.IsPositive() {
+		pag.DetPag = append(pag.DetPag, DetPag{
+			IndPag: paidOnCredit,
+			TPag:   paymentOther,
+			XPag:   "Pagamento a prazo",
+			VPag:   owed.Decimal(),
+		})
+	} else if owed.IsNegative() {
+		pag.VTroco = owed.Neg().Decimal()
+	}
+	if len(pag.DetPag) == 0 {
+		// Nothing to pay
+		pag.DetPag = []DetPag{{TPag: paymentNone, VPag: total.Decimal()}}
+	}
+	return pag
+}
+
+func (e *Exporter) buildItem(n int, item *invoice.InvoiceItem) Det {
+	subtotal := item.Subtotal()
+	qty := formatQuantity(item.Quantity)
+
+	ncm := item.NCM
+	if ncm == "" {
+		ncm = e.issuer.DefaultNCM
+	}
+
+	det := Det{
+		NItem: n,
+		Prod: Prod{
+			CProd:    strconv.Itoa(item.ProductID),
+			CEAN:     withoutGTIN,
+			XProd:    item.Name,
+			NCM:      ncm,
+			CFOP:     e.issuer.DefaultCFOP,
+			UCom:     e.issuer.DefaultUnit,
+			QCom:     qty,
+			VUnCom:   item.Price.Decimal(),
+			VProd:    subtotal.Decimal(),
+			CEANTrib: withoutGTIN,
+			UTrib:    e.issuer.DefaultUnit,
+			QTrib:    qty,
+			VUnTrib:  item.Price.Decimal(),
+			IndTot:   "1",
+		},
+	}
+	if item.Discount.IsPositive() {
+		det.Prod.VDesc = item.Discount.Decimal()
+	}
+	if item.Surcharge.IsPositive() {
+		det.Prod.VOutro = item.Surcharge.Decimal()
+	}
+
+	if e.issuer.CRT == crtSimples {
+		det.Imposto.ICMS.ICMSSN102 = &ICMSSN102{Orig: "0", CSOSN: "102"}
+	} else if line, ok := item.Tax(tax.KindICMS); ok {
+		det.Imposto.ICMS.ICMS00 = &ICMS00{
+			Orig:  "0",
+			CST:   "00",
+			ModBC: "3",
+			VBC:   line.Base.Decimal(),
+			PICMS: line.Rate.String(),
+			VICMS: line.Amount.Decimal(),
+		}
+	} else {
+		// Invoices closed before the tax engine existed
+		base := item.Net()
+		det.Imposto.ICMS.ICMS00 = &ICMS00{
+			Orig:  "0",
+			CST:   "00",
+			ModBC: "3",
+			VBC:   base.Decimal(),
+			PICMS: e.issuer.ICMSRate,
+			VICMS: applyRate(base, e.issuer.ICMSRate).Decimal(),
+		}
+	}
+
+	if line, ok := item.Tax(tax.KindIPI); ok {
+		det.Imposto.IPI = &IPI{
+			CEnq: "999",
+			IPITrib: &IPITrib{
+				CST:  "50",
+				VBC:  line.Base.Decimal(),
+				PIPI: line.Rate.String(),
+				VIPI: line.Amount.Decimal(),
+			},
+		}
+	}
+
+	if line, ok := item.Tax(tax.KindPIS); ok {
+		det.Imposto.PIS.PISAliq = &Aliq{CST: "01", VBC: line.Base.Decimal(), PPIS: line.Rate.String(), VPIS: line.Amount.Decimal()}
+	} else {
+		det.Imposto.PIS.PISNT = &NT{CST: "07"}
+	}
+	if line, ok := item.Tax(tax.KindCOFINS); ok {
+		det.Imposto.COFINS.COFINSAliq = &AliqCOFINS{CST: "01", VBC: line.Base.Decimal(), PCOFINS: line.Rate.String(), VCOFINS: line.Amount.Decimal()}
+	} else {
+		det.Imposto.COFINS.COFINSNT = &NT{CST: "07"}
+	}
+
+	return det
+}
+
+func buildDest(c *invoice.CustomerSnapshot) *Dest {
+	dest := &Dest{
+		XNome:     c.Name,
+		IndIEDest: "9",
+		Email:     c.Email,
+	}
+
+	if c.DocumentType == "CNPJ" {
+		dest.CNPJ = c.Document
+	} else {
+		dest.CPF = c.Document
+	}
+
+	// 1: ICMS taxpayer with IE, 9: non-taxpayer
+	if ie := onlyDigits(c.StateRegistration); ie != "" {
+		dest.IndIEDest = "1"
+		dest.IE = ie
+	}
+
+	if a := c.Address; a != nil {
+		dest.EnderDest = &Address{
+			XLgr:    a.Street,
+			Nro:     a.Number,
+			XCpl:    a.Complement,
+			XBairro: a.District,
+			CMun:    a.CityCode,
+			XMun:    a.City,
+			UF:      a.UF,
+			CEP:     onlyDigits(a.CEP),
+			CPais:   "1058",
+			XPais:   "BRASIL",
+		}
+	}
+	return dest
+}
+
+type totals struct {
+	products  money.Money
+	discount  money.Money
+	surcharge money.Money
+	icmsBase  money.Money
+	icms      money.Money
+	ipi       money.Money
+	pis       money.Money
+	cofins    money.Money
+}
+
+func newTotals() *totals {
+	zero := money.Zero(money.DefaultCurrency)
+	return &totals{
+		products: zero, discount: zero, surcharge: zero,
+		icmsBase: zero, icms: zero, ipi: zero, pis: zero, cofins: zero,
+	}
+}
+
+func (t *totals) add(det Det, item *invoice.InvoiceItem) {
+	t.products = t.products.Add(item.Subtotal())
+	t.discount = t.discount.Add(item.Discount)
+	t.surcharge = t.surcharge.Add(item.Surcharge)
+	if icms := det.Imposto.ICMS.ICMS00; icms != nil {
+		t.icmsBase = t.icmsBase.Add(money.MustParse(icms.VBC, money.DefaultCurrency))
+		t.icms = t.icms.Add(money.MustParse(icms.VICMS, money.DefaultCurrency))
+	}
+	if line, ok := item.Tax(tax.KindIPI); ok {
+		t.ipi = t.ipi.Add(line.Amount)
+	}
+	if line, ok := item.Tax(tax.KindPIS); ok {
+		t.pis = t.pis.Add(line.Amount)
+	}
+	if line, ok := item.Tax(tax.KindCOFINS); ok {
+		t.cofins = t.cofins.Add(line.Amount)
+	}
+}
+
+func (t *totals) icmsTot() ICMSTot {
+	zero := money.Zero(money.DefaultCurrency).Decimal()
+	return ICMSTot{
+		VBC:        t.icmsBase.Decimal(),
+		VICMS:      t.icms.Decimal(),
+		VICMSDeson: zero,
+		VFCP:       zero,
+		VBCST:      zero,
+		VST:        zero,
+		VFCPST:     zero,
+		VFCPSTRet:  zero,
+		VProd:      t.products.Decimal(),
+		VFrete:     zero,
+		VSeg:       zero,
+		VDesc:      t.discount.Decimal(),
+		VII:        zero,
+		VIPI:       t.ipi.Decimal(),
+		VIPIDevol:  zero,
+		VPIS:       t.pis.Decimal(),
+		VCOFINS:    t.cofins.Decimal(),
+		VOutro:     t.surcharge.Decimal(),
+		VNF:        t.documentValue().Decimal(),
+	}
+}
+
+// documentValue is vNF: ICMS, PIS and COFINS are already inside the product
+// value.
+func (t *totals) documentValue() money.Money {
+	return t.products.Sub(t.discount).Add(t.surcharge).Add(t.ipi)
+}
+
+// AccessKey builds the 44-digit chave de acesso, including its modulo 11
+// check digit.
+func AccessKey(cUF string, issuedAt time.Time, cnpj, model string, series, number int, emission string, code int) string {
+	base := fmt.Sprintf("%s%s%s%s%03d%09d%s%08d",
+		cUF, issuedAt.Format("0601"), cnpj, model, series, number, emission, code)
+	return base + strconv.Itoa(mod11(base))
+}
+
+func mod11(digits string) int {
+	sum, weight := 0, 2
+	for i := len(digits) - 1; i >= 0; i-- {
+		sum += int(digits[i]-'0') * weight
+		weight++
+		if weight > 9 {
+			weight = 2
+		}
+	}
+	dv := 11 - sum%11
+	if dv >= 10 {
+		return 0
+	}
+	return dv
+}
+
+// documentNumber uses the never-restarting document counter of the invoice
+// series, or the digits of the number for invoices created before series
+// existed (nNF is numeric, 1 to 9 digits), falling back to the invoice ID.
+func documentNumber(inv *invoice.Invoice) int {
+	if inv.DocumentNumber > 0 {
+		return inv.DocumentNumber % 1000000000
+	}
+
+	digits := onlyDigits(inv.Number)
+	if len(digits) > 9 {
+		digits = digits[len(digits)-9:]
+	}
+	n, err := strconv.Atoi(digits)
+	if err != nil || n == 0 {
+		return inv.ID % 1000000000
+	}
+	return n
+}
+
+// randomCode derives cNF from the invoice so re-exporting the same invoice
+// yields the same access key. SEFAZ rejects cNF equal to nNF.
+func randomCode(inv *invoice.Invoice, nNF int) int {
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%d:%s", inv.ID, inv.Number)
+	code := int(h.Sum32() % 100000000)
+	if code == nNF {
+		code = (code + 1) % 100000000
+	}
+	return code
+}
+
+func formatQuantity(q int) string {
+	return strconv.Itoa(q) + "." + strings.Repeat("0", quantityDecimal)
+}
+
+// applyRate computes base * rate%, rounding half away from zero.
+func applyRate(base money.Money, rate string) money.Money {
+	r, err := money.Parse(rate, base.Currency)
+	if err != nil {
+		return money.Zero(base.Currency)
+	}
+	return base.MulRatio(r.Amount, 10000)
+}
+
+func onlyDigits(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+var ufCodes = map[string]string{
+	"RO": "11", "AC": "12", "AM": "13", "RR": "14", "PA": "15", "AP": "16", "TO": "17",
+	"MA": "21", "PI": "22", "CE": "23", "RN": "24", "PB": "25", "PE": "26", "AL": "27", "SE": "28", "BA": "29",
+	"MG": "31", "ES": "32", "RJ": "33", "SP": "35",
+	"PR": "41", "SC": "42", "RS": "43",
+	"MS": "50", "MT": "51", "GO": "52", "DF": "53",
+}
@@ -0,0 +1,224 @@
+package nfe
+
+import (
+	"bytes"
+	"encoding/xml"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/invoice"
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/tax"
+	"github.com/vitorwhois/microservice-invoice-billing/money"
+)
+
+var brt = time.FixedZone("BRT", -3*60*60)
+
+func testIssuer(crt string) Issuer {
+	return Issuer{
+		CNPJ:        "12.345.678/0001-95",
+		Name:        "Loja Exemplo Ltda",
+		TradeName:   "Loja Exemplo",
+		IE:          "123.456.789.110",
+		CRT:         crt,
+		Street:      "Avenida Paulista",
+		Number:      "1000",
+		District:    "Bela Vista",
+		CityCode:    "3550308",
+		City:        "Sao Paulo",
+		UF:          "SP",
+		CEP:         "01310-100",
+		Series:      1,
+		Environment: "2",
+		Nature:      "Venda de mercadoria",
+		DefaultNCM:  "84713012",
+		DefaultCFOP: "5102",
+		DefaultUnit: "UN",
+		ICMSRate:    "18.00",
+		Location:    brt,
+	}
+}
+
+func brl(s string) money.Money {
+	return money.MustParse(s, money.DefaultCurrency)
+}
+
+// testInvoice is a closed invoice with two items, one taxed, sold on credit
+// in two installments to a taxpayer in another state.
+func testInvoice() *invoice.Invoice {
+	closedAt := time.Date(2026, 3, 14, 15, 9, 26, 0, brt)
+	icms, _ := tax.ParseRate("12.00")
+	ipi, _ := tax.ParseRate("5.00")
+
+	return &invoice.Invoice{
+		ID:             42,
+		Number:         "INV-2026-000042",
+		DocumentNumber: 42,
+		Status:         invoice.StatusIssued,
+		Currency:       money.DefaultCurrency,
+		ClosedAt:       &closedAt,
+		Items: []*invoice.InvoiceItem{
+			{
+				ProductID: 7,
+				Name:      "Notebook 14 polegadas",
+				NCM:       "84713012",
+				Quantity:  2,
+				Price:     brl("3500.00"),
+				Discount:  brl("100.00"),
+				Surcharge: brl("0.00"),
+				Taxes: []tax.Line{
+					{Kind: tax.KindICMS, Base: brl("6900.00"), Rate: icms, Amount: brl("828.00"), Included: true},
+					{Kind: tax.KindIPI, Base: brl("6900.00"), Rate: ipi, Amount: brl("345.00")},
+				},
+			},
+			{
+				ProductID: 9,
+				Name:      "Mouse sem fio",
+				Quantity:  3,
+				Price:     brl("49.90"),
+				Discount:  brl("0.00"),
+				Surcharge: brl("5.00"),
+			},
+		},
+		TotalValue: brl("7399.70"),
+		Customer: &invoice.CustomerSnapshot{
+			CustomerID:        3,
+			Name:              "Comercial Rio Ltda",
+			Document:          "98765432000110",
+			DocumentType:      "CNPJ",
+			Email:             "compras@comercialrio.com.br",
+			StateRegistration: "12.345.678",
+			Address: &invoice.CustomerAddress{
+				Street:   "Rua do Ouvidor",
+				Number:   "50",
+				District: "Centro",
+				CityCode: "3304557",
+				City:     "Rio de Janeiro",
+				UF:       "RJ",
+				CEP:      "20040-030",
+			},
+		},
+		Installments: []*invoice.Installment{
+			{Number: 1, DueDate: time.Date(2026, 4, 13, 0, 0, 0, 0, time.UTC), Amount: brl("3699.85")},
+			{Number: 2, DueDate: time.Date(2026, 5, 13, 0, 0, 0, 0, time.UTC), Amount: brl("3699.85")},
+		},
+	}
+}
+
+func testPayments() []*invoice.Payment {
+	return []*invoice.Payment{
+		{Method: invoice.PaymentPix, Amount: brl("1000.00")},
+		{Method: invoice.PaymentCash, Amount: brl("200.00")},
+		{Method: invoice.PaymentPix, Amount: brl("500.00")},
+	}
+}
+
+// validate checks doc against the schema subset in testdata with xmllint.
+func validate(t *testing.T, doc []byte) {
+	t.Helper()
+
+	xmllint, err := exec.LookPath("xmllint")
+	if err != nil {
+		t.Skip("xmllint not installed")
+	}
+
+	path := filepath.Join(t.TempDir(), "nfe.xml")
+	if err := os.WriteFile(path, doc, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	out, err := exec.Command(xmllint, "--noout", "--schema", filepath.Join("testdata", "nfe_v4.00_subset.xsd"), path).CombinedOutput()
+	if err != nil {
+		t.Fatalf("schema validation failed: %v\n%s\n%s", err, out, doc)
+	}
+}
+
+func TestExportValidatesAgainstSchema(t *testing.T) {
+	tests := []struct {
+		name     string
+		crt      string
+		invoice  func() *invoice.Invoice
+		payments []*invoice.Payment
+	}{
+		{"simples nacional", crtSimples, testInvoice, nil},
+		{"regime normal", "3", testInvoice, testPayments()},
+		{"paid in cash", "3", func() *invoice.Invoice {
+			inv := testInvoice()
+			inv.Installments = nil
+			inv.Customer = nil
+			return inv
+		}, []*invoice.Payment{{Method: invoice.PaymentCash, Amount: brl("8000.00")}}},
+		{"individual buyer", crtSimples, func() *invoice.Invoice {
+			inv := testInvoice()
+			inv.Customer.Document = "12345678909"
+			inv.Customer.DocumentType = "CPF"
+			inv.Customer.StateRegistration = ""
+			return inv
+		}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := NewExporter(testIssuer(tt.crt)).Export(tt.invoice(), tt.payments)
+			if err != nil {
+				t.Fatal(err)
+			}
+			validate(t, doc)
+		})
+	}
+}
+
+func TestExportRejectsOpenInvoices(t *testing.T) {
+	inv := testInvoice()
+	inv.Status = invoice.StatusOpen
+	if _, err := NewExporter(testIssuer(crtSimples)).Export(inv, nil); err != ErrInvoiceNotIssued {
+		t.Fatalf("got %v, want ErrInvoiceNotIssued", err)
+	}
+}
+
+func TestPaymentDeclaresWhatWasReceived(t *testing.T) {
+	tests := []struct {
+		name     string
+		total    string
+		payments []*invoice.Payment
+		want     Pag
+	}{
+		{
+			name:  "unpaid",
+			total: "100.00",
+			want:  Pag{DetPag: []DetPag{{IndPag: paidOnCredit, TPag: paymentOther, XPag: "Pagamento a prazo", VPag: "100.00"}}},
+		},
+		{
+			name:     "partly paid, merged per method",
+			total:    "7744.70",
+			payments: testPayments(),
+			want: Pag{DetPag: []DetPag{
+				{IndPag: paidUpFront, TPag: "17", VPag: "1500.00"},
+				{IndPag: paidUpFront, TPag: "01", VPag: "200.00"},
+				{IndPag: paidOnCredit, TPag: paymentOther, XPag: "Pagamento a prazo", VPag: "6044.70"},
+			}},
+		},
+		{
+			name:     "overpaid",
+			total:    "95.00",
+			payments: []*invoice.Payment{{Method: invoice.PaymentCash, Amount: brl("100.00")}},
+			want:     Pag{DetPag: []DetPag{{IndPag: paidUpFront, TPag: "01", VPag: "100.00"}}, VTroco: "5.00"},
+		},
+		{
+			name:  "nothing to pay",
+			total: "0.00",
+			want:  Pag{DetPag: []DetPag{{TPag: paymentNone, VPag: "0.00"}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := xml.Marshal(payment(brl(tt.total), tt.payments))
+			want, _ := xml.Marshal(tt.want)
+			if !bytes.Equal(got, want) {
+				t.Fatalf("got  %s\nwant %s", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// A4 page size in points.
+const (
+	PageWidth  = 595.28
+	PageHeight = 841.89
+)
+
+// Document is a minimal PDF 1.4 writer: A4 pages, the two standard Helvetica
+// faces and stroked or filled rectangles. Nothing is compressed and no
+// timestamps are written, so the same input always yields the same bytes.
+type Document struct {
+	title string
+	pages []*Page
+}
+
+// Page coordinates have their origin at the top-left corner, y growing
+// downwards, and are converted to PDF user space when drawn.
+type Page struct {
+	content bytes.Buffer
+}
+
+func New(title string) *Document {
+	return &Document{title: title}
+}
+
+func (d *Document) AddPage() *Page {
+	p := &Page{}
+	d.pages = append(d.pages, p)
+	return p
+}
+
+func (d *Document) PageCount() int {
+	return len(d.pages)
+}
+
+func (p *Page) Text(x, y float64, font Font, size float64, s string) {
+	fmt.Fprintf(&p.content, "BT /%s %s Tf %s %s Td (%s) Tj ET\n",
+		font.resource(), num(size), num(x), num(PageHeight-y), escape(encode(s)))
+}
+
+// TextRight draws s so that it ends at x.
+func (p *Page) TextRight(x, y float64, font Font, size float64, s string) {
+	p.Text(x-font.Width(s, size), y, font, size, s)
+}
+
+// TextCenter draws s centred on x.
+func (p *Page) TextCenter(x, y float64, font Font, size float64, s string) {
+	p.Text(x-font.Width(s, size)/2, y, font, size, s)
+}
+
+func (p *Page) Line(x1, y1, x2, y2, width float64) {
+	fmt.Fprintf(&p.content, "%s w %s %s m %s %s l S\n",
+		num(width), num(x1), num(PageHeight-y1), num(x2), num(PageHeight-y2))
+}
+
+func (p *Page) Rect(x, y, w, h, width float64) {
+	fmt.Fprintf(&p.content, "%s w %s %s %s %s re S\n",
+		num(width), num(x), num(PageHeight-y-h), num(w), num(h))
+}
+
+// FillRect paints a rectangle in the given gray level (0 black, 1 white).
+func (p *Page) FillRect(x, y, w, h, gray float64) {
+	fmt.Fprintf(&p.content, "q %s g %s %s %s %s re f Q\n",
+		num(gray), num(x), num(PageHeight-y-h), num(w), num(h))
+}
+
+func (d *Document) Bytes() []byte {
+	var buf bytes.Buffer
+	d.WriteTo(&buf)
+	return buf.Bytes()
+}
+
+// WriteTo serialises the document. Object layout: 1 catalog, 2 page tree,
+// 3 and 4 fonts, 5 info, then a page object and its content stream for each
+// page.
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	var offsets []int
+
+	begin := func() int {
+		offsets = append(offsets, buf.Len())
+		n := len(offsets)
+		fmt.Fprintf(&buf, "%d 0 obj\n", n)
+		return n
+	}
+	end := func() {
+		buf.WriteString("endobj\n")
+	}
+
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	const firstPage = 6
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+2*i)
+	}
+
+	begin()
+	buf.WriteString("<< /Type /Catalog /Pages 2 0 R >>\n")
+	end()
+
+	begin()
+	fmt.Fprintf(&buf, "<< /Type /Pages /Kids [%s] /Count %d >>\n", strings.Join(kids, " "), len(d.pages))
+	end()
+
+	for _, font := range []Font{Helvetica, HelveticaBold} {
+		begin()
+		fmt.Fprintf(&buf, "<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>\n", font.name())
+		end()
+	}
+
+	begin()
+	fmt.Fprintf(&buf, "<< /Title (%s) /Producer (billing-service) >>\n", escape(encode(d.title)))
+	end()
+
+	for _, p := range d.pages {
+		n := begin()
+		fmt.Fprintf(&buf, "<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] ", num(PageWidth), num(PageHeight))
+		fmt.Fprintf(&buf, "/Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>\n", n+1)
+		end()
+
+		begin()
+		fmt.Fprintf(&buf, "<< /Length %d >>\nstream\n", p.content.Len())
+		buf.Write(p.content.Bytes())
+		buf.WriteString("endstream\n")
+		end()
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	n, err := w.Write(buf.Bytes())
+	return int64(n), err
+}
+
+// encode converts s to WinAnsiEncoding. Latin-1 characters map one to one,
+// which covers Portuguese; anything else becomes '?'.
+func encode(s string) string {
+	b := make([]byte, 0, len(s))
+	for _, r := range s {
+		switch {
+		case r < 0x20:
+			b = append(b, ' ')
+		case r < 0x7f, r >= 0xa0 && r <= 0xff:
+			b = append(b, byte(r))
+		default:
+			b = append(b, '?')
+		}
+	}
+	return string(b)
+}
+
+func escape(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `(`, `\(`, `)`, `\)`)
+	return r.Replace(s)
+}
+
+// num formats a coordinate with at most three decimals, dropping trailing
+// zeros.
+func num(f float64) string {
+	s := strconv.FormatFloat(f, 'f', 3, 64)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	if s == "-0" {
+		return "0"
+	}
+	return s
+}
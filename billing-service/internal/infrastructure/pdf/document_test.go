@@ -0,0 +1,56 @@
+package pdf
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func golden(t *testing.T, name string, got []byte) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("%s differs from the golden file; run go test -update if the change is intended", name)
+	}
+}
+
+func TestDocumentMatchesGolden(t *testing.T) {
+	doc := New("Fatura (teste) \\ acentuação")
+
+	page := doc.AddPage()
+	page.Rect(30, 30, PageWidth-60, 80, 1)
+	page.FillRect(30, 120, PageWidth-60, 20, 0.9)
+	page.Text(40, 60, HelveticaBold, 14, "DANFE de teste")
+	page.TextRight(PageWidth-40, 60, Helvetica, 9, "Nº 000.000.042")
+	page.TextCenter(PageWidth/2, 135, Helvetica, 8, "São Paulo - ação (1/2)")
+	page.Line(30, 150, PageWidth-30, 150, 0.5)
+
+	doc.AddPage().Text(40, 60, Helvetica, 10, "Segunda página")
+
+	golden(t, "document", doc.Bytes())
+}
+
+func TestDocumentIsDeterministic(t *testing.T) {
+	build := func() []byte {
+		doc := New("x")
+		doc.AddPage().Text(10, 10, Helvetica, 10, "same bytes")
+		return doc.Bytes()
+	}
+	if !bytes.Equal(build(), build()) {
+		t.Fatal("two renders of the same document differ")
+	}
+}
@@ -0,0 +1,80 @@
+package pdf
+
+import "strings"
+
+type Font int
+
+const (
+	Helvetica Font = iota
+	HelveticaBold
+)
+
+func (f Font) name() string {
+	if f == HelveticaBold {
+		return "Helvetica-Bold"
+	}
+	return "Helvetica"
+}
+
+func (f Font) resource() string {
+	if f == HelveticaBold {
+		return "F2"
+	}
+	return "F1"
+}
+
+// Width returns the width of s in points when set at the given size.
+func (f Font) Width(s string, size float64) float64 {
+	widths := &helveticaWidths
+	if f == HelveticaBold {
+		widths = &helveticaBoldWidths
+	}
+
+	total := 0
+	for _, c := range []byte(encode(s)) {
+		if c >= 0x20 && c < 0x7f {
+			total += widths[c-0x20]
+			continue
+		}
+		// Accented letters are as wide as their base letter; 556 is close
+		// enough for everything else in Latin-1.
+		total += 556
+	}
+	return float64(total) * size / 1000
+}
+
+// Fit shortens s with an ellipsis until it is at most maxWidth wide.
+func (f Font) Fit(s string, size, maxWidth float64) string {
+	if f.Width(s, size) <= maxWidth {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 {
+		runes = runes[:len(runes)-1]
+		candidate := strings.TrimSpace(string(runes)) + "..."
+		if f.Width(candidate, size) <= maxWidth {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// Advance widths for the printable ASCII range (0x20-0x7e) from the standard
+// Type 1 font metrics.
+var helveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+var helveticaBoldWidths = [95]int{
+	278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+	975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+	333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+	611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+}
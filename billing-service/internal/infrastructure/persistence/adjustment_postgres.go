@@ -0,0 +1,99 @@
+package persistence
+
+import (
+	"context"
+
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/invoice"
+	"github.com/vitorwhois/microservice-invoice-billing/money"
+)
+
+func (r *PostgresRepository) AddAdjustment(ctx context.Context, inv *invoice.Invoice, adj *invoice.Adjustment) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := lockEditable(ctx, tx, inv); err != nil {
+		return err
+	}
+
+	query := `
+        INSERT INTO invoice_adjustments (invoice_id, invoice_item_id, kind, method, rate, amount, reason, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+        RETURNING id`
+
+	err = tx.QueryRowContext(ctx, query,
+		adj.InvoiceID, nullableID(adj.ItemID), adj.Kind, adj.Method, adj.Rate, adj.Amount, adj.Reason, adj.CreatedAt,
+	).Scan(&adj.ID)
+	if err != nil {
+		return err
+	}
+
+	if err := saveTotals(ctx, tx, inv); err != nil {
+		return err
+	}
+
+	if err := insertEvents(ctx, tx, []invoice.Event{invoice.AdjustmentAddedEvent(adj)}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *PostgresRepository) RemoveAdjustment(ctx context.Context, inv *invoice.Invoice, adj *invoice.Adjustment) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := lockEditable(ctx, tx, inv); err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx,
+		`DELETE FROM invoice_adjustments WHERE id = $1 AND invoice_id = $2`, adj.ID, adj.InvoiceID)
+	if err != nil {
+		return err
+	}
+	if err := expectRow(result, invoice.ErrAdjustmentNotFound); err != nil {
+		return err
+	}
+
+	if err := saveTotals(ctx, tx, inv); err != nil {
+		return err
+	}
+
+	if err := insertEvents(ctx, tx, []invoice.Event{invoice.AdjustmentRemovedEvent(adj)}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *PostgresRepository) loadAdjustments(ctx context.Context, inv *invoice.Invoice) error {
+	query := `
+        SELECT id, COALESCE(invoice_item_id, 0), kind, method, rate, amount, reason, created_at
+        FROM invoice_adjustments
+        WHERE invoice_id = $1
+        ORDER BY id`
+
+	rows, err := r.db.QueryContext(ctx, query, inv.ID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	inv.Adjustments = make([]*invoice.Adjustment, 0)
+	for rows.Next() {
+		adj := &invoice.Adjustment{InvoiceID: inv.ID, Amount: money.Zero(inv.Currency)}
+		if err := rows.Scan(
+			&adj.ID, &adj.ItemID, &adj.Kind, &adj.Method, &adj.Rate, &adj.Amount, &adj.Reason, &adj.CreatedAt,
+		); err != nil {
+			return err
+		}
+		inv.Adjustments = append(inv.Adjustments, adj)
+	}
+	return rows.Err()
+}
@@ -0,0 +1,81 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/invoice"
+	"github.com/vitorwhois/microservice-invoice-billing/money"
+)
+
+type PostgresBoletoRepository struct {
+	db *sql.DB
+}
+
+func NewBoletoRepository(db *sql.DB) invoice.BoletoRepository {
+	return &PostgresBoletoRepository{db: db}
+}
+
+func (r *PostgresBoletoRepository) NextSequence(ctx context.Context) (int64, error) {
+	var sequence int64
+	err := r.db.QueryRowContext(ctx, `SELECT nextval('boleto_sequence')`).Scan(&sequence)
+	return sequence, err
+}
+
+func (r *PostgresBoletoRepository) Create(ctx context.Context, b *invoice.Boleto, inv *invoice.Invoice) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+        INSERT INTO boletos (invoice_id, bank_code, sequence, our_number, due_date, amount, barcode, digitable_line, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+        RETURNING id`
+
+	err = tx.QueryRowContext(ctx, query,
+		b.InvoiceID, b.BankCode, b.Sequence, b.OurNumber, b.DueDate, b.Amount, b.Barcode, b.DigitableLine, b.CreatedAt,
+	).Scan(&b.ID)
+	if err != nil {
+		return err
+	}
+
+	if err := insertEvents(ctx, tx, inv.PullEvents()); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *PostgresBoletoRepository) ListByInvoice(ctx context.Context, invoiceID int) ([]*invoice.Boleto, error) {
+	query := `
+        SELECT b.id, b.invoice_id, b.bank_code, b.sequence, b.our_number, b.due_date, b.amount, i.currency, b.barcode, b.digitable_line, b.created_at
+        FROM boletos b
+        JOIN invoices i ON i.id = b.invoice_id
+        WHERE b.invoice_id = $1
+        ORDER BY b.created_at, b.id`
+
+	rows, err := r.db.QueryContext(ctx, query, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	boletos := make([]*invoice.Boleto, 0)
+	for rows.Next() {
+		b := &invoice.Boleto{}
+		var amount money.Literal
+		var currency string
+		err := rows.Scan(&b.ID, &b.InvoiceID, &b.BankCode, &b.Sequence, &b.OurNumber, &b.DueDate,
+			&amount, &currency, &b.Barcode, &b.DigitableLine, &b.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		if b.Amount, err = amount.In(currency); err != nil {
+			return nil, err
+		}
+		boletos = append(boletos, b)
+	}
+	return boletos, rows.Err()
+}
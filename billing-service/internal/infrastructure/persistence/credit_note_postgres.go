@@ -0,0 +1,158 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/invoice"
+	"github.com/vitorwhois/microservice-invoice-billing/money"
+)
+
+type PostgresCreditNoteRepository struct {
+	db *sql.DB
+}
+
+func NewCreditNoteRepository(db *sql.DB) invoice.CreditNoteRepository {
+	return &PostgresCreditNoteRepository{db: db}
+}
+
+func (r *PostgresCreditNoteRepository) Create(ctx context.Context, note *invoice.CreditNote, inv *invoice.Invoice) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// The invoice row lock serialises the numbering of its credit notes
+	var issued int
+	if _, err := tx.ExecContext(ctx, `SELECT id FROM invoices WHERE id = $1 FOR UPDATE`, note.InvoiceID); err != nil {
+		return err
+	}
+	if err := tx.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM credit_notes WHERE invoice_id = $1`, note.InvoiceID).Scan(&issued); err != nil {
+		return err
+	}
+	inv.CreditNoteIssued(note, issued+1)
+
+	query := `
+        INSERT INTO credit_notes (invoice_id, number, reason, total_value, created_at)
+        VALUES ($1, $2, $3, $4, $5)
+        RETURNING id`
+
+	err = tx.QueryRowContext(ctx, query,
+		note.InvoiceID, note.Number, note.Reason, note.TotalValue, note.CreatedAt,
+	).Scan(&note.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range note.Items {
+		item.CreditNoteID = note.ID
+		query := `
+            INSERT INTO credit_note_items (credit_note_id, invoice_item_id, product_id, quantity, price, amount, returned)
+            VALUES ($1, $2, $3, $4, $5, $6, $7)
+            RETURNING id`
+
+		err = tx.QueryRowContext(ctx, query,
+			item.CreditNoteID, item.InvoiceItemID, item.ProductID, item.Quantity, item.Price, item.Amount, item.Returned,
+		).Scan(&item.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := updateInvoice(ctx, tx, inv); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *PostgresCreditNoteRepository) ListByInvoice(ctx context.Context, invoiceID int) ([]*invoice.CreditNote, error) {
+	query := `
+        SELECT n.id, n.invoice_id, n.number, n.reason, n.total_value, i.currency, n.created_at
+        FROM credit_notes n
+        JOIN invoices i ON i.id = n.invoice_id
+        WHERE n.invoice_id = $1
+        ORDER BY n.id`
+
+	return r.list(ctx, query, invoiceID)
+}
+
+func (r *PostgresCreditNoteRepository) ListPendingReturns(ctx context.Context) ([]*invoice.CreditNote, error) {
+	query := `
+        SELECT n.id, n.invoice_id, n.number, n.reason, n.total_value, i.currency, n.created_at
+        FROM credit_notes n
+        JOIN invoices i ON i.id = n.invoice_id
+        WHERE n.id IN (SELECT credit_note_id FROM credit_note_items WHERE NOT returned)
+        ORDER BY n.id`
+
+	return r.list(ctx, query)
+}
+
+func (r *PostgresCreditNoteRepository) MarkReturned(ctx context.Context, item *invoice.CreditNoteItem) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE credit_note_items SET returned = TRUE WHERE id = $1`, item.ID)
+	if err != nil {
+		return err
+	}
+	item.Returned = true
+	return nil
+}
+
+func (r *PostgresCreditNoteRepository) list(ctx context.Context, query string, args ...interface{}) ([]*invoice.CreditNote, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notes := make([]*invoice.CreditNote, 0)
+	for rows.Next() {
+		note := &invoice.CreditNote{}
+		var total money.Literal
+		var currency string
+		if err := rows.Scan(&note.ID, &note.InvoiceID, &note.Number, &note.Reason, &total, &currency, &note.CreatedAt); err != nil {
+			return nil, err
+		}
+		if note.TotalValue, err = total.In(currency); err != nil {
+			return nil, err
+		}
+		notes = append(notes, note)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, note := range notes {
+		if note.Items, err = r.items(ctx, note.ID, note.TotalValue.Currency); err != nil {
+			return nil, err
+		}
+	}
+	return notes, nil
+}
+
+func (r *PostgresCreditNoteRepository) items(ctx context.Context, creditNoteID int, currency string) ([]*invoice.CreditNoteItem, error) {
+	query := `
+        SELECT id, credit_note_id, invoice_item_id, product_id, quantity, price, amount, returned
+        FROM credit_note_items
+        WHERE credit_note_id = $1
+        ORDER BY id`
+
+	rows, err := r.db.QueryContext(ctx, query, creditNoteID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]*invoice.CreditNoteItem, 0)
+	for rows.Next() {
+		// Amounts are in the currency of the invoice: seed it before scanning
+		item := &invoice.CreditNoteItem{Price: money.Zero(currency), Amount: money.Zero(currency)}
+		if err := rows.Scan(&item.ID, &item.CreditNoteID, &item.InvoiceItemID, &item.ProductID, &item.Quantity, &item.Price, &item.Amount, &item.Returned); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
@@ -0,0 +1,195 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/customer"
+)
+
+type PostgresCustomerRepository struct {
+	db *sql.DB
+}
+
+func NewCustomerRepository(db *sql.DB) customer.Repository {
+	return &PostgresCustomerRepository{db: db}
+}
+
+func (r *PostgresCustomerRepository) Create(ctx context.Context, c *customer.Customer) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+        INSERT INTO customers (name, document, document_type, email, state_registration, created_at, updated_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)
+        RETURNING id`
+
+	err = tx.QueryRowContext(ctx, query,
+		c.Name, c.Document, c.DocumentType, c.Email, c.StateRegistration, c.CreatedAt, c.UpdatedAt,
+	).Scan(&c.ID)
+	if err != nil {
+		return customerError(err)
+	}
+
+	if err := insertAddresses(ctx, tx, c); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *PostgresCustomerRepository) GetByID(ctx context.Context, id int) (*customer.Customer, error) {
+	query := `
+        SELECT ` + customerColumns + `
+        FROM customers WHERE id = $1`
+
+	c := &customer.Customer{}
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&c.ID, &c.Name, &c.Document, &c.DocumentType, &c.Email, &c.StateRegistration, &c.CreatedAt, &c.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, customer.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Addresses, err = r.addresses(ctx, c.ID); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (r *PostgresCustomerRepository) List(ctx context.Context) ([]*customer.Customer, error) {
+	query := `
+        SELECT ` + customerColumns + `
+        FROM customers
+        ORDER BY name, id`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	customers := make([]*customer.Customer, 0)
+	for rows.Next() {
+		c := &customer.Customer{}
+		if err := rows.Scan(
+			&c.ID, &c.Name, &c.Document, &c.DocumentType, &c.Email, &c.StateRegistration, &c.CreatedAt, &c.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		customers = append(customers, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, c := range customers {
+		if c.Addresses, err = r.addresses(ctx, c.ID); err != nil {
+			return nil, err
+		}
+	}
+	return customers, nil
+}
+
+// Update rewrites the customer and replaces its addresses.
+func (r *PostgresCustomerRepository) Update(ctx context.Context, c *customer.Customer) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+        UPDATE customers
+        SET name = $1, document = $2, document_type = $3, email = $4, state_registration = $5, updated_at = $6
+        WHERE id = $7`
+
+	result, err := tx.ExecContext(ctx, query,
+		c.Name, c.Document, c.DocumentType, c.Email, c.StateRegistration, c.UpdatedAt, c.ID,
+	)
+	if err != nil {
+		return customerError(err)
+	}
+	if err := expectRow(result, customer.ErrNotFound); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM customer_addresses WHERE customer_id = $1`, c.ID); err != nil {
+		return err
+	}
+	if err := insertAddresses(ctx, tx, c); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *PostgresCustomerRepository) Delete(ctx context.Context, id int) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM customers WHERE id = $1`, id)
+	if err != nil {
+		return customerError(err)
+	}
+	return expectRow(result, customer.ErrNotFound)
+}
+
+const customerColumns = `id, name, document, document_type, email, state_registration, created_at, updated_at`
+
+func insertAddresses(ctx context.Context, tx *sql.Tx, c *customer.Customer) error {
+	query := `
+        INSERT INTO customer_addresses (customer_id, kind, street, number, complement, district, city_code, city, uf, cep, country)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
+
+	for _, a := range c.Addresses {
+		if _, err := tx.ExecContext(ctx, query,
+			c.ID, a.Kind, a.Street, a.Number, a.Complement, a.District, a.CityCode, a.City, a.UF, a.CEP, a.Country,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *PostgresCustomerRepository) addresses(ctx context.Context, customerID int) ([]customer.Address, error) {
+	query := `
+        SELECT kind, street, number, complement, district, city_code, city, uf, cep, country
+        FROM customer_addresses
+        WHERE customer_id = $1
+        ORDER BY id`
+
+	rows, err := r.db.QueryContext(ctx, query, customerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	addresses := make([]customer.Address, 0)
+	for rows.Next() {
+		var a customer.Address
+		if err := rows.Scan(&a.Kind, &a.Street, &a.Number, &a.Complement, &a.District, &a.CityCode, &a.City, &a.UF, &a.CEP, &a.Country); err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, a)
+	}
+	return addresses, rows.Err()
+}
+
+// customerError maps constraint violations to domain errors: a repeated
+// document, or deleting a customer that invoices still point to.
+func customerError(err error) error {
+	if pqErr, ok := err.(*pq.Error); ok {
+		switch pqErr.Code {
+		case "23505":
+			return customer.ErrDuplicateDocument
+		case "23503":
+			return customer.ErrInUse
+		}
+	}
+	return err
+}
@@ -0,0 +1,63 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/exchange"
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/invoice"
+	"github.com/vitorwhois/microservice-invoice-billing/money"
+)
+
+func (r *PostgresRepository) loadExchangeRates(ctx context.Context, inv *invoice.Invoice) error {
+	query := `
+        SELECT from_currency, to_currency, rate, source, as_of
+        FROM invoice_exchange_rates
+        WHERE invoice_id = $1
+        ORDER BY id`
+
+	rows, err := r.db.QueryContext(ctx, query, inv.ID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	inv.ExchangeRates = make([]exchange.Rate, 0)
+	for rows.Next() {
+		var rate exchange.Rate
+		var asOf sql.NullTime
+		if err := rows.Scan(&rate.From, &rate.To, &rate.Value, &rate.Source, &asOf); err != nil {
+			return err
+		}
+		rate.AsOf = asOf.Time
+		inv.ExchangeRates = append(inv.ExchangeRates, rate)
+	}
+	return rows.Err()
+}
+
+// saveExchangeRates replaces the rates frozen on the invoice, so a close that
+// is retried keeps only the rates of the last attempt.
+func saveExchangeRates(ctx context.Context, tx *sql.Tx, inv *invoice.Invoice) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM invoice_exchange_rates WHERE invoice_id = $1`, inv.ID); err != nil {
+		return err
+	}
+
+	query := `
+        INSERT INTO invoice_exchange_rates (invoice_id, from_currency, to_currency, rate, source, as_of)
+        VALUES ($1, $2, $3, $4, $5, $6)`
+
+	for _, rate := range inv.ExchangeRates {
+		asOf := sql.NullTime{Time: rate.AsOf.UTC(), Valid: !rate.AsOf.IsZero()}
+		if _, err := tx.ExecContext(ctx, query, inv.ID, rate.From, rate.To, rate.Value, rate.Source, asOf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func baseCurrency(item *invoice.InvoiceItem) string {
+	if item.BasePrice.Currency == "" {
+		return money.DefaultCurrency
+	}
+	return item.BasePrice.Currency
+}
@@ -0,0 +1,120 @@
+package persistence
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"time"
+
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/idempotency"
+)
+
+type PostgresIdempotencyRepository struct {
+	db *sql.DB
+}
+
+func NewIdempotencyRepository(db *sql.DB) idempotency.Repository {
+	return &PostgresIdempotencyRepository{db: db}
+}
+
+func (r *PostgresIdempotencyRepository) Reserve(ctx context.Context, key string, requestHash string, staleBefore time.Time) (*idempotency.Record, bool, error) {
+	token, err := newLockToken()
+	if err != nil {
+		return nil, false, err
+	}
+	now := time.Now()
+	rec := &idempotency.Record{Key: key, RequestHash: requestHash, LockToken: token, LockedAt: now, CreatedAt: now}
+
+	// The conditional upsert takes over a stale reservation of the same
+	// request atomically, so only one retry gets to run the handler again
+	insertQuery := `
+        INSERT INTO idempotency_keys (key, request_hash, lock_token, locked_at, created_at)
+        VALUES ($1, $2, $3, $4, $4)
+        ON CONFLICT (key) DO UPDATE SET lock_token = EXCLUDED.lock_token, locked_at = EXCLUDED.locked_at
+        WHERE idempotency_keys.completed = FALSE
+          AND idempotency_keys.request_hash = EXCLUDED.request_hash
+          AND idempotency_keys.locked_at < $5`
+
+	result, err := r.db.ExecContext(ctx, insertQuery, rec.Key, rec.RequestHash, rec.LockToken, now, staleBefore)
+	if err != nil {
+		return nil, false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return nil, false, err
+	}
+	if rows == 1 {
+		return rec, true, nil
+	}
+
+	selectQuery := `
+        SELECT key, request_hash, completed, status_code, content_type, body, lock_token, locked_at, completed_at, created_at
+        FROM idempotency_keys
+        WHERE key = $1`
+
+	existing := &idempotency.Record{}
+	var completedAt sql.NullTime
+	err = r.db.QueryRowContext(ctx, selectQuery, key).Scan(
+		&existing.Key, &existing.RequestHash, &existing.Completed, &existing.StatusCode,
+		&existing.ContentType, &existing.Body, &existing.LockToken, &existing.LockedAt, &completedAt, &existing.CreatedAt,
+	)
+	if err != nil {
+		return nil, false, err
+	}
+	if completedAt.Valid {
+		existing.CompletedAt = &completedAt.Time
+	}
+	return existing, false, nil
+}
+
+func (r *PostgresIdempotencyRepository) Complete(ctx context.Context, rec *idempotency.Record) error {
+	now := time.Now()
+	query := `
+        UPDATE idempotency_keys
+        SET completed = TRUE, status_code = $1, content_type = $2, body = $3, completed_at = $4
+        WHERE key = $5 AND lock_token = $6 AND completed = FALSE`
+
+	result, err := r.db.ExecContext(ctx, query, rec.StatusCode, rec.ContentType, rec.Body, now, rec.Key, rec.LockToken)
+	if err != nil {
+		return err
+	}
+	if err := expectRow(result, idempotency.ErrKeyTakenOver); err != nil {
+		return err
+	}
+	rec.Completed = true
+	rec.CompletedAt = &now
+	return nil
+}
+
+func (r *PostgresIdempotencyRepository) Release(ctx context.Context, rec *idempotency.Record) error {
+	query := `DELETE FROM idempotency_keys WHERE key = $1 AND lock_token = $2 AND completed = FALSE`
+
+	result, err := r.db.ExecContext(ctx, query, rec.Key, rec.LockToken)
+	if err != nil {
+		return err
+	}
+	return expectRow(result, idempotency.ErrKeyTakenOver)
+}
+
+func (r *PostgresIdempotencyRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	query := `
+        DELETE FROM idempotency_keys
+        WHERE (completed AND completed_at < $1) OR (NOT completed AND locked_at < $1)`
+
+	result, err := r.db.ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// newLockToken returns a random token telling reservations of a key apart.
+func newLockToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
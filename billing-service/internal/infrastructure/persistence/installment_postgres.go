@@ -0,0 +1,97 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/invoice"
+	"github.com/vitorwhois/microservice-invoice-billing/money"
+)
+
+// ListPastDue returns the invoices awaiting payment with a pending installment
+// due before asOf's day.
+func (r *PostgresRepository) ListPastDue(ctx context.Context, asOf time.Time) ([]*invoice.Invoice, error) {
+	query := `
+        SELECT ` + invoiceColumns + `
+        FROM invoices
+        WHERE status IN ($1, $2)
+          AND EXISTS (
+              SELECT 1 FROM invoice_installments
+              WHERE invoice_id = invoices.id AND status = $3 AND due_date < $4)
+        ORDER BY id`
+
+	return r.listInvoices(ctx, query,
+		invoice.StatusIssued, invoice.StatusPartiallyPaid, invoice.InstallmentPending, asOf.Format("2006-01-02"))
+}
+
+// ListOverdue returns the invoices awaiting payment with an overdue
+// installment, the ones late the longest first.
+func (r *PostgresRepository) ListOverdue(ctx context.Context) ([]*invoice.Invoice, error) {
+	query := `
+        SELECT ` + invoiceColumns + `
+        FROM invoices
+        WHERE status IN ($1, $2)
+          AND EXISTS (
+              SELECT 1 FROM invoice_installments
+              WHERE invoice_id = invoices.id AND status = $3)
+        ORDER BY (
+            SELECT MIN(due_date) FROM invoice_installments
+            WHERE invoice_id = invoices.id AND status = $3), id`
+
+	return r.listInvoices(ctx, query, invoice.StatusIssued, invoice.StatusPartiallyPaid, invoice.InstallmentOverdue)
+}
+
+func (r *PostgresRepository) loadInstallments(ctx context.Context, inv *invoice.Invoice) error {
+	query := `
+        SELECT id, number, due_date, amount, status, paid_at
+        FROM invoice_installments
+        WHERE invoice_id = $1
+        ORDER BY number`
+
+	rows, err := r.db.QueryContext(ctx, query, inv.ID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	inv.Installments = make([]*invoice.Installment, 0)
+	for rows.Next() {
+		inst := &invoice.Installment{InvoiceID: inv.ID, Amount: money.Zero(inv.Currency)}
+		if err := rows.Scan(&inst.ID, &inst.Number, &inst.DueDate, &inst.Amount, &inst.Status, &inst.PaidAt); err != nil {
+			return err
+		}
+		inv.Installments = append(inv.Installments, inst)
+	}
+	return rows.Err()
+}
+
+// saveInstallments inserts the installments scheduled when the invoice was
+// issued and stores the status of the existing ones.
+func saveInstallments(ctx context.Context, tx *sql.Tx, inv *invoice.Invoice) error {
+	for _, inst := range inv.Installments {
+		if inst.ID == 0 {
+			query := `
+                INSERT INTO invoice_installments (invoice_id, number, due_date, amount, status, paid_at)
+                VALUES ($1, $2, $3, $4, $5, $6)
+                RETURNING id`
+
+			err := tx.QueryRowContext(ctx, query,
+				inv.ID, inst.Number, inst.DueDate, inst.Amount, inst.Status, inst.PaidAt,
+			).Scan(&inst.ID)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		_, err := tx.ExecContext(ctx,
+			`UPDATE invoice_installments SET status = $1, paid_at = $2 WHERE id = $3`,
+			inst.Status, inst.PaidAt, inst.ID,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
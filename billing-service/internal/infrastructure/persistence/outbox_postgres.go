@@ -0,0 +1,136 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/invoice"
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/outbox"
+)
+
+const invoiceAggregate = "invoice"
+
+// outboxClaimLock is the advisory lock key serialising outbox claims.
+const outboxClaimLock = 7_007_001
+
+type PostgresOutboxRepository struct {
+	db *sql.DB
+}
+
+func NewOutboxRepository(db *sql.DB) outbox.Repository {
+	return &PostgresOutboxRepository{db: db}
+}
+
+// insertEvents appends invoice events to the outbox inside the caller's
+// transaction, so they are stored if and only if the change is committed.
+func insertEvents(ctx context.Context, tx *sql.Tx, events []invoice.Event) error {
+	query := `
+        INSERT INTO outbox_events (aggregate_type, aggregate_id, event_type, payload, occurred_at)
+        VALUES ($1, $2, $3, $4, $5)`
+
+	for _, ev := range events {
+		payload, err := json.Marshal(ev.Payload)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, query,
+			invoiceAggregate, ev.InvoiceID, ev.Name, payload, ev.OccurredAt,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *PostgresOutboxRepository) Claim(ctx context.Context, limit int, now, claimedUntil time.Time) ([]outbox.Message, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Claims are serialised so that two relays cannot both see an aggregate
+	// as free and claim different events of it
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, outboxClaimLock); err != nil {
+		return nil, err
+	}
+
+	query := `
+        UPDATE outbox_events SET claimed_until = $2
+        WHERE id IN (
+            SELECT e.id FROM outbox_events e
+            WHERE e.published_at IS NULL AND e.dead_lettered_at IS NULL
+              AND (e.claimed_until IS NULL OR e.claimed_until <= $1)
+              AND NOT EXISTS (
+                  SELECT 1 FROM outbox_events prev
+                  WHERE prev.aggregate_type = e.aggregate_type
+                    AND prev.aggregate_id = e.aggregate_id
+                    AND prev.id < e.id
+                    AND prev.published_at IS NULL AND prev.dead_lettered_at IS NULL
+                    AND prev.claimed_until > $1)
+            ORDER BY e.id
+            LIMIT $3
+            FOR UPDATE SKIP LOCKED)
+        RETURNING id, aggregate_type, aggregate_id, event_type, payload, occurred_at, attempts, last_error`
+
+	rows, err := tx.QueryContext(ctx, query, now, claimedUntil, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make([]outbox.Message, 0)
+	for rows.Next() {
+		var msg outbox.Message
+		if err := rows.Scan(
+			&msg.ID, &msg.AggregateType, &msg.AggregateID, &msg.EventType, &msg.Payload,
+			&msg.OccurredAt, &msg.Attempts, &msg.LastError,
+		); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		messages = append(messages, msg)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(messages, func(i, j int) bool { return messages[i].ID < messages[j].ID })
+	return messages, tx.Commit()
+}
+
+func (r *PostgresOutboxRepository) MarkPublished(ctx context.Context, id int64, at time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+        UPDATE outbox_events
+        SET published_at = $1, attempts = attempts + 1, last_error = '', claimed_until = NULL
+        WHERE id = $2`, at, id)
+	return err
+}
+
+func (r *PostgresOutboxRepository) MarkFailed(ctx context.Context, id int64, cause error, retryAt time.Time, maxAttempts int) (bool, error) {
+	query := `
+        UPDATE outbox_events
+        SET attempts = attempts + 1, last_error = $1, claimed_until = $2,
+            dead_lettered_at = CASE WHEN attempts + 1 >= $3 THEN $2 END
+        WHERE id = $4
+        RETURNING dead_lettered_at IS NOT NULL`
+
+	var dead bool
+	if err := r.db.QueryRowContext(ctx, query, cause.Error(), retryAt, maxAttempts, id).Scan(&dead); err != nil {
+		return false, err
+	}
+	return dead, nil
+}
+
+func (r *PostgresOutboxRepository) Release(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE outbox_events SET claimed_until = NULL WHERE id = ANY($1)`, pq.Array(ids))
+	return err
+}
@@ -0,0 +1,74 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/invoice"
+	"github.com/vitorwhois/microservice-invoice-billing/money"
+)
+
+type PostgresPaymentRepository struct {
+	db *sql.DB
+}
+
+func NewPaymentRepository(db *sql.DB) invoice.PaymentRepository {
+	return &PostgresPaymentRepository{db: db}
+}
+
+func (r *PostgresPaymentRepository) Create(ctx context.Context, p *invoice.Payment, inv *invoice.Invoice) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+        INSERT INTO payments (invoice_id, method, amount, paid_at, reference, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        RETURNING id`
+
+	err = tx.QueryRowContext(ctx, query,
+		p.InvoiceID, p.Method, p.Amount, p.PaidAt, p.Reference, p.CreatedAt,
+	).Scan(&p.ID)
+	if err != nil {
+		return err
+	}
+
+	if err := updateInvoice(ctx, tx, inv); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *PostgresPaymentRepository) ListByInvoice(ctx context.Context, invoiceID int) ([]*invoice.Payment, error) {
+	query := `
+        SELECT p.id, p.invoice_id, p.method, p.amount, i.currency, p.paid_at, p.reference, p.created_at
+        FROM payments p
+        JOIN invoices i ON i.id = p.invoice_id
+        WHERE p.invoice_id = $1
+        ORDER BY p.paid_at, p.id`
+
+	rows, err := r.db.QueryContext(ctx, query, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	payments := make([]*invoice.Payment, 0)
+	for rows.Next() {
+		p := &invoice.Payment{}
+		var amount money.Literal
+		var currency string
+		if err := rows.Scan(&p.ID, &p.InvoiceID, &p.Method, &amount, &currency, &p.PaidAt, &p.Reference, &p.CreatedAt); err != nil {
+			return nil, err
+		}
+		var err error
+		if p.Amount, err = amount.In(currency); err != nil {
+			return nil, err
+		}
+		payments = append(payments, p)
+	}
+	return payments, rows.Err()
+}
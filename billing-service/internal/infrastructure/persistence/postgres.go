@@ -3,8 +3,14 @@ package persistence
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"fmt"
 
+	"github.com/lib/pq"
 	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/invoice"
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/numbering"
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/tax"
+	"github.com/vitorwhois/microservice-invoice-billing/money"
 )
 
 type PostgresRepository struct {
@@ -15,35 +21,44 @@ func NewInvoiceRepository(db *sql.DB) invoice.Repository {
 	return &PostgresRepository{db: db}
 }
 
-func (r *PostgresRepository) Create(ctx context.Context, inv *invoice.Invoice) error {
+func (r *PostgresRepository) Create(ctx context.Context, inv *invoice.Invoice, series *numbering.Series) error {
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
+	if series != nil {
+		if err := allocateNumber(ctx, tx, inv, series); err != nil {
+			return err
+		}
+	}
+
 	query := `
-        INSERT INTO invoices (number, status, created_at, total_value)
-        VALUES ($1, $2, $3, $4)
+        INSERT INTO invoices (number, series_code, period, sequence, document_number, status, currency, created_at, total_value, customer_id, payment_terms)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
         RETURNING id`
 
 	err = tx.QueryRowContext(ctx, query,
-		inv.Number, inv.Status, inv.CreatedAt, inv.TotalValue,
+		inv.Number, nullableCode(inv.Series), inv.Period, inv.Sequence, inv.DocumentNumber, inv.Status, inv.Currency, inv.CreatedAt, inv.TotalValue, nullableID(inv.CustomerID), inv.PaymentTerms,
 	).Scan(&inv.ID)
 
 	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return invoice.ErrDuplicateNumber
+		}
 		return err
 	}
 
 	for _, item := range inv.Items {
 		item.InvoiceID = inv.ID
 		query := `
-            INSERT INTO invoice_items (invoice_id, product_id, quantity, price, name)
-            VALUES ($1, $2, $3, $4, $5)
+            INSERT INTO invoice_items (invoice_id, product_id, quantity, price, base_price, base_currency, name, reservation_id)
+            VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
             RETURNING id`
 
 		err = tx.QueryRowContext(ctx, query,
-			item.InvoiceID, item.ProductID, item.Quantity, item.Price, item.Name,
+			item.InvoiceID, item.ProductID, item.Quantity, item.Price, item.BasePrice, baseCurrency(item), item.Name, nullableID(item.ReservationID),
 		).Scan(&item.ID)
 
 		if err != nil {
@@ -51,19 +66,26 @@ func (r *PostgresRepository) Create(ctx context.Context, inv *invoice.Invoice) e
 		}
 	}
 
+	transitions := append([]invoice.Transition{invoice.InitialTransition(inv)}, inv.PullTransitions()...)
+	if err := insertTransitions(ctx, tx, inv.ID, transitions); err != nil {
+		return err
+	}
+
+	events := append([]invoice.Event{invoice.CreatedEvent(inv)}, inv.PullEvents()...)
+	if err := insertEvents(ctx, tx, events); err != nil {
+		return err
+	}
+
 	return tx.Commit()
 }
 
 func (r *PostgresRepository) GetByID(ctx context.Context, id int) (*invoice.Invoice, error) {
 	invQuery := `
-        SELECT id, number, status, created_at, closed_at, total_value
+        SELECT ` + invoiceColumns + `
         FROM invoices 
         WHERE id = $1`
 
-	inv := &invoice.Invoice{}
-	err := r.db.QueryRowContext(ctx, invQuery, id).Scan(
-		&inv.ID, &inv.Number, &inv.Status, &inv.CreatedAt, &inv.ClosedAt, &inv.TotalValue,
-	)
+	inv, err := scanInvoice(r.db.QueryRowContext(ctx, invQuery, id))
 
 	if err == sql.ErrNoRows {
 		return nil, invoice.ErrNotFound
@@ -73,83 +95,357 @@ func (r *PostgresRepository) GetByID(ctx context.Context, id int) (*invoice.Invo
 		return nil, err
 	}
 
-	itemsQuery := `
-        SELECT id, product_id, quantity, price, name
-        FROM invoice_items
-        WHERE invoice_id = $1`
+	if err := r.loadItems(ctx, inv); err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
 
-	rows, err := r.db.QueryContext(ctx, itemsQuery, id)
+func (r *PostgresRepository) Update(ctx context.Context, inv *invoice.Invoice) error {
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer rows.Close()
+	defer tx.Rollback()
 
-	inv.Items = make([]*invoice.InvoiceItem, 0)
-	for rows.Next() {
-		item := &invoice.InvoiceItem{InvoiceID: id}
-		if err := rows.Scan(&item.ID, &item.ProductID, &item.Quantity, &item.Price, &item.Name); err != nil {
-			return nil, err
-		}
-		inv.Items = append(inv.Items, item)
+	if err := updateInvoice(ctx, tx, inv); err != nil {
+		return err
 	}
-	inv.CalculateTotal()
-	return inv, nil
+
+	return tx.Commit()
 }
 
-func (r *PostgresRepository) Update(ctx context.Context, inv *invoice.Invoice) error {
+// updateInvoice saves the invoice header and installments along with the
+// history entries and events recorded on it, inside the caller's transaction.
+// It fails with ErrConcurrentUpdate when the invoice changed since inv was
+// read.
+func updateInvoice(ctx context.Context, tx *sql.Tx, inv *invoice.Invoice) error {
 	query := `
         UPDATE invoices
-        SET status = $1, closed_at = $2, total_value = $3
-        WHERE id = $4`
+        SET status = $1, closed_at = $2, total_value = $3, customer_snapshot = $4, payment_terms = $5, version = version + 1
+        WHERE id = $6 AND version = $7`
 
-	_, err := r.db.ExecContext(ctx, query,
-		inv.Status, inv.ClosedAt, inv.TotalValue, inv.ID,
+	snapshot, err := marshalSnapshot(inv.Customer)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, query,
+		inv.Status, inv.ClosedAt, inv.TotalValue, snapshot, inv.PaymentTerms, inv.ID, inv.Version,
 	)
+	if err != nil {
+		return err
+	}
+	if err := expectRow(result, invoice.ErrConcurrentUpdate); err != nil {
+		return err
+	}
+	inv.Version++
 
-	return err
+	if err := saveInstallments(ctx, tx, inv); err != nil {
+		return err
+	}
+
+	if err := insertTransitions(ctx, tx, inv.ID, inv.PullTransitions()); err != nil {
+		return err
+	}
+
+	return insertEvents(ctx, tx, inv.PullEvents())
 }
-func (r *PostgresRepository) AddItem(ctx context.Context, item *invoice.InvoiceItem) error {
+func (r *PostgresRepository) AddItem(ctx context.Context, inv *invoice.Invoice, item *invoice.InvoiceItem) error {
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
+	if err := lockEditable(ctx, tx, inv); err != nil {
+		return err
+	}
+
 	itemQuery := `
-        INSERT INTO invoice_items (invoice_id, product_id, quantity, price, name)
-        VALUES ($1, $2, $3, $4, $5)
+        INSERT INTO invoice_items (invoice_id, product_id, quantity, price, base_price, base_currency, name, reservation_id)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
         RETURNING id`
 
 	err = tx.QueryRowContext(ctx, itemQuery,
-		item.InvoiceID, item.ProductID, item.Quantity, item.Price, item.Name,
+		item.InvoiceID, item.ProductID, item.Quantity, item.Price, item.BasePrice, baseCurrency(item), item.Name, nullableID(item.ReservationID),
 	).Scan(&item.ID)
 
 	if err != nil {
 		return err
 	}
 
-	updateQuery := `
-        UPDATE invoices 
-		SET total_value = total_value + CAST($1 AS numeric) * CAST($2 AS numeric)
-        WHERE id = $3`
+	if err := saveTotals(ctx, tx, inv); err != nil {
+		return err
+	}
 
-	_, err = tx.ExecContext(ctx, updateQuery,
-		item.Quantity, item.Price, item.InvoiceID,
-	)
+	if err := insertEvents(ctx, tx, []invoice.Event{invoice.ItemAddedEvent(item)}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *PostgresRepository) UpdateItem(ctx context.Context, inv *invoice.Invoice, item *invoice.InvoiceItem, previousQuantity int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
+
+	if err := lockEditable(ctx, tx, inv); err != nil {
+		return err
+	}
+
+	itemQuery := `
+        UPDATE invoice_items
+        SET quantity = $1, reservation_id = $2
+        WHERE id = $3 AND invoice_id = $4 AND quantity = $5`
+
+	// The quantity check catches an edit that raced ours on the same line
+	result, err := tx.ExecContext(ctx, itemQuery, item.Quantity, nullableID(item.ReservationID), item.ID, item.InvoiceID, previousQuantity)
+	if err != nil {
+		return err
+	}
+	if err := expectRow(result, invoice.ErrConcurrentUpdate); err == invoice.ErrConcurrentUpdate {
+		var exists bool
+		if qerr := tx.QueryRowContext(ctx,
+			`SELECT EXISTS (SELECT 1 FROM invoice_items WHERE id = $1 AND invoice_id = $2)`,
+			item.ID, item.InvoiceID).Scan(&exists); qerr == nil && !exists {
+			return invoice.ErrItemNotFound
+		}
+		return err
+	} else if err != nil {
+		return err
+	}
+
+	if err := saveTotals(ctx, tx, inv); err != nil {
+		return err
+	}
+
+	if err := insertEvents(ctx, tx, []invoice.Event{invoice.ItemUpdatedEvent(item, previousQuantity)}); err != nil {
+		return err
+	}
 
 	return tx.Commit()
 }
 
+func (r *PostgresRepository) RemoveItem(ctx context.Context, inv *invoice.Invoice, item *invoice.InvoiceItem) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := lockEditable(ctx, tx, inv); err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx,
+		`DELETE FROM invoice_items WHERE id = $1 AND invoice_id = $2`, item.ID, item.InvoiceID)
+	if err != nil {
+		return err
+	}
+	if err := expectRow(result, invoice.ErrItemNotFound); err != nil {
+		return err
+	}
+
+	if err := saveTotals(ctx, tx, inv); err != nil {
+		return err
+	}
+
+	if err := insertEvents(ctx, tx, []invoice.Event{invoice.ItemRemovedEvent(item)}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// lockEditable locks the row of an invoice that can still be edited and bumps
+// its version. The change was computed from inv, so it is refused with
+// ErrConcurrentUpdate when the invoice moved on since inv was read; a print
+// that read the invoice before the change then fails to start, and once a
+// print has started the change is refused.
+func lockEditable(ctx context.Context, tx *sql.Tx, inv *invoice.Invoice) error {
+	var status invoice.Status
+	var version int
+	err := tx.QueryRowContext(ctx,
+		`SELECT status, version FROM invoices WHERE id = $1 FOR UPDATE`, inv.ID).Scan(&status, &version)
+	if err == sql.ErrNoRows {
+		return invoice.ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if status != invoice.StatusDraft && status != invoice.StatusOpen {
+		return errNotEditable
+	}
+	if version != inv.Version {
+		return invoice.ErrConcurrentUpdate
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE invoices SET version = version + 1 WHERE id = $1`, inv.ID); err != nil {
+		return err
+	}
+	inv.Version++
+	return nil
+}
+
+var errNotEditable = fmt.Errorf("%w: the invoice can no longer be edited", invoice.ErrInvalidStatus)
+
+func expectRow(result sql.Result, notFound error) error {
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return notFound
+	}
+	return nil
+}
+
+// loadItems reads the items of the invoice with their tax breakdown and
+// adjustments, recomputes the totals from them and reads the installments.
+func (r *PostgresRepository) loadItems(ctx context.Context, inv *invoice.Invoice) error {
+	itemsQuery := `
+        SELECT id, product_id, quantity, price, base_price, base_currency, name, ncm, COALESCE(reservation_id, 0)
+        FROM invoice_items
+        WHERE invoice_id = $1
+        ORDER BY id`
+
+	rows, err := r.db.QueryContext(ctx, itemsQuery, inv.ID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	inv.Items = make([]*invoice.InvoiceItem, 0)
+	byID := make(map[int]*invoice.InvoiceItem)
+	for rows.Next() {
+		// Amounts are stored without their currency: seed it before scanning
+		item := &invoice.InvoiceItem{InvoiceID: inv.ID, Price: money.Zero(inv.Currency)}
+		var basePrice money.Literal
+		var currency string
+		if err := rows.Scan(&item.ID, &item.ProductID, &item.Quantity, &item.Price, &basePrice, &currency, &item.Name, &item.NCM, &item.ReservationID); err != nil {
+			return err
+		}
+		var err error
+		if item.BasePrice, err = basePrice.In(currency); err != nil {
+			return err
+		}
+		inv.Items = append(inv.Items, item)
+		byID[item.ID] = item
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	taxQuery := `
+        SELECT t.invoice_item_id, t.kind, t.base, t.rate, t.amount, t.included
+        FROM invoice_item_taxes t
+        JOIN invoice_items i ON i.id = t.invoice_item_id
+        WHERE i.invoice_id = $1
+        ORDER BY t.id`
+
+	taxRows, err := r.db.QueryContext(ctx, taxQuery, inv.ID)
+	if err != nil {
+		return err
+	}
+	defer taxRows.Close()
+
+	for taxRows.Next() {
+		var itemID int
+		line := tax.Line{Base: money.Zero(inv.Currency), Amount: money.Zero(inv.Currency)}
+		if err := taxRows.Scan(&itemID, &line.Kind, &line.Base, &line.Rate, &line.Amount, &line.Included); err != nil {
+			return err
+		}
+		if item, ok := byID[itemID]; ok {
+			item.Taxes = append(item.Taxes, line)
+		}
+	}
+	if err := taxRows.Err(); err != nil {
+		return err
+	}
+
+	if err := r.loadAdjustments(ctx, inv); err != nil {
+		return err
+	}
+	if err := r.loadExchangeRates(ctx, inv); err != nil {
+		return err
+	}
+
+	// An invoice whose discounts no longer fit still loads, so that it can be
+	// corrected; every change and the print check the fit again
+	_ = inv.CalculateTotal()
+	return r.loadInstallments(ctx, inv)
+}
+
+func (r *PostgresRepository) SaveTotals(ctx context.Context, inv *invoice.Invoice) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := saveTotals(ctx, tx, inv); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// saveTotals writes the tax breakdown and adjustment shares of every item and
+// the invoice totals inside the caller's transaction.
+func saveTotals(ctx context.Context, tx *sql.Tx, inv *invoice.Invoice) error {
+	insertQuery := `
+        INSERT INTO invoice_item_taxes (invoice_item_id, kind, base, rate, amount, included)
+        VALUES ($1, $2, $3, $4, $5, $6)`
+
+	for _, item := range inv.Items {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM invoice_item_taxes WHERE invoice_item_id = $1`, item.ID); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE invoice_items SET price = $1, ncm = $2, discount = $3, surcharge = $4 WHERE id = $5`,
+			item.Price, item.NCM, item.Discount, item.Surcharge, item.ID,
+		); err != nil {
+			return err
+		}
+		for _, line := range item.Taxes {
+			if _, err := tx.ExecContext(ctx, insertQuery,
+				item.ID, line.Kind, line.Base, line.Rate, line.Amount, line.Included,
+			); err != nil {
+				return err
+			}
+		}
+	}
+
+	totalsQuery := `
+        UPDATE invoices
+        SET subtotal = $1, discount_total = $2, surcharge_total = $3, tax_total = $4, total_value = $5
+        WHERE id = $6`
+
+	if _, err := tx.ExecContext(ctx, totalsQuery,
+		inv.Subtotal, inv.DiscountTotal, inv.SurchargeTotal, inv.TaxTotal, inv.TotalValue, inv.ID,
+	); err != nil {
+		return err
+	}
+
+	return saveExchangeRates(ctx, tx, inv)
+}
+
 func (r *PostgresRepository) List(ctx context.Context) ([]*invoice.Invoice, error) {
 	query := `
-        SELECT id, number, status, created_at, closed_at, total_value
+        SELECT ` + invoiceColumns + `
         FROM invoices
         ORDER BY created_at DESC`
 
-	rows, err := r.db.QueryContext(ctx, query)
+	return r.listInvoices(ctx, query)
+}
+
+func (r *PostgresRepository) listInvoices(ctx context.Context, query string, args ...interface{}) ([]*invoice.Invoice, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -157,34 +453,65 @@ func (r *PostgresRepository) List(ctx context.Context) ([]*invoice.Invoice, erro
 
 	invoices := make([]*invoice.Invoice, 0)
 	for rows.Next() {
-		inv := &invoice.Invoice{}
-		if err := rows.Scan(&inv.ID, &inv.Number, &inv.Status, &inv.CreatedAt, &inv.ClosedAt, &inv.TotalValue); err != nil {
-			return nil, err
-		}
-
-		itemsQuery := `
-			SELECT id, product_id, quantity, price, name
-			FROM invoice_items
-			WHERE invoice_id = $1`
-
-		itemRows, err := r.db.QueryContext(ctx, itemsQuery, inv.ID)
+		inv, err := scanInvoice(rows)
 		if err != nil {
 			return nil, err
 		}
 
-		inv.Items = make([]*invoice.InvoiceItem, 0)
-		for itemRows.Next() {
-			item := &invoice.InvoiceItem{InvoiceID: inv.ID}
-			if err := itemRows.Scan(&item.ID, &item.ProductID, &item.Quantity, &item.Price, &item.Name); err != nil {
-				itemRows.Close()
-				return nil, err
-			}
-			inv.Items = append(inv.Items, item)
+		if err := r.loadItems(ctx, inv); err != nil {
+			return nil, err
 		}
-		itemRows.Close()
 
 		invoices = append(invoices, inv)
 	}
 
 	return invoices, nil
 }
+
+const invoiceColumns = `id, number, COALESCE(series_code, ''), period, sequence, document_number, status, currency, created_at, closed_at, total_value, COALESCE(customer_id, 0), customer_snapshot, payment_terms, version`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanInvoice(row rowScanner) (*invoice.Invoice, error) {
+	inv := &invoice.Invoice{}
+	var snapshot []byte
+	var total money.Literal
+	err := row.Scan(
+		&inv.ID, &inv.Number, &inv.Series, &inv.Period, &inv.Sequence, &inv.DocumentNumber, &inv.Status, &inv.Currency, &inv.CreatedAt, &inv.ClosedAt, &total, &inv.CustomerID, &snapshot, &inv.PaymentTerms, &inv.Version,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if inv.TotalValue, err = total.In(inv.Currency); err != nil {
+		return nil, err
+	}
+
+	if snapshot != nil {
+		inv.Customer = &invoice.CustomerSnapshot{}
+		if err := json.Unmarshal(snapshot, inv.Customer); err != nil {
+			return nil, err
+		}
+	}
+	return inv, nil
+}
+
+func marshalSnapshot(snapshot *invoice.CustomerSnapshot) (interface{}, error) {
+	if snapshot == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func nullableID(id int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(id), Valid: id != 0}
+}
+
+func nullableCode(code string) sql.NullString {
+	return sql.NullString{String: code, Valid: code != ""}
+}
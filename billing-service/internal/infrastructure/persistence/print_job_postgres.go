@@ -0,0 +1,156 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/printjob"
+)
+
+type PostgresPrintJobRepository struct {
+	db *sql.DB
+}
+
+func NewPrintJobRepository(db *sql.DB) printjob.Repository {
+	return &PostgresPrintJobRepository{db: db}
+}
+
+const printJobColumns = `id, invoice_id, status, step_reached, failed_reason, recovery, attempts, lease_until, available_at, created_at, started_at, finished_at`
+
+func (r *PostgresPrintJobRepository) Create(ctx context.Context, job *printjob.Job) error {
+	query := `
+        INSERT INTO print_jobs (invoice_id, status, available_at, created_at)
+        VALUES ($1, $2, $3, $4)
+        RETURNING id`
+
+	err := r.db.QueryRowContext(ctx, query, job.InvoiceID, job.Status, job.AvailableAt, job.CreatedAt).Scan(&job.ID)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		return printjob.ErrAlreadyQueued
+	}
+	return err
+}
+
+func (r *PostgresPrintJobRepository) Get(ctx context.Context, id int) (*printjob.Job, error) {
+	query := `SELECT ` + printJobColumns + ` FROM print_jobs WHERE id = $1`
+
+	job, err := scanPrintJob(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, printjob.ErrNotFound
+	}
+	return job, err
+}
+
+func (r *PostgresPrintJobRepository) FindUnfinishedByInvoice(ctx context.Context, invoiceID int) (*printjob.Job, error) {
+	query := `
+        SELECT ` + printJobColumns + `
+        FROM print_jobs
+        WHERE invoice_id = $1 AND status IN ($2, $3)
+        ORDER BY id DESC
+        LIMIT 1`
+
+	job, err := scanPrintJob(r.db.QueryRowContext(ctx, query, invoiceID, printjob.StatusQueued, printjob.StatusRunning))
+	if err == sql.ErrNoRows {
+		return nil, printjob.ErrNotFound
+	}
+	return job, err
+}
+
+func (r *PostgresPrintJobRepository) Claim(ctx context.Context, now, leaseUntil time.Time) (*printjob.Job, error) {
+	query := `
+        UPDATE print_jobs
+        SET status = $1, started_at = $2, lease_until = $3, attempts = attempts + 1
+        WHERE id = (
+            SELECT id FROM print_jobs
+            WHERE status = $4 AND available_at <= $2
+            ORDER BY available_at, created_at, id
+            LIMIT 1
+            FOR UPDATE SKIP LOCKED)
+        RETURNING ` + printJobColumns
+
+	job, err := scanPrintJob(r.db.QueryRowContext(ctx, query, printjob.StatusRunning, now, leaseUntil, printjob.StatusQueued))
+	if err == sql.ErrNoRows {
+		return nil, printjob.ErrNotFound
+	}
+	return job, err
+}
+
+func (r *PostgresPrintJobRepository) RenewLease(ctx context.Context, id, attempt int, until time.Time) error {
+	query := `
+        UPDATE print_jobs
+        SET lease_until = $1
+        WHERE id = $2 AND attempts = $3 AND status = $4`
+
+	result, err := r.db.ExecContext(ctx, query, until, id, attempt, printjob.StatusRunning)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return printjob.ErrNotFound
+	}
+	return nil
+}
+
+// Release only touches the job while the claim that ran it still holds it:
+// once its lease expired the job may have been taken by another worker.
+func (r *PostgresPrintJobRepository) Release(ctx context.Context, job *printjob.Job) error {
+	recovery, err := json.Marshal(job.Recovery)
+	if err != nil {
+		return err
+	}
+
+	query := `
+        UPDATE print_jobs
+        SET status = $1, step_reached = $2, failed_reason = $3, recovery = $4, finished_at = $5,
+            available_at = $6, lease_until = NULL
+        WHERE id = $7 AND attempts = $8 AND status = $9`
+
+	result, err := r.db.ExecContext(ctx, query,
+		job.Status, job.StepReached, job.FailedReason, string(recovery), job.FinishedAt,
+		job.AvailableAt, job.ID, job.Attempts, printjob.StatusRunning,
+	)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return printjob.ErrNotFound
+	}
+	return nil
+}
+
+func (r *PostgresPrintJobRepository) RequeueExpired(ctx context.Context, now time.Time) (int, error) {
+	query := `
+        UPDATE print_jobs
+        SET status = $1, available_at = $2, lease_until = NULL
+        WHERE status = $3 AND lease_until < $2`
+
+	result, err := r.db.ExecContext(ctx, query, printjob.StatusQueued, now, printjob.StatusRunning)
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	return int(n), err
+}
+
+func scanPrintJob(row rowScanner) (*printjob.Job, error) {
+	job := &printjob.Job{}
+	var recovery []byte
+	err := row.Scan(&job.ID, &job.InvoiceID, &job.Status, &job.StepReached, &job.FailedReason, &recovery,
+		&job.Attempts, &job.LeaseUntil, &job.AvailableAt, &job.CreatedAt, &job.StartedAt, &job.FinishedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if recovery != nil {
+		if err := json.Unmarshal(recovery, &job.Recovery); err != nil {
+			return nil, err
+		}
+	}
+	return job, nil
+}
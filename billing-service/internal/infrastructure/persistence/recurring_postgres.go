@@ -0,0 +1,310 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/recurring"
+)
+
+type PostgresRecurringRepository struct {
+	db *sql.DB
+}
+
+func NewRecurringRepository(db *sql.DB) recurring.Repository {
+	return &PostgresRecurringRepository{db: db}
+}
+
+const scheduleColumns = `id, name, COALESCE(customer_id, 0), series_code, payment_terms, cadence, cron, start_at, end_at, auto_print, active, next_run_at, created_at`
+
+func (r *PostgresRecurringRepository) Create(ctx context.Context, s *recurring.Schedule) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+        INSERT INTO recurring_invoices (name, customer_id, series_code, payment_terms, cadence, cron, start_at, end_at, auto_print, active, next_run_at, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+        RETURNING id`
+
+	err = tx.QueryRowContext(ctx, query,
+		s.Name, nullableID(s.CustomerID), s.Series, s.PaymentTerms, s.Cadence, s.Cron, s.StartAt.UTC(), utcOrNil(s.EndAt),
+		s.AutoPrint, s.Active, utcOrNil(s.NextRunAt), s.CreatedAt,
+	).Scan(&s.ID)
+	if err != nil {
+		return err
+	}
+
+	if err := insertScheduleItems(ctx, tx, s); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *PostgresRecurringRepository) Get(ctx context.Context, id int) (*recurring.Schedule, error) {
+	query := `SELECT ` + scheduleColumns + ` FROM recurring_invoices WHERE id = $1`
+
+	s, err := scanSchedule(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, recurring.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := loadScheduleItems(ctx, r.db, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (r *PostgresRecurringRepository) List(ctx context.Context) ([]*recurring.Schedule, error) {
+	query := `SELECT ` + scheduleColumns + ` FROM recurring_invoices ORDER BY id`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	schedules := make([]*recurring.Schedule, 0)
+	for rows.Next() {
+		s, err := scanSchedule(rows)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, s := range schedules {
+		if err := loadScheduleItems(ctx, r.db, s); err != nil {
+			return nil, err
+		}
+	}
+	return schedules, nil
+}
+
+func (r *PostgresRecurringRepository) Update(ctx context.Context, s *recurring.Schedule) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+        UPDATE recurring_invoices
+        SET name = $1, customer_id = $2, series_code = $3, payment_terms = $4, cadence = $5, cron = $6,
+            start_at = $7, end_at = $8, auto_print = $9, active = $10, next_run_at = $11
+        WHERE id = $12`
+
+	result, err := tx.ExecContext(ctx, query,
+		s.Name, nullableID(s.CustomerID), s.Series, s.PaymentTerms, s.Cadence, s.Cron,
+		s.StartAt.UTC(), utcOrNil(s.EndAt), s.AutoPrint, s.Active, utcOrNil(s.NextRunAt), s.ID,
+	)
+	if err != nil {
+		return err
+	}
+	if err := expectRow(result, recurring.ErrNotFound); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM recurring_invoice_items WHERE recurring_invoice_id = $1`, s.ID); err != nil {
+		return err
+	}
+	if err := insertScheduleItems(ctx, tx, s); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *PostgresRecurringRepository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration) (*recurring.Schedule, *recurring.Run, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+        SELECT ` + scheduleColumns + `
+        FROM recurring_invoices
+        WHERE active AND next_run_at <= $1
+        ORDER BY next_run_at, id
+        LIMIT 1
+        FOR UPDATE SKIP LOCKED`
+
+	s, err := scanSchedule(tx.QueryRowContext(ctx, query, now.UTC()))
+	if err == sql.ErrNoRows {
+		return nil, nil, recurring.ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := loadScheduleItems(ctx, tx, s); err != nil {
+		return nil, nil, err
+	}
+
+	run := recurring.NewRun(s.ID, *s.NextRunAt, lease)
+	s.Advance()
+
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE recurring_invoices SET next_run_at = $1 WHERE id = $2`, utcOrNil(s.NextRunAt), s.ID,
+	); err != nil {
+		return nil, nil, err
+	}
+
+	runQuery := `
+        INSERT INTO recurring_invoice_runs (recurring_invoice_id, scheduled_for, status, started_at, lease_until)
+        VALUES ($1, $2, $3, $4, $5)
+        RETURNING id`
+
+	if err := tx.QueryRowContext(ctx, runQuery,
+		run.ScheduleID, run.ScheduledFor.UTC(), run.Status, run.StartedAt, run.LeaseUntil,
+	).Scan(&run.ID); err != nil {
+		return nil, nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, err
+	}
+	return s, run, nil
+}
+
+func (r *PostgresRecurringRepository) RenewRun(ctx context.Context, runID int, until time.Time) error {
+	query := `
+        UPDATE recurring_invoice_runs
+        SET lease_until = $1
+        WHERE id = $2 AND status = $3`
+
+	result, err := r.db.ExecContext(ctx, query, until, runID, recurring.RunRunning)
+	if err != nil {
+		return err
+	}
+	return expectRow(result, recurring.ErrRunLost)
+}
+
+func (r *PostgresRecurringRepository) FinishRun(ctx context.Context, run *recurring.Run) error {
+	query := `
+        UPDATE recurring_invoice_runs
+        SET status = $1, invoice_id = $2, printed = $3, step = $4, error = $5, finished_at = $6
+        WHERE id = $7 AND status = $8`
+
+	result, err := r.db.ExecContext(ctx, query,
+		run.Status, nullableID(run.InvoiceID), run.Printed, run.Step, run.Error, run.FinishedAt, run.ID, recurring.RunRunning,
+	)
+	if err != nil {
+		return err
+	}
+	return expectRow(result, recurring.ErrRunLost)
+}
+
+func (r *PostgresRecurringRepository) ListRuns(ctx context.Context, scheduleID int) ([]*recurring.Run, error) {
+	query := `
+        SELECT id, recurring_invoice_id, scheduled_for, status, COALESCE(invoice_id, 0), printed, step, error, started_at, finished_at, lease_until
+        FROM recurring_invoice_runs
+        WHERE recurring_invoice_id = $1
+        ORDER BY scheduled_for DESC, id DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, scheduleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	runs := make([]*recurring.Run, 0)
+	for rows.Next() {
+		run := &recurring.Run{}
+		err := rows.Scan(&run.ID, &run.ScheduleID, &run.ScheduledFor, &run.Status, &run.InvoiceID,
+			&run.Printed, &run.Step, &run.Error, &run.StartedAt, &run.FinishedAt, &run.LeaseUntil)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
+
+func (r *PostgresRecurringRepository) AbandonRuns(ctx context.Context, now time.Time, reason string) (int, error) {
+	query := `
+        UPDATE recurring_invoice_runs
+        SET status = $1, error = $2, finished_at = $3
+        WHERE status = $4 AND lease_until < $3`
+
+	result, err := r.db.ExecContext(ctx, query, recurring.RunFailed, reason, now, recurring.RunRunning)
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	return int(n), err
+}
+
+// queryer is what loading needs from either the database or a transaction.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func loadScheduleItems(ctx context.Context, q queryer, s *recurring.Schedule) error {
+	rows, err := q.QueryContext(ctx, `
+        SELECT product_id, quantity
+        FROM recurring_invoice_items
+        WHERE recurring_invoice_id = $1
+        ORDER BY id`, s.ID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	s.Items = make([]recurring.Item, 0)
+	for rows.Next() {
+		var item recurring.Item
+		if err := rows.Scan(&item.ProductID, &item.Quantity); err != nil {
+			return err
+		}
+		s.Items = append(s.Items, item)
+	}
+	return rows.Err()
+}
+
+func insertScheduleItems(ctx context.Context, tx *sql.Tx, s *recurring.Schedule) error {
+	for _, item := range s.Items {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO recurring_invoice_items (recurring_invoice_id, product_id, quantity) VALUES ($1, $2, $3)`,
+			s.ID, item.ProductID, item.Quantity,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func scanSchedule(row rowScanner) (*recurring.Schedule, error) {
+	s := &recurring.Schedule{}
+	err := row.Scan(
+		&s.ID, &s.Name, &s.CustomerID, &s.Series, &s.PaymentTerms, &s.Cadence, &s.Cron, &s.StartAt, &s.EndAt,
+		&s.AutoPrint, &s.Active, &s.NextRunAt, &s.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// TIMESTAMP columns hold UTC
+	s.StartAt = s.StartAt.UTC()
+	if err := s.Restore(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func utcOrNil(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
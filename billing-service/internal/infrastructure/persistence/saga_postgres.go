@@ -0,0 +1,169 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/saga"
+)
+
+type PostgresSagaRepository struct {
+	db *sql.DB
+}
+
+func NewSagaRepository(db *sql.DB) saga.Repository {
+	return &PostgresSagaRepository{db: db}
+}
+
+func (r *PostgresSagaRepository) Create(ctx context.Context, s *saga.Saga) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+        INSERT INTO print_sagas (invoice_id, status, step_reached, failed_reason, created_at, updated_at)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        RETURNING id`
+
+	err = tx.QueryRowContext(ctx, query,
+		s.InvoiceID, s.Status, s.StepReached, s.FailedReason, s.CreatedAt, s.UpdatedAt,
+	).Scan(&s.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, step := range s.Steps {
+		step.SagaID = s.ID
+		step.UpdatedAt = s.UpdatedAt
+		query := `
+            INSERT INTO print_saga_steps (saga_id, seq, action, compensation, product_id, quantity, reservation_id, status, error, updated_at)
+            VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+            RETURNING id`
+
+		err = tx.QueryRowContext(ctx, query,
+			step.SagaID, step.Seq, step.Action, step.Compensation, step.ProductID, step.Quantity,
+			step.ReservationID, step.Status, step.Error, step.UpdatedAt,
+		).Scan(&step.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *PostgresSagaRepository) Update(ctx context.Context, s *saga.Saga) error {
+	s.UpdatedAt = time.Now()
+	query := `
+        UPDATE print_sagas
+        SET status = $1, step_reached = $2, failed_reason = $3, updated_at = $4
+        WHERE id = $5`
+
+	_, err := r.db.ExecContext(ctx, query,
+		s.Status, s.StepReached, s.FailedReason, s.UpdatedAt, s.ID,
+	)
+	return err
+}
+
+func (r *PostgresSagaRepository) UpdateStep(ctx context.Context, step *saga.Step) error {
+	step.UpdatedAt = time.Now()
+	query := `
+        UPDATE print_saga_steps
+        SET status = $1, error = $2, reservation_id = $3, updated_at = $4
+        WHERE id = $5`
+
+	_, err := r.db.ExecContext(ctx, query,
+		step.Status, step.Error, step.ReservationID, step.UpdatedAt, step.ID,
+	)
+	return err
+}
+
+func (r *PostgresSagaRepository) FindUnfinishedByInvoice(ctx context.Context, invoiceID int) (*saga.Saga, error) {
+	query := `
+        SELECT id, invoice_id, status, step_reached, failed_reason, created_at, updated_at
+        FROM print_sagas
+        WHERE invoice_id = $1 AND status = $2
+        ORDER BY id DESC
+        LIMIT 1`
+
+	s := &saga.Saga{}
+	err := r.db.QueryRowContext(ctx, query, invoiceID, saga.StatusRunning).Scan(
+		&s.ID, &s.InvoiceID, &s.Status, &s.StepReached, &s.FailedReason, &s.CreatedAt, &s.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, saga.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.loadSteps(ctx, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (r *PostgresSagaRepository) ListUnfinished(ctx context.Context) ([]*saga.Saga, error) {
+	query := `
+        SELECT id, invoice_id, status, step_reached, failed_reason, created_at, updated_at
+        FROM print_sagas
+        WHERE status = $1
+        ORDER BY id`
+
+	rows, err := r.db.QueryContext(ctx, query, saga.StatusRunning)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sagas := make([]*saga.Saga, 0)
+	for rows.Next() {
+		s := &saga.Saga{}
+		if err := rows.Scan(
+			&s.ID, &s.InvoiceID, &s.Status, &s.StepReached, &s.FailedReason, &s.CreatedAt, &s.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		sagas = append(sagas, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, s := range sagas {
+		if err := r.loadSteps(ctx, s); err != nil {
+			return nil, err
+		}
+	}
+	return sagas, nil
+}
+
+func (r *PostgresSagaRepository) loadSteps(ctx context.Context, s *saga.Saga) error {
+	query := `
+        SELECT id, seq, action, compensation, product_id, quantity, reservation_id, status, error, updated_at
+        FROM print_saga_steps
+        WHERE saga_id = $1
+        ORDER BY seq`
+
+	rows, err := r.db.QueryContext(ctx, query, s.ID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	s.Steps = make([]*saga.Step, 0)
+	for rows.Next() {
+		step := &saga.Step{SagaID: s.ID}
+		if err := rows.Scan(
+			&step.ID, &step.Seq, &step.Action, &step.Compensation, &step.ProductID, &step.Quantity,
+			&step.ReservationID, &step.Status, &step.Error, &step.UpdatedAt,
+		); err != nil {
+			return err
+		}
+		s.Steps = append(s.Steps, step)
+	}
+	return rows.Err()
+}
@@ -0,0 +1,127 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/invoice"
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/numbering"
+)
+
+type PostgresSeriesRepository struct {
+	db *sql.DB
+}
+
+func NewSeriesRepository(db *sql.DB) numbering.Repository {
+	return &PostgresSeriesRepository{db: db}
+}
+
+func (r *PostgresSeriesRepository) Create(ctx context.Context, s *numbering.Series) error {
+	query := `
+        INSERT INTO invoice_series (code, prefix, padding, reset_yearly, created_at)
+        VALUES ($1, $2, $3, $4, $5)`
+
+	_, err := r.db.ExecContext(ctx, query, s.Code, s.Prefix, s.Padding, s.ResetYearly, s.CreatedAt)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		return numbering.ErrDuplicateCode
+	}
+	return err
+}
+
+func (r *PostgresSeriesRepository) Get(ctx context.Context, code string) (*numbering.Series, error) {
+	query := `
+        SELECT code, prefix, padding, reset_yearly, created_at
+        FROM invoice_series
+        WHERE code = $1`
+
+	s := &numbering.Series{}
+	err := r.db.QueryRowContext(ctx, query, code).Scan(&s.Code, &s.Prefix, &s.Padding, &s.ResetYearly, &s.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, numbering.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (r *PostgresSeriesRepository) List(ctx context.Context) ([]*numbering.Series, error) {
+	query := `
+        SELECT code, prefix, padding, reset_yearly, created_at
+        FROM invoice_series
+        ORDER BY code`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	series := make([]*numbering.Series, 0)
+	for rows.Next() {
+		s := &numbering.Series{}
+		if err := rows.Scan(&s.Code, &s.Prefix, &s.Padding, &s.ResetYearly, &s.CreatedAt); err != nil {
+			return nil, err
+		}
+		series = append(series, s)
+	}
+	return series, rows.Err()
+}
+
+// allocateNumber assigns the invoice its place in the series. The counter rows
+// stay locked until the caller's transaction ends, which serialises
+// concurrent inserts and rolls the counters back together with a failed one.
+//
+// Explicit numbers are checked against the series format and must be the
+// next number of the series, so the series never has gaps.
+func allocateNumber(ctx context.Context, tx *sql.Tx, inv *invoice.Invoice, s *numbering.Series) error {
+	inv.Series = s.Code
+
+	if inv.Number != "" {
+		period, sequence, err := s.Parse(inv.Number)
+		if err != nil {
+			return err
+		}
+
+		next, err := nextSequence(ctx, tx, s.Code, period)
+		if err != nil {
+			return err
+		}
+		if sequence != next {
+			return numbering.ErrOutOfSequence
+		}
+		inv.Period, inv.Sequence = period, sequence
+	} else {
+		inv.Period = s.Period(inv.CreatedAt)
+		sequence, err := nextSequence(ctx, tx, s.Code, inv.Period)
+		if err != nil {
+			return err
+		}
+		inv.Sequence = sequence
+		inv.Number = s.Format(inv.Period, inv.Sequence)
+	}
+
+	query := `
+        UPDATE invoice_series
+        SET last_document_number = last_document_number + 1
+        WHERE code = $1
+        RETURNING last_document_number`
+
+	return tx.QueryRowContext(ctx, query, s.Code).Scan(&inv.DocumentNumber)
+}
+
+// nextSequence moves the counter of the period one step forward and returns
+// the new value.
+func nextSequence(ctx context.Context, tx *sql.Tx, code string, period int) (int, error) {
+	query := `
+        INSERT INTO invoice_series_counters (series_code, period, last_value)
+        VALUES ($1, $2, 1)
+        ON CONFLICT (series_code, period) DO UPDATE
+        SET last_value = invoice_series_counters.last_value + 1
+        RETURNING last_value`
+
+	var sequence int
+	err := tx.QueryRowContext(ctx, query, code, period).Scan(&sequence)
+	return sequence, err
+}
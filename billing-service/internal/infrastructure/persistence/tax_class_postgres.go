@@ -0,0 +1,48 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/tax"
+)
+
+type PostgresTaxClassRepository struct {
+	db *sql.DB
+}
+
+func NewTaxClassRepository(db *sql.DB) tax.ClassRepository {
+	return &PostgresTaxClassRepository{db: db}
+}
+
+func (r *PostgresTaxClassRepository) Get(ctx context.Context, productID int) (*tax.Class, error) {
+	query := `
+        SELECT product_id, ncm, ipi_rate, exempt, updated_at
+        FROM product_tax_classes
+        WHERE product_id = $1`
+
+	class := &tax.Class{}
+	err := r.db.QueryRowContext(ctx, query, productID).Scan(
+		&class.ProductID, &class.NCM, &class.IPIRate, &class.Exempt, &class.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, tax.ErrClassNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return class, nil
+}
+
+func (r *PostgresTaxClassRepository) Save(ctx context.Context, class *tax.Class) error {
+	query := `
+        INSERT INTO product_tax_classes (product_id, ncm, ipi_rate, exempt, updated_at)
+        VALUES ($1, $2, $3, $4, $5)
+        ON CONFLICT (product_id) DO UPDATE
+        SET ncm = EXCLUDED.ncm, ipi_rate = EXCLUDED.ipi_rate, exempt = EXCLUDED.exempt, updated_at = EXCLUDED.updated_at`
+
+	_, err := r.db.ExecContext(ctx, query,
+		class.ProductID, class.NCM, class.IPIRate, class.Exempt, class.UpdatedAt,
+	)
+	return err
+}
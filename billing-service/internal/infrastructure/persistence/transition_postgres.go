@@ -0,0 +1,54 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/vitorwhois/microservice-invoice-billing/billing-service/internal/domain/invoice"
+)
+
+// insertTransitions appends status changes to the invoice history inside the
+// caller's transaction.
+func insertTransitions(ctx context.Context, tx *sql.Tx, invoiceID int, transitions []invoice.Transition) error {
+	query := `
+        INSERT INTO invoice_status_transitions (invoice_id, from_status, to_status, reason, occurred_at)
+        VALUES ($1, $2, $3, $4, $5)`
+
+	for _, t := range transitions {
+		if _, err := tx.ExecContext(ctx, query,
+			invoiceID, t.From, t.To, t.Reason, t.OccurredAt,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *PostgresRepository) ListTransitions(ctx context.Context, invoiceID int) ([]*invoice.Transition, error) {
+	query := `
+        SELECT id, invoice_id, from_status, to_status, reason, occurred_at
+        FROM invoice_status_transitions
+        WHERE invoice_id = $1
+        ORDER BY id`
+
+	rows, err := r.db.QueryContext(ctx, query, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transitions := make([]*invoice.Transition, 0)
+	for rows.Next() {
+		t := &invoice.Transition{}
+		if err := rows.Scan(&t.ID, &t.InvoiceID, &t.From, &t.To, &t.Reason, &t.OccurredAt); err != nil {
+			return nil, err
+		}
+		transitions = append(transitions, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return transitions, nil
+}
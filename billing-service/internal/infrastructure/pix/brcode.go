@@ -0,0 +1,212 @@
+// Package pix builds PIX BR Codes: the EMV-MPM payload that payment apps read
+// from a QR code or paste as "copia e cola", following the Manual de Padrões
+// para Iniciação do Pix of the Central Bank.
+package pix
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/vitorwhois/microservice-invoice-billing/money"
+)
+
+var ErrInvalidCharge = errors.New("invalid PIX charge")
+
+const (
+	gui             = "br.gov.bcb.pix"
+	currencyBRL     = "986"
+	countryBR       = "BR"
+	noCategory      = "0000"
+	unspecifiedTxID = "***"
+
+	maxNameLength        = 25
+	maxCityLength        = 15
+	maxStaticTxIDLength  = 25
+	maxDescriptionLength = 72
+	// maxFieldLength is the most an EMV field can hold: its length has two
+	// digits
+	maxFieldLength = 99
+)
+
+// EMV tags of the BR Code.
+const (
+	tagFormatIndicator  = "00"
+	tagInitiationMethod = "01"
+	tagMerchantAccount  = "26"
+	tagCategoryCode     = "52"
+	tagCurrency         = "53"
+	tagAmount           = "54"
+	tagCountry          = "58"
+	tagMerchantName     = "59"
+	tagMerchantCity     = "60"
+	tagAdditionalData   = "62"
+	tagCRC              = "63"
+
+	tagAccountGUI         = "00"
+	tagAccountKey         = "01"
+	tagAccountDescription = "02"
+	tagAccountURL         = "25"
+	tagAdditionalTxID     = "05"
+)
+
+// Charge is one payment request. A charge with a Location URL is dynamic: the
+// payer's bank fetches the amount and details from the PSP at that URL.
+// Otherwise it is static and carries the key and amount itself.
+type Charge struct {
+	Key          string
+	Location     string
+	MerchantName string
+	MerchantCity string
+	Amount       money.Money
+	TxID         string
+	Description  string
+}
+
+func (c Charge) Dynamic() bool {
+	return c.Location != ""
+}
+
+// Payload returns the BR Code, ending with its CRC16 checksum.
+func (c Charge) Payload() (string, error) {
+	if (c.Key == "" && !c.Dynamic()) || c.MerchantName == "" || c.MerchantCity == "" {
+		return "", ErrInvalidCharge
+	}
+	if c.Amount.IsNegative() || (c.Amount.Currency != "" && c.Amount.Currency != "BRL") {
+		return "", ErrInvalidCharge
+	}
+
+	account := &emv{}
+	account.field(tagAccountGUI, gui)
+	if c.Dynamic() {
+		account.field(tagAccountURL, strings.TrimPrefix(strings.TrimPrefix(c.Location, "https://"), "http://"))
+	} else {
+		account.field(tagAccountKey, c.Key)
+		// The description gets whatever room the key leaves in the account
+		// field, and is dropped when there is none
+		room := min(maxDescriptionLength, maxFieldLength-account.b.Len()-4)
+		if description := truncate(ascii(c.Description), room); description != "" {
+			account.field(tagAccountDescription, description)
+		}
+	}
+	if account.err != nil {
+		return "", account.err
+	}
+
+	// The txid of a dynamic charge lives behind the location URL
+	txid := unspecifiedTxID
+	if !c.Dynamic() {
+		if id := truncate(alphanumeric(c.TxID), maxStaticTxIDLength); id != "" {
+			txid = id
+		}
+	}
+
+	additional := &emv{}
+	additional.field(tagAdditionalTxID, txid)
+
+	code := &emv{}
+	code.field(tagFormatIndicator, "01")
+	// 12: the code is meant to be paid once
+	code.field(tagInitiationMethod, "12")
+	code.field(tagMerchantAccount, account.b.String())
+	code.field(tagCategoryCode, noCategory)
+	code.field(tagCurrency, currencyBRL)
+	if c.Amount.IsPositive() {
+		code.field(tagAmount, c.Amount.Decimal())
+	}
+	code.field(tagCountry, countryBR)
+	code.field(tagMerchantName, truncate(ascii(c.MerchantName), maxNameLength))
+	code.field(tagMerchantCity, truncate(ascii(c.MerchantCity), maxCityLength))
+	code.field(tagAdditionalData, additional.b.String())
+	if code.err != nil {
+		return "", code.err
+	}
+
+	// The checksum covers everything up to and including its own tag and length
+	code.b.WriteString(tagCRC + "04")
+	code.b.WriteString(fmt.Sprintf("%04X", crc16(code.b.String())))
+	return code.b.String(), nil
+}
+
+// emv writes tag-length-value fields, keeping the first value too long to
+// be written as its error.
+type emv struct {
+	b   strings.Builder
+	err error
+}
+
+func (e *emv) field(tag, value string) {
+	if e.err != nil {
+		return
+	}
+	if len(value) > maxFieldLength {
+		e.err = fmt.Errorf("%w: field %s is %d characters long, the limit is %d", ErrInvalidCharge, tag, len(value), maxFieldLength)
+		return
+	}
+	fmt.Fprintf(&e.b, "%s%02d%s", tag, len(value), value)
+}
+
+// crc16 is CRC-16/CCITT-FALSE: polynomial 0x1021, initial value 0xFFFF.
+func crc16(s string) uint16 {
+	crc := uint16(0xFFFF)
+	for i := 0; i < len(s); i++ {
+		crc ^= uint16(s[i]) << 8
+		for bit := 0; bit < 8; bit++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+func truncate(s string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	if len(s) > n {
+		return strings.TrimSpace(s[:n])
+	}
+	return s
+}
+
+func alphanumeric(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if (r >= '0' && r <= '9') || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// ascii drops the accents of Portuguese text, since payment apps are only
+// required to read plain ASCII, and removes any other non-ASCII character.
+func ascii(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if plain, ok := unaccented[r]; ok {
+			r = plain
+		}
+		if r >= 0x20 && r < 0x7F {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+var unaccented = map[rune]rune{
+	'á': 'a', 'à': 'a', 'â': 'a', 'ã': 'a', 'ä': 'a',
+	'Á': 'A', 'À': 'A', 'Â': 'A', 'Ã': 'A', 'Ä': 'A',
+	'é': 'e', 'è': 'e', 'ê': 'e', 'ë': 'e',
+	'É': 'E', 'È': 'E', 'Ê': 'E', 'Ë': 'E',
+	'í': 'i', 'ì': 'i', 'î': 'i', 'ï': 'i',
+	'Í': 'I', 'Ì': 'I', 'Î': 'I', 'Ï': 'I',
+	'ó': 'o', 'ò': 'o', 'ô': 'o', 'õ': 'o', 'ö': 'o',
+	'Ó': 'O', 'Ò': 'O', 'Ô': 'O', 'Õ': 'O', 'Ö': 'O',
+	'ú': 'u', 'ù': 'u', 'û': 'u', 'ü': 'u',
+	'Ú': 'U', 'Ù': 'U', 'Û': 'U', 'Ü': 'U',
+	'ç': 'c', 'Ç': 'C', 'ñ': 'n', 'Ñ': 'N',
+}
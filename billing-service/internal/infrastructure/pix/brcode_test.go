@@ -0,0 +1,171 @@
+package pix
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/vitorwhois/microservice-invoice-billing/money"
+)
+
+// bcbExample is the static BR Code given as example in the Manual de Padrões
+// para Iniciação do Pix.
+const bcbExample = "00020126580014br.gov.bcb.pix0136123e4567-e12b-12d1-a456-4266554400005204000053039865802BR5913Fulano de Tal6008BRASILIA62070503***63041D3D"
+
+func TestCRC16(t *testing.T) {
+	tests := []struct {
+		input string
+		want  uint16
+	}{
+		// Check value of CRC-16/CCITT-FALSE
+		{"123456789", 0x29B1},
+		{bcbExample[:len(bcbExample)-4], 0x1D3D},
+	}
+	for _, tt := range tests {
+		if got := crc16(tt.input); got != tt.want {
+			t.Errorf("crc16(%q) = %04X, want %04X", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestPayloadMatchesBCBExample(t *testing.T) {
+	got, err := Charge{
+		Key:          "123e4567-e12b-12d1-a456-426655440000",
+		MerchantName: "Fulano de Tal",
+		MerchantCity: "BRASILIA",
+	}.Payload()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Same fields as the example, plus the point of initiation method
+	// marking the code as single use
+	want := "000201" + "010212" + bcbExample[6:len(bcbExample)-4]
+	want += fmt.Sprintf("%04X", crc16(want))
+	if got != want {
+		t.Fatalf("got  %s\nwant %s", got, want)
+	}
+}
+
+func TestPayloadFields(t *testing.T) {
+	tests := []struct {
+		name   string
+		charge Charge
+		want   map[string]string
+	}{
+		{
+			name: "static with amount and txid",
+			charge: Charge{
+				Key:          "pagamentos@loja.com.br",
+				MerchantName: "Loja de Conveniência São João",
+				MerchantCity: "São José dos Campos",
+				Amount:       money.MustParse("1234.50", "BRL"),
+				TxID:         "INV-2026-000042",
+				Description:  "Fatura INV-2026-000042",
+			},
+			want: map[string]string{
+				tagAmount:         "1234.50",
+				tagMerchantName:   "Loja de Conveniencia Sao",
+				tagMerchantCity:   "Sao Jose dos Ca",
+				tagAdditionalData: "0513INV2026000042",
+				tagMerchantAccount: "0014br.gov.bcb.pix0122pagamentos@loja.com.br" +
+					"0222Fatura INV-2026-000042",
+			},
+		},
+		{
+			name: "dynamic",
+			charge: Charge{
+				Location:     "https://pix.psp.example.com/qr/v2/9d36b84fc70b478fb95c12729b90ca25",
+				MerchantName: "Loja",
+				MerchantCity: "Sao Paulo",
+				Amount:       money.MustParse("10.00", "BRL"),
+			},
+			want: map[string]string{
+				tagMerchantAccount: "0014br.gov.bcb.pix2558pix.psp.example.com/qr/v2/9d36b84fc70b478fb95c12729b90ca25",
+				tagAdditionalData:  "0503***",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload, err := tt.charge.Payload()
+			if err != nil {
+				t.Fatal(err)
+			}
+			fields := parse(t, payload)
+			for tag, want := range tt.want {
+				if fields[tag] != want {
+					t.Errorf("field %s = %q, want %q", tag, fields[tag], want)
+				}
+			}
+		})
+	}
+}
+
+func TestPayloadTrimsDescriptionToFit(t *testing.T) {
+	key := strings.Repeat("k", 50) + "@example.com"
+	payload, err := Charge{
+		Key:          key,
+		MerchantName: "Loja",
+		MerchantCity: "Sao Paulo",
+		Description:  strings.Repeat("Pagamento da fatura ", 5),
+	}.Payload()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	account := parse(t, payload)[tagMerchantAccount]
+	if len(account) != maxFieldLength {
+		t.Fatalf("account field is %d characters long, want it filled up to %d", len(account), maxFieldLength)
+	}
+	if !strings.Contains(account, "0211Pagamento d") {
+		t.Fatalf("description not trimmed to the room left: %q", account)
+	}
+}
+
+func TestPayloadRejectsFieldsTooLong(t *testing.T) {
+	charges := map[string]Charge{
+		"key": {
+			Key:          strings.Repeat("k", 90),
+			MerchantName: "Loja",
+			MerchantCity: "Sao Paulo",
+		},
+		"location": {
+			Location:     "https://pix.example.com/" + strings.Repeat("a", 80),
+			MerchantName: "Loja",
+			MerchantCity: "Sao Paulo",
+		},
+	}
+	for name, charge := range charges {
+		if _, err := charge.Payload(); !errors.Is(err, ErrInvalidCharge) {
+			t.Errorf("%s: got %v, want ErrInvalidCharge", name, err)
+		}
+	}
+}
+
+// parse splits a BR Code into its top-level fields, checking every length
+// and the CRC on the way.
+func parse(t *testing.T, payload string) map[string]string {
+	t.Helper()
+
+	if got, want := payload[len(payload)-4:], fmt.Sprintf("%04X", crc16(payload[:len(payload)-4])); got != want {
+		t.Fatalf("CRC is %s, want %s", got, want)
+	}
+
+	fields := make(map[string]string)
+	for rest := payload; rest != ""; {
+		if len(rest) < 4 {
+			t.Fatalf("truncated field %q", rest)
+		}
+		n, err := strconv.Atoi(rest[2:4])
+		if err != nil || len(rest) < 4+n {
+			t.Fatalf("bad length in %q", rest)
+		}
+		fields[rest[:2]] = rest[4 : 4+n]
+		rest = rest[4+n:]
+	}
+	return fields
+}
@@ -0,0 +1,33 @@
+package pix
+
+import (
+	"strings"
+
+	"github.com/vitorwhois/microservice-invoice-billing/money"
+)
+
+// Receiver is the account invoices are paid into. With a LocationTemplate the
+// charges are dynamic: "{txid}" in the template is replaced by the charge's
+// txid, and the PSP serving that URL must know about the charge.
+type Receiver struct {
+	Key              string
+	Name             string
+	City             string
+	LocationTemplate string
+}
+
+// Charge builds the charge for amount, identified by txid.
+func (r *Receiver) Charge(txid string, amount money.Money, description string) Charge {
+	c := Charge{
+		Key:          r.Key,
+		MerchantName: r.Name,
+		MerchantCity: r.City,
+		Amount:       amount,
+		TxID:         alphanumeric(txid),
+		Description:  description,
+	}
+	if r.LocationTemplate != "" {
+		c.Location = strings.ReplaceAll(r.LocationTemplate, "{txid}", c.TxID)
+	}
+	return c
+}
@@ -0,0 +1,198 @@
+package qrcode
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// bcbExample is the static PIX BR Code from the Central Bank manual.
+const bcbExample = "00020126580014br.gov.bcb.pix0136123e4567-e12b-12d1-a456-4266554400005204000053039865802BR5913Fulano de Tal6008BRASILIA62070503***63041D3D"
+
+func TestEncodeRoundTrip(t *testing.T) {
+	tests := []struct {
+		data    string
+		level   Level
+		version int
+	}{
+		{"PIX", Low, 1},
+		{"https://pix.example.com/qr/v2/9d36b84fc70b478fb95c12729b90ca25", Medium, 4},
+		{bcbExample, Medium, 8},
+		{strings.Repeat("0123456789", 40), High, 21},
+	}
+
+	for _, tt := range tests {
+		code, err := Encode([]byte(tt.data), tt.level)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code.Version != tt.version || code.Size != 4*tt.version+17 {
+			t.Errorf("%.20q: version %d size %d, want version %d", tt.data, code.Version, code.Size, tt.version)
+		}
+		checkFinders(t, code)
+		if got := decode(t, code); !bytes.Equal(got, []byte(tt.data)) {
+			t.Errorf("decoded %q, want %q", got, tt.data)
+		}
+	}
+}
+
+func TestEncodeTooLong(t *testing.T) {
+	if _, err := Encode(make([]byte, 3000), Low); err != ErrTooLong {
+		t.Fatalf("got %v, want ErrTooLong", err)
+	}
+}
+
+func TestPNG(t *testing.T) {
+	code, err := Encode([]byte(bcbExample), Medium)
+	if err != nil {
+		t.Fatal(err)
+	}
+	png, err := code.PNG(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(png, []byte("\x89PNG\r\n\x1a\n")) {
+		t.Fatalf("not a PNG: % x", png[:8])
+	}
+}
+
+// checkFinders looks for the three 7x7 finder patterns in the corners.
+func checkFinders(t *testing.T, c *Code) {
+	t.Helper()
+	for _, corner := range [][2]int{{0, 0}, {c.Size - 7, 0}, {0, c.Size - 7}} {
+		for dy := 0; dy < 7; dy++ {
+			for dx := 0; dx < 7; dx++ {
+				ring := max(abs(dx-3), abs(dy-3))
+				if want := ring != 2; c.Dark(corner[0]+dx, corner[1]+dy) != want {
+					t.Fatalf("finder at %v broken at %d,%d", corner, dx, dy)
+				}
+			}
+		}
+	}
+}
+
+// decode reads a symbol back the way a scanner does: format information,
+// unmasking, zigzag codeword order, de-interleaving with a Reed-Solomon
+// check of every block and finally the byte mode segment.
+func decode(t *testing.T, c *Code) []byte {
+	t.Helper()
+
+	// First copy of the format information, around the top-left finder
+	var format int
+	for i := 0; i <= 5; i++ {
+		format |= b2i(c.Dark(8, i)) << i
+	}
+	format |= b2i(c.Dark(8, 7)) << 6
+	format |= b2i(c.Dark(8, 8)) << 7
+	format |= b2i(c.Dark(7, 8)) << 8
+	for i := 9; i < 15; i++ {
+		format |= b2i(c.Dark(14-i, 8)) << i
+	}
+	format ^= 0x5412
+	rem := format
+	for i := 14; i >= 10; i-- {
+		if rem>>i&1 == 1 {
+			rem ^= 0x537 << (i - 10)
+		}
+	}
+	if rem != 0 {
+		t.Fatalf("format information %015b fails its BCH check", format)
+	}
+	data := format >> 10
+	if level := data >> 3; level != formatBits[c.Level] {
+		t.Fatalf("format level %d, want %d", level, formatBits[c.Level])
+	}
+	mask := data & 7
+
+	var raw []byte
+	var cur, n int
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < c.Size; vert++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				y := vert
+				if (right+1)&2 == 0 {
+					y = c.Size - 1 - vert
+				}
+				if c.isFunction[y][x] {
+					continue
+				}
+				dark := c.Dark(x, y) != maskInverts(mask, x, y)
+				cur = cur<<1 | b2i(dark)
+				if n++; n%8 == 0 {
+					raw = append(raw, byte(cur))
+					cur = 0
+				}
+			}
+		}
+	}
+
+	numBlocks := eccBlocks[c.Level][c.Version]
+	eccLen := eccCodewordsPerBlock[c.Level][c.Version]
+	rawCodewords := numRawDataModules(c.Version) / 8
+	numShortBlocks := numBlocks - rawCodewords%numBlocks
+	shortLen := rawCodewords/numBlocks - eccLen
+
+	blocks := make([][]byte, numBlocks)
+	k := 0
+	for i := 0; i < shortLen+1; i++ {
+		for j := range blocks {
+			if i < shortLen || j >= numShortBlocks {
+				blocks[j] = append(blocks[j], raw[k])
+				k++
+			}
+		}
+	}
+	var message []byte
+	divisor := rsDivisor(eccLen)
+	for j := range blocks {
+		if !bytes.Equal(rsRemainder(blocks[j], divisor), eccOf(raw, k+j, numBlocks, eccLen)) {
+			t.Fatalf("block %d fails its Reed-Solomon check", j)
+		}
+		message = append(message, blocks[j]...)
+	}
+
+	bits := &reader{data: message}
+	if mode := bits.read(4); mode != modeByte {
+		t.Fatalf("mode %d, want byte mode", mode)
+	}
+	out := make([]byte, bits.read(charCountBits(c.Version)))
+	for i := range out {
+		out[i] = byte(bits.read(8))
+	}
+	return out
+}
+
+// eccOf gathers the interleaved ECC codewords of the block whose first one
+// is at start.
+func eccOf(raw []byte, start, numBlocks, eccLen int) []byte {
+	ecc := make([]byte, eccLen)
+	for i := range ecc {
+		ecc[i] = raw[start+i*numBlocks]
+	}
+	return ecc
+}
+
+type reader struct {
+	data []byte
+	pos  int
+}
+
+func (r *reader) read(n int) int {
+	v := 0
+	for i := 0; i < n; i++ {
+		v = v<<1 | int(r.data[r.pos>>3]>>(7-r.pos&7)&1)
+		r.pos++
+	}
+	return v
+}
+
+func b2i(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
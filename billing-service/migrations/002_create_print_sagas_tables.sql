@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS print_sagas (
+    id SERIAL PRIMARY KEY,
+    invoice_id INTEGER NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    step_reached VARCHAR(50) NOT NULL DEFAULT 'started',
+    failed_reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (invoice_id) REFERENCES invoices(id)
+);
+
+CREATE TABLE IF NOT EXISTS print_saga_steps (
+    id SERIAL PRIMARY KEY,
+    saga_id INTEGER NOT NULL,
+    seq INTEGER NOT NULL,
+    action VARCHAR(50) NOT NULL,
+    compensation VARCHAR(50) NOT NULL DEFAULT '',
+    product_id INTEGER NOT NULL DEFAULT 0,
+    quantity INTEGER NOT NULL DEFAULT 0,
+    status VARCHAR(20) NOT NULL,
+    error TEXT NOT NULL DEFAULT '',
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (saga_id) REFERENCES print_sagas(id),
+    UNIQUE (saga_id, seq)
+);
+
+CREATE INDEX idx_print_sagas_status ON print_sagas (status);
+CREATE INDEX idx_print_saga_steps_saga_id ON print_saga_steps (saga_id);
@@ -0,0 +1,13 @@
+UPDATE invoices SET status = 'ISSUED' WHERE status = 'CLOSED';
+
+CREATE TABLE IF NOT EXISTS invoice_status_transitions (
+    id SERIAL PRIMARY KEY,
+    invoice_id INTEGER NOT NULL,
+    from_status VARCHAR(20) NOT NULL DEFAULT '',
+    to_status VARCHAR(20) NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    occurred_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (invoice_id) REFERENCES invoices(id)
+);
+
+CREATE INDEX idx_invoice_status_transitions_invoice_id ON invoice_status_transitions (invoice_id, id);
@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS credit_notes (
+    id SERIAL PRIMARY KEY,
+    invoice_id INTEGER NOT NULL,
+    number VARCHAR(60) NOT NULL UNIQUE,
+    reason TEXT NOT NULL DEFAULT '',
+    total_value DECIMAL(10,2) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (invoice_id) REFERENCES invoices(id)
+);
+
+CREATE TABLE IF NOT EXISTS credit_note_items (
+    id SERIAL PRIMARY KEY,
+    credit_note_id INTEGER NOT NULL,
+    invoice_item_id INTEGER NOT NULL,
+    product_id INTEGER NOT NULL,
+    quantity INTEGER NOT NULL,
+    price DECIMAL(10,2) NOT NULL,
+    returned BOOLEAN NOT NULL DEFAULT FALSE,
+    FOREIGN KEY (credit_note_id) REFERENCES credit_notes(id),
+    FOREIGN KEY (invoice_item_id) REFERENCES invoice_items(id)
+);
+
+CREATE INDEX idx_credit_notes_invoice_id ON credit_notes (invoice_id);
+CREATE INDEX idx_credit_note_items_pending ON credit_note_items (credit_note_id) WHERE NOT returned;
@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS customers (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    document VARCHAR(14) NOT NULL UNIQUE,
+    document_type VARCHAR(4) NOT NULL,
+    email VARCHAR(255) NOT NULL DEFAULT '',
+    state_registration VARCHAR(20) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS customer_addresses (
+    id SERIAL PRIMARY KEY,
+    customer_id INTEGER NOT NULL,
+    kind VARCHAR(20) NOT NULL,
+    street VARCHAR(255) NOT NULL,
+    number VARCHAR(20) NOT NULL DEFAULT '',
+    complement VARCHAR(100) NOT NULL DEFAULT '',
+    district VARCHAR(100) NOT NULL DEFAULT '',
+    city_code VARCHAR(7) NOT NULL DEFAULT '',
+    city VARCHAR(100) NOT NULL,
+    uf CHAR(2) NOT NULL,
+    cep VARCHAR(8) NOT NULL DEFAULT '',
+    FOREIGN KEY (customer_id) REFERENCES customers(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_customer_addresses_customer_id ON customer_addresses (customer_id);
+
+ALTER TABLE invoices ADD COLUMN IF NOT EXISTS customer_id INTEGER REFERENCES customers(id);
+ALTER TABLE invoices ADD COLUMN IF NOT EXISTS customer_snapshot JSONB;
@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS product_tax_classes (
+    product_id INTEGER PRIMARY KEY,
+    ncm VARCHAR(8) NOT NULL DEFAULT '',
+    ipi_rate DECIMAL(5,2) NOT NULL DEFAULT 0,
+    exempt BOOLEAN NOT NULL DEFAULT FALSE,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS invoice_item_taxes (
+    id SERIAL PRIMARY KEY,
+    invoice_item_id INTEGER NOT NULL,
+    kind VARCHAR(10) NOT NULL,
+    base DECIMAL(10,2) NOT NULL,
+    rate DECIMAL(5,2) NOT NULL,
+    amount DECIMAL(10,2) NOT NULL,
+    included BOOLEAN NOT NULL,
+    FOREIGN KEY (invoice_item_id) REFERENCES invoice_items(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_invoice_item_taxes_item_id ON invoice_item_taxes (invoice_item_id);
+
+ALTER TABLE invoice_items ADD COLUMN IF NOT EXISTS ncm VARCHAR(8) NOT NULL DEFAULT '';
+ALTER TABLE invoices ADD COLUMN IF NOT EXISTS subtotal DECIMAL(10,2) NOT NULL DEFAULT 0;
+ALTER TABLE invoices ADD COLUMN IF NOT EXISTS tax_total DECIMAL(10,2) NOT NULL DEFAULT 0;
+
+UPDATE invoices SET subtotal = total_value;
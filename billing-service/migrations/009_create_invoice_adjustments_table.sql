@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS invoice_adjustments (
+    id SERIAL PRIMARY KEY,
+    invoice_id INTEGER NOT NULL,
+    invoice_item_id INTEGER,
+    kind VARCHAR(10) NOT NULL,
+    method VARCHAR(10) NOT NULL,
+    rate DECIMAL(5,2) NOT NULL DEFAULT 0,
+    amount DECIMAL(10,2) NOT NULL DEFAULT 0,
+    reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (invoice_id) REFERENCES invoices(id) ON DELETE CASCADE,
+    FOREIGN KEY (invoice_item_id) REFERENCES invoice_items(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_invoice_adjustments_invoice_id ON invoice_adjustments (invoice_id);
+
+ALTER TABLE invoice_items ADD COLUMN IF NOT EXISTS discount DECIMAL(10,2) NOT NULL DEFAULT 0;
+ALTER TABLE invoice_items ADD COLUMN IF NOT EXISTS surcharge DECIMAL(10,2) NOT NULL DEFAULT 0;
+ALTER TABLE invoices ADD COLUMN IF NOT EXISTS discount_total DECIMAL(10,2) NOT NULL DEFAULT 0;
+ALTER TABLE invoices ADD COLUMN IF NOT EXISTS surcharge_total DECIMAL(10,2) NOT NULL DEFAULT 0;
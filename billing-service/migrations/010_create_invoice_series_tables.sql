@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS invoice_series (
+    code VARCHAR(20) PRIMARY KEY,
+    prefix VARCHAR(20) NOT NULL DEFAULT '',
+    padding INTEGER NOT NULL,
+    reset_yearly BOOLEAN NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- One counter per series and year (period 0 for series that never reset).
+-- Rows are locked by the transaction that inserts the invoice, so numbers
+-- are handed out without gaps.
+CREATE TABLE IF NOT EXISTS invoice_series_counters (
+    series_code VARCHAR(20) NOT NULL,
+    period INTEGER NOT NULL,
+    last_value INTEGER NOT NULL,
+    PRIMARY KEY (series_code, period),
+    FOREIGN KEY (series_code) REFERENCES invoice_series(code)
+);
+
+ALTER TABLE invoices ADD COLUMN IF NOT EXISTS series_code VARCHAR(20) REFERENCES invoice_series(code);
+ALTER TABLE invoices ADD COLUMN IF NOT EXISTS sequence INTEGER NOT NULL DEFAULT 0;
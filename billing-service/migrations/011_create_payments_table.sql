@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS payments (
+    id SERIAL PRIMARY KEY,
+    invoice_id INTEGER NOT NULL,
+    method VARCHAR(20) NOT NULL,
+    amount DECIMAL(10,2) NOT NULL CHECK (amount > 0),
+    paid_at TIMESTAMP NOT NULL,
+    reference VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (invoice_id) REFERENCES invoices(id)
+);
+
+CREATE INDEX idx_payments_invoice_id ON payments (invoice_id);
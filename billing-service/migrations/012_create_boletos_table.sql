@@ -0,0 +1,18 @@
+CREATE SEQUENCE IF NOT EXISTS boleto_sequence START 1;
+
+CREATE TABLE IF NOT EXISTS boletos (
+    id SERIAL PRIMARY KEY,
+    invoice_id INTEGER NOT NULL,
+    bank_code CHAR(3) NOT NULL,
+    sequence BIGINT NOT NULL,
+    our_number VARCHAR(30) NOT NULL,
+    due_date DATE NOT NULL,
+    amount DECIMAL(10,2) NOT NULL CHECK (amount > 0),
+    barcode CHAR(44) NOT NULL,
+    digitable_line CHAR(47) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (invoice_id) REFERENCES invoices(id),
+    UNIQUE (bank_code, sequence)
+);
+
+CREATE INDEX idx_boletos_invoice_id ON boletos (invoice_id);
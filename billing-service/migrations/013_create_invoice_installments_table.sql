@@ -0,0 +1,15 @@
+ALTER TABLE invoices ADD COLUMN IF NOT EXISTS payment_terms VARCHAR(100) NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS invoice_installments (
+    id SERIAL PRIMARY KEY,
+    invoice_id INTEGER NOT NULL,
+    number INTEGER NOT NULL,
+    due_date DATE NOT NULL,
+    amount DECIMAL(10,2) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'PENDING',
+    paid_at TIMESTAMP,
+    FOREIGN KEY (invoice_id) REFERENCES invoices(id),
+    UNIQUE (invoice_id, number)
+);
+
+CREATE INDEX idx_invoice_installments_status_due_date ON invoice_installments (status, due_date);
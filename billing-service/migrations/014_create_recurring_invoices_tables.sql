@@ -0,0 +1,40 @@
+CREATE TABLE IF NOT EXISTS recurring_invoices (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    customer_id INTEGER REFERENCES customers(id),
+    series_code VARCHAR(20) NOT NULL DEFAULT '',
+    payment_terms VARCHAR(100) NOT NULL DEFAULT '',
+    cadence VARCHAR(20) NOT NULL,
+    cron VARCHAR(100) NOT NULL DEFAULT '',
+    start_at TIMESTAMP NOT NULL,
+    end_at TIMESTAMP,
+    auto_print BOOLEAN NOT NULL DEFAULT FALSE,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    next_run_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_recurring_invoices_next_run_at ON recurring_invoices (next_run_at) WHERE active;
+
+CREATE TABLE IF NOT EXISTS recurring_invoice_items (
+    id SERIAL PRIMARY KEY,
+    recurring_invoice_id INTEGER NOT NULL,
+    product_id INTEGER NOT NULL,
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    FOREIGN KEY (recurring_invoice_id) REFERENCES recurring_invoices(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS recurring_invoice_runs (
+    id SERIAL PRIMARY KEY,
+    recurring_invoice_id INTEGER NOT NULL,
+    scheduled_for TIMESTAMP NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    invoice_id INTEGER REFERENCES invoices(id),
+    printed BOOLEAN NOT NULL DEFAULT FALSE,
+    step VARCHAR(20) NOT NULL DEFAULT '',
+    error TEXT NOT NULL DEFAULT '',
+    started_at TIMESTAMP NOT NULL,
+    finished_at TIMESTAMP,
+    FOREIGN KEY (recurring_invoice_id) REFERENCES recurring_invoices(id),
+    UNIQUE (recurring_invoice_id, scheduled_for)
+);
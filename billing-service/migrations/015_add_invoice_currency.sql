@@ -0,0 +1,19 @@
+ALTER TABLE invoices ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'BRL';
+
+-- base_price is the product price in the currency the inventory quotes it in
+ALTER TABLE invoice_items ADD COLUMN IF NOT EXISTS base_price DECIMAL(10,2);
+ALTER TABLE invoice_items ADD COLUMN IF NOT EXISTS base_currency CHAR(3) NOT NULL DEFAULT 'BRL';
+UPDATE invoice_items SET base_price = price WHERE base_price IS NULL;
+ALTER TABLE invoice_items ALTER COLUMN base_price SET NOT NULL;
+
+CREATE TABLE IF NOT EXISTS invoice_exchange_rates (
+    id SERIAL PRIMARY KEY,
+    invoice_id INTEGER NOT NULL,
+    from_currency CHAR(3) NOT NULL,
+    to_currency CHAR(3) NOT NULL,
+    rate NUMERIC(18,8) NOT NULL CHECK (rate > 0),
+    source VARCHAR(50) NOT NULL DEFAULT '',
+    as_of TIMESTAMP,
+    FOREIGN KEY (invoice_id) REFERENCES invoices(id),
+    UNIQUE (invoice_id, from_currency)
+);
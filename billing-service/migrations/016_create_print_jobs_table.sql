@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS print_jobs (
+    id SERIAL PRIMARY KEY,
+    invoice_id INTEGER NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    step_reached VARCHAR(50) NOT NULL DEFAULT '',
+    failed_reason TEXT NOT NULL DEFAULT '',
+    recovery JSONB,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    started_at TIMESTAMP,
+    finished_at TIMESTAMP,
+    FOREIGN KEY (invoice_id) REFERENCES invoices(id)
+);
+
+CREATE INDEX idx_print_jobs_queued ON print_jobs (created_at) WHERE status = 'QUEUED';
+CREATE INDEX idx_print_jobs_invoice_id ON print_jobs (invoice_id);
@@ -0,0 +1,3 @@
+-- Incremented on every update of the invoice, which only applies when the
+-- version read is still the current one
+ALTER TABLE invoices ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
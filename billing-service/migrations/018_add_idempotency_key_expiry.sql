@@ -0,0 +1,9 @@
+-- A reservation whose request never finished (crash or panic) can be taken
+-- over once locked_at is older than the lock timeout, and completed keys are
+-- purged once completed_at falls out of the retention window.
+ALTER TABLE idempotency_keys ADD COLUMN IF NOT EXISTS locked_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE idempotency_keys ADD COLUMN IF NOT EXISTS completed_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_completed_at ON idempotency_keys (completed_at);
+
+UPDATE idempotency_keys SET completed_at = created_at WHERE completed AND completed_at IS NULL;
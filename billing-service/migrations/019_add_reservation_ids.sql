@@ -0,0 +1,6 @@
+-- The inventory reservation holding the stock of each line, so billing only
+-- ever confirms, resizes or cancels its own reservations.
+ALTER TABLE invoice_items ADD COLUMN IF NOT EXISTS reservation_id INTEGER;
+
+-- The reservation a print saga step took or releases.
+ALTER TABLE print_saga_steps ADD COLUMN IF NOT EXISTS reservation_id INTEGER NOT NULL DEFAULT 0;
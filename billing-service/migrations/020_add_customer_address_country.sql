@@ -0,0 +1,2 @@
+-- ISO 3166-1 alpha-2 country of the address; existing addresses are Brazilian
+ALTER TABLE customer_addresses ADD COLUMN IF NOT EXISTS country CHAR(2) NOT NULL DEFAULT 'BR';
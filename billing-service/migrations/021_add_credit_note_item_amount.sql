@@ -0,0 +1,5 @@
+-- Amount credited for the line: its share of what the buyer was charged,
+-- after discounts and with the taxes added on top
+ALTER TABLE credit_note_items ADD COLUMN IF NOT EXISTS amount DECIMAL(10,2);
+UPDATE credit_note_items SET amount = price * quantity WHERE amount IS NULL;
+ALTER TABLE credit_note_items ALTER COLUMN amount SET NOT NULL;
@@ -0,0 +1,31 @@
+-- period records which counter the sequence was taken from, so a number
+-- can never be handed out twice within a series.
+ALTER TABLE invoices ADD COLUMN IF NOT EXISTS period INTEGER NOT NULL DEFAULT 0;
+
+UPDATE invoices i
+SET period = EXTRACT(YEAR FROM i.created_at)
+FROM invoice_series s
+WHERE i.series_code = s.code AND s.reset_yearly AND i.period = 0;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_invoices_series_sequence
+    ON invoices (series_code, period, sequence) WHERE series_code IS NOT NULL;
+
+-- document_number is the NF-e nNF: one counter per series that never
+-- restarts, unlike the yearly sequence.
+ALTER TABLE invoice_series ADD COLUMN IF NOT EXISTS last_document_number INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE invoices ADD COLUMN IF NOT EXISTS document_number INTEGER NOT NULL DEFAULT 0;
+
+UPDATE invoices i
+SET document_number = n.position
+FROM (
+    SELECT id, ROW_NUMBER() OVER (PARTITION BY series_code ORDER BY id) AS position
+    FROM invoices
+    WHERE series_code IS NOT NULL
+) n
+WHERE i.id = n.id AND i.document_number = 0;
+
+UPDATE invoice_series s
+SET last_document_number = COALESCE((SELECT MAX(document_number) FROM invoices WHERE series_code = s.code), 0);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_invoices_series_document_number
+    ON invoices (series_code, document_number) WHERE series_code IS NOT NULL;
@@ -0,0 +1,14 @@
+-- Relays claim events for claimed_until and publish them after committing the
+-- claim. A failed event is claimed again only once its retry is due, holding
+-- back the later events of its aggregate; after too many attempts it is
+-- dead-lettered and no longer blocks them.
+ALTER TABLE outbox_events ADD COLUMN IF NOT EXISTS claimed_until TIMESTAMP;
+ALTER TABLE outbox_events ADD COLUMN IF NOT EXISTS dead_lettered_at TIMESTAMP;
+
+DROP INDEX IF EXISTS idx_outbox_events_unpublished;
+CREATE INDEX IF NOT EXISTS idx_outbox_events_pending
+    ON outbox_events (aggregate_type, aggregate_id, id)
+    WHERE published_at IS NULL AND dead_lettered_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_outbox_events_dead_lettered
+    ON outbox_events (dead_lettered_at)
+    WHERE dead_lettered_at IS NOT NULL;
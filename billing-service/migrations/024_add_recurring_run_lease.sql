@@ -0,0 +1,11 @@
+-- A running occurrence holds a lease its process keeps renewing; only runs
+-- whose lease expired are taken for interrupted. Runs already in progress get
+-- an expired lease.
+ALTER TABLE recurring_invoice_runs ADD COLUMN IF NOT EXISTS lease_until TIMESTAMP;
+
+UPDATE recurring_invoice_runs SET lease_until = started_at WHERE lease_until IS NULL;
+
+ALTER TABLE recurring_invoice_runs ALTER COLUMN lease_until SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_recurring_invoice_runs_running
+    ON recurring_invoice_runs (lease_until) WHERE status = 'RUNNING';
@@ -0,0 +1,29 @@
+-- Amounts are stored with up to four decimal places, the most any ISO 4217
+-- currency has (CLF), and up to 15 integer digits so that currencies such as
+-- IDR or VND fit.
+ALTER TABLE invoices
+    ALTER COLUMN total_value TYPE NUMERIC(19,4),
+    ALTER COLUMN subtotal TYPE NUMERIC(19,4),
+    ALTER COLUMN tax_total TYPE NUMERIC(19,4),
+    ALTER COLUMN discount_total TYPE NUMERIC(19,4),
+    ALTER COLUMN surcharge_total TYPE NUMERIC(19,4);
+
+ALTER TABLE invoice_items
+    ALTER COLUMN price TYPE NUMERIC(19,4),
+    ALTER COLUMN base_price TYPE NUMERIC(19,4),
+    ALTER COLUMN discount TYPE NUMERIC(19,4),
+    ALTER COLUMN surcharge TYPE NUMERIC(19,4);
+
+ALTER TABLE invoice_item_taxes
+    ALTER COLUMN base TYPE NUMERIC(19,4),
+    ALTER COLUMN amount TYPE NUMERIC(19,4);
+
+ALTER TABLE invoice_adjustments ALTER COLUMN amount TYPE NUMERIC(19,4);
+ALTER TABLE invoice_installments ALTER COLUMN amount TYPE NUMERIC(19,4);
+ALTER TABLE payments ALTER COLUMN amount TYPE NUMERIC(19,4);
+ALTER TABLE boletos ALTER COLUMN amount TYPE NUMERIC(19,4);
+
+ALTER TABLE credit_notes ALTER COLUMN total_value TYPE NUMERIC(19,4);
+ALTER TABLE credit_note_items
+    ALTER COLUMN price TYPE NUMERIC(19,4),
+    ALTER COLUMN amount TYPE NUMERIC(19,4);
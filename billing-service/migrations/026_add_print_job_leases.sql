@@ -0,0 +1,33 @@
+-- A running print holds a lease its worker keeps renewing; only jobs whose
+-- lease expired are taken back. attempts counts the claims, so a worker only
+-- updates the job it claimed. Queued jobs wait until available_at, so a print
+-- whose saga is still settling is retried later rather than at once.
+ALTER TABLE print_jobs ADD COLUMN IF NOT EXISTS lease_until TIMESTAMP;
+ALTER TABLE print_jobs ADD COLUMN IF NOT EXISTS attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE print_jobs ADD COLUMN IF NOT EXISTS available_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP;
+
+UPDATE print_jobs SET lease_until = started_at WHERE status = 'RUNNING' AND lease_until IS NULL;
+
+-- At most one queued or running job per invoice: keep the newest of any
+-- duplicates left by concurrent submissions
+UPDATE print_jobs j
+SET status = 'FAILED', failed_reason = 'duplicate of job ' || newest.id, finished_at = CURRENT_TIMESTAMP
+FROM (
+    SELECT invoice_id, MAX(id) AS id
+    FROM print_jobs
+    WHERE status IN ('QUEUED', 'RUNNING')
+    GROUP BY invoice_id
+) newest
+WHERE j.invoice_id = newest.invoice_id
+  AND j.id <> newest.id
+  AND j.status IN ('QUEUED', 'RUNNING');
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_print_jobs_unfinished_invoice
+    ON print_jobs (invoice_id) WHERE status IN ('QUEUED', 'RUNNING');
+
+CREATE INDEX IF NOT EXISTS idx_print_jobs_running
+    ON print_jobs (lease_until) WHERE status = 'RUNNING';
+
+DROP INDEX IF EXISTS idx_print_jobs_queued;
+CREATE INDEX IF NOT EXISTS idx_print_jobs_available
+    ON print_jobs (available_at, created_at) WHERE status = 'QUEUED';
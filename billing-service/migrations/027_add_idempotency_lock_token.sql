@@ -0,0 +1,3 @@
+-- Each reservation of a key gets a fresh lock token, so a request whose
+-- reservation was taken over can no longer release or complete the key.
+ALTER TABLE idempotency_keys ADD COLUMN IF NOT EXISTS lock_token VARCHAR(64) NOT NULL DEFAULT '';
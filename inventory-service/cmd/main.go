@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
@@ -9,6 +10,8 @@ import (
 	"time"
 
 	"github.com/vitorwhois/microservice-invoice-billing/inventory-service/internal/application/product"
+	"github.com/vitorwhois/microservice-invoice-billing/inventory-service/internal/application/reservation"
+	"github.com/vitorwhois/microservice-invoice-billing/inventory-service/internal/application/retry"
 	"github.com/vitorwhois/microservice-invoice-billing/inventory-service/internal/config"
 	"github.com/vitorwhois/microservice-invoice-billing/inventory-service/internal/infrastructure/http/handlers"
 	"github.com/vitorwhois/microservice-invoice-billing/inventory-service/internal/infrastructure/http/routes"
@@ -36,11 +39,26 @@ func main() {
 		log.Printf("Running with failure mode: %s", failureMode)
 	}
 
+	// Every stock update, direct or through a reservation, retries the same way
+	retryPolicy := retry.Policy{
+		Attempts:  cfg.Stock.RetryAttempts,
+		BaseDelay: time.Duration(cfg.Stock.RetryBaseDelayMs) * time.Millisecond,
+		MaxDelay:  time.Duration(cfg.Stock.RetryMaxDelayMs) * time.Millisecond,
+	}
+
 	productRepo := persistence.NewProductRepository(db)
-	productService := product.NewProductService(productRepo, failureMode)
+	productService := product.NewProductService(productRepo, retryPolicy)
 	productHandler := handlers.NewProductHandler(productService)
 
-	router := routes.NewRouter(productHandler)
+	reservationRepo := persistence.NewReservationRepository(db)
+	reservationService := reservation.NewReservationService(productRepo, reservationRepo,
+		time.Duration(cfg.Reservation.TTLSeconds)*time.Second, retryPolicy, failureMode)
+	reservationHandler := handlers.NewReservationHandler(reservationService)
+
+	go reservationService.RunSweeper(context.Background(),
+		time.Duration(cfg.Reservation.SweepIntervalSeconds)*time.Second)
+
+	router := routes.NewRouter(productHandler, reservationHandler)
 
 	c := cors.New(cors.Options{
 		AllowedOrigins: []string{"*"},
@@ -0,0 +1,32 @@
+package product
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/vitorwhois/microservice-invoice-billing/inventory-service/internal/domain/product"
+)
+
+// updateStock loads the product, applies change and saves it. When another
+// request updated the product in between, the whole cycle is repeated with
+// fresh data until the retry budget runs out, in which case
+// ErrConcurrentUpdate is returned.
+func (s *Service) updateStock(ctx context.Context, id int, change func(*product.Product) error) error {
+	return s.saveStock(ctx, id, change, func(p *product.Product) error {
+		return s.repo.Update(ctx, p)
+	})
+}
+
+// saveStock is updateStock with the product written by save.
+func (s *Service) saveStock(ctx context.Context, id int, change, save func(*product.Product) error) error {
+	return s.retry.Do(ctx, fmt.Sprintf("product %d", id), func() error {
+		p, err := s.repo.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := change(p); err != nil {
+			return err
+		}
+		return save(p)
+	})
+}
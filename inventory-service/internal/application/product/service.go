@@ -2,25 +2,26 @@ package product
 
 import (
 	"context"
-	"errors"
 	"log"
 
+	"github.com/vitorwhois/microservice-invoice-billing/inventory-service/internal/application/retry"
 	"github.com/vitorwhois/microservice-invoice-billing/inventory-service/internal/domain/product"
+	"github.com/vitorwhois/microservice-invoice-billing/money"
 )
 
 type Service struct {
-	repo        product.Repository
-	failureMode string
+	repo  product.Repository
+	retry retry.Policy
 }
 
-func NewProductService(repo product.Repository, failureMode string) *Service {
+func NewProductService(repo product.Repository, policy retry.Policy) *Service {
 	return &Service{
-		repo:        repo,
-		failureMode: failureMode,
+		repo:  repo,
+		retry: policy,
 	}
 }
 
-func (s *Service) CreateProduct(ctx context.Context, name string, price float64, stock int) (*product.Product, error) {
+func (s *Service) CreateProduct(ctx context.Context, name string, price money.Money, stock int) (*product.Product, error) {
 	product, err := product.NewProduct(name, price, stock)
 	if err != nil {
 		return nil, err
@@ -34,58 +35,26 @@ func (s *Service) CreateProduct(ctx context.Context, name string, price float64,
 	return product, nil
 }
 
-func (s *Service) ReserveStock(ctx context.Context, id int, quantity int) error {
-	log.Printf("Reserving stock for product %d e quantity %d", id, quantity)
+// Restock returns units to the product. A non-empty key makes the call
+// idempotent: repeating it with a key already applied changes nothing.
+func (s *Service) Restock(ctx context.Context, id int, quantity int, key string) error {
+	log.Printf("Restocking product %d with quantity %d", id, quantity)
 
-	if s.failureMode == "reserve" {
-		log.Printf("Simulating failure in ReserveStock for product %d", id)
-		return errors.New("simulated failure in stock reservation")
+	change := func(p *product.Product) error {
+		return p.Restock(quantity)
 	}
-
-	product, err := s.repo.GetByID(ctx, id)
-	if err != nil {
-		log.Printf("Product %d not found", id)
-		return err
-	}
-
-	if err := product.ReserveStock(quantity); err != nil {
-		log.Printf("Error reserving stock for product %d: %v", id, err)
-		return err
-	}
-
-	return s.repo.Update(ctx, product)
-}
-
-func (s *Service) ConfirmStock(ctx context.Context, id int, quantity int) error {
-
-	if s.failureMode == "confirm" {
-		log.Printf("Simulating failure in ConfirmStock for product %d", id)
-		return errors.New("simulated failure in stock confirmation")
+	if key == "" {
+		return s.updateStock(ctx, id, change)
 	}
 
-	product, err := s.repo.GetByID(ctx, id)
-	if err != nil {
-		return err
+	err := s.saveStock(ctx, id, change, func(p *product.Product) error {
+		return s.repo.UpdateRestock(ctx, p, key, quantity)
+	})
+	if err == product.ErrRestockApplied {
+		log.Printf("Restock %q of product %d was already applied", key, id)
+		return nil
 	}
-
-	if err := product.ConfirmReservation(quantity); err != nil {
-		return err
-	}
-
-	return s.repo.Update(ctx, product)
-}
-
-func (s *Service) CancelReservation(ctx context.Context, id int, quantity int) error {
-	product, err := s.repo.GetByID(ctx, id)
-	if err != nil {
-		return err
-	}
-
-	if err := product.CancelReservation(quantity); err != nil {
-		return err
-	}
-
-	return s.repo.Update(ctx, product)
+	return err
 }
 
 func (s *Service) GetProductByID(ctx context.Context, id int) (*product.Product, error) {
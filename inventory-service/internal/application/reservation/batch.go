@@ -0,0 +1,209 @@
+package reservation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/vitorwhois/microservice-invoice-billing/inventory-service/internal/domain/product"
+)
+
+// ReserveBatch reserves stock for every line or for none of them.
+func (s *Service) ReserveBatch(ctx context.Context, lines []product.BatchLine, owner string, ttl time.Duration) (*product.ReservationBatch, error) {
+	log.Printf("Reserving batch of %d lines for %q", len(lines), owner)
+
+	if s.failureMode == "reserve" {
+		log.Printf("Simulating failure in ReserveBatch for %q", owner)
+		return nil, errors.New("simulated failure in stock reservation")
+	}
+
+	if ttl <= 0 {
+		ttl = s.defaultTTL
+	}
+
+	var b *product.ReservationBatch
+	err := s.retry.Do(ctx, fmt.Sprintf("batch for %q", owner), func() error {
+		var err error
+		b, err = product.NewReservationBatch(lines, owner, ttl)
+		if err != nil {
+			return err
+		}
+
+		holds, held, err := s.takeHolds(ctx, lines, b.CreatedAt)
+		if err != nil {
+			return err
+		}
+
+		products, err := s.batchProducts(ctx, b)
+		if err != nil {
+			return err
+		}
+
+		// Units already held for a product only need to change hands
+		for i, res := range b.Reservations {
+			need := res.Quantity - held[res.ProductID]
+			if need > 0 {
+				err = products[i].ReserveStock(need)
+			} else if need < 0 {
+				err = products[i].CancelReservation(-need)
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		return s.reservations.CreateBatch(ctx, b, holds, products)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// takeHolds closes the active holds named by the lines and reports how many
+// units they held per product. Holds that expired, were already settled or
+// belong to another product or batch are skipped: their units are reserved
+// afresh.
+func (s *Service) takeHolds(ctx context.Context, lines []product.BatchLine, now time.Time) ([]*product.Reservation, map[int]int, error) {
+	var holds []*product.Reservation
+	held := make(map[int]int)
+	seen := make(map[int]bool)
+
+	for _, l := range lines {
+		if l.HoldID == 0 || seen[l.HoldID] {
+			continue
+		}
+		seen[l.HoldID] = true
+
+		hold, err := s.reservations.GetByID(ctx, l.HoldID)
+		if err == product.ErrReservationNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if hold.ProductID != l.ProductID || hold.BatchID != 0 || hold.IsExpired(now) {
+			continue
+		}
+		// The hold's units now belong to the batch
+		if err := hold.Cancel(now); err != nil {
+			continue
+		}
+
+		holds = append(holds, hold)
+		held[hold.ProductID] += hold.Quantity
+	}
+
+	sort.Slice(holds, func(i, j int) bool { return holds[i].ID < holds[j].ID })
+	return holds, held, nil
+}
+
+func (s *Service) GetBatch(ctx context.Context, id int) (*product.ReservationBatch, error) {
+	return s.reservations.GetBatch(ctx, id)
+}
+
+func (s *Service) ConfirmBatch(ctx context.Context, id int) (*product.ReservationBatch, error) {
+	if s.failureMode == "confirm" {
+		log.Printf("Simulating failure in ConfirmBatch for batch %d", id)
+		return nil, errors.New("simulated failure in stock confirmation")
+	}
+
+	return s.settleBatch(ctx, id, func(b *product.ReservationBatch) error {
+		now := time.Now()
+		if b.IsExpired(now) {
+			if err := s.releaseBatch(ctx, b, now); err != nil {
+				log.Printf("Error releasing expired batch %d: %v", id, err)
+			}
+			return product.ErrReservationExpired
+		}
+
+		products, err := s.batchProducts(ctx, b)
+		if err != nil {
+			return err
+		}
+
+		if err := b.Confirm(now); err != nil {
+			return err
+		}
+		for i, res := range b.Reservations {
+			if err := products[i].ConfirmReservation(res.Quantity); err != nil {
+				return err
+			}
+		}
+
+		return s.reservations.UpdateBatch(ctx, b, products)
+	})
+}
+
+func (s *Service) CancelBatch(ctx context.Context, id int) (*product.ReservationBatch, error) {
+	return s.settleBatch(ctx, id, func(b *product.ReservationBatch) error {
+		products, err := s.batchProducts(ctx, b)
+		if err != nil {
+			return err
+		}
+
+		if err := b.Cancel(time.Now()); err != nil {
+			return err
+		}
+		for i, res := range b.Reservations {
+			if err := products[i].CancelReservation(res.Quantity); err != nil {
+				return err
+			}
+		}
+
+		return s.reservations.UpdateBatch(ctx, b, products)
+	})
+}
+
+// settleBatch loads batch id and applies change to it, reading the batch
+// again on every attempt like settle.
+func (s *Service) settleBatch(ctx context.Context, id int, change func(*product.ReservationBatch) error) (*product.ReservationBatch, error) {
+	var b *product.ReservationBatch
+	err := s.retry.Do(ctx, fmt.Sprintf("batch %d", id), func() error {
+		var err error
+		b, err = s.reservations.GetBatch(ctx, id)
+		if err != nil {
+			return err
+		}
+		return change(b)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func (s *Service) releaseBatch(ctx context.Context, b *product.ReservationBatch, now time.Time) error {
+	products, err := s.batchProducts(ctx, b)
+	if err != nil {
+		return err
+	}
+
+	if err := b.Expire(now); err != nil {
+		return err
+	}
+	for i, res := range b.Reservations {
+		if err := products[i].CancelReservation(res.Quantity); err != nil {
+			return err
+		}
+	}
+
+	return s.reservations.UpdateBatch(ctx, b, products)
+}
+
+// batchProducts loads the product of each reservation, in the same order,
+// which is sorted by product ID.
+func (s *Service) batchProducts(ctx context.Context, b *product.ReservationBatch) ([]*product.Product, error) {
+	products := make([]*product.Product, 0, len(b.Reservations))
+	for _, res := range b.Reservations {
+		p, err := s.products.GetByID(ctx, res.ProductID)
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, p)
+	}
+	return products, nil
+}
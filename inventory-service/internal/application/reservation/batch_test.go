@@ -0,0 +1,108 @@
+package reservation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/vitorwhois/microservice-invoice-billing/inventory-service/internal/application/retry"
+	"github.com/vitorwhois/microservice-invoice-billing/inventory-service/internal/domain/product"
+)
+
+// fakeProducts and fakeReservations keep what ReserveBatch reads and writes
+// in memory. Methods a test does not expect panic through the nil interface.
+type fakeProducts struct {
+	product.Repository
+	byID map[int]*product.Product
+}
+
+func (f *fakeProducts) GetByID(ctx context.Context, id int) (*product.Product, error) {
+	p, ok := f.byID[id]
+	if !ok {
+		return nil, product.ErrNotFound
+	}
+	copied := *p
+	return &copied, nil
+}
+
+type fakeReservations struct {
+	product.ReservationRepository
+	byID map[int]*product.Reservation
+
+	// What CreateBatch was asked to save
+	holds    []*product.Reservation
+	products []*product.Product
+}
+
+func (f *fakeReservations) GetByID(ctx context.Context, id int) (*product.Reservation, error) {
+	res, ok := f.byID[id]
+	if !ok {
+		return nil, product.ErrReservationNotFound
+	}
+	copied := *res
+	return &copied, nil
+}
+
+func (f *fakeReservations) CreateBatch(ctx context.Context, b *product.ReservationBatch, holds []*product.Reservation, products []*product.Product) error {
+	f.holds, f.products = holds, products
+	return nil
+}
+
+func hold(id, productID, quantity int, expiresAt time.Time) *product.Reservation {
+	return &product.Reservation{
+		ID:        id,
+		ProductID: productID,
+		Quantity:  quantity,
+		Status:    product.ReservationActive,
+		ExpiresAt: expiresAt,
+	}
+}
+
+func TestReserveBatchTakesHolds(t *testing.T) {
+	now := time.Now()
+	live, expired := now.Add(time.Hour), now.Add(-time.Minute)
+
+	tests := []struct {
+		name string
+		hold *product.Reservation
+		// taken is whether the hold is handed over to the batch
+		taken bool
+		// reserved is the reserved stock of the product once the batch of
+		// 3 units is taken, starting from the 2 units of the hold
+		reserved int
+	}{
+		{"active hold changes hands", hold(7, 1, 2, live), true, 3},
+		{"expired hold is skipped", hold(7, 1, 2, expired), false, 5},
+		{"hold of another product is skipped", hold(7, 2, 2, live), false, 5},
+		{"settled hold is skipped", &product.Reservation{ID: 7, ProductID: 1, Quantity: 2, Status: product.ReservationConfirmed, ExpiresAt: live}, false, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			products := &fakeProducts{byID: map[int]*product.Product{
+				1: {ID: 1, Stock: 10, ReservedStock: 2},
+			}}
+			reservations := &fakeReservations{byID: map[int]*product.Reservation{7: tt.hold}}
+			s := NewReservationService(products, reservations, time.Hour, retry.Policy{Attempts: 1}, "")
+
+			lines := []product.BatchLine{{ProductID: 1, Quantity: 3, HoldID: 7}}
+			if _, err := s.ReserveBatch(context.Background(), lines, "invoice-1-print", 0); err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.taken {
+				if len(reservations.holds) != 1 || reservations.holds[0].ID != 7 {
+					t.Fatalf("holds taken = %v, want hold 7", reservations.holds)
+				}
+				if got := reservations.holds[0].Status; got != product.ReservationCancelled {
+					t.Errorf("taken hold status = %s, want %s", got, product.ReservationCancelled)
+				}
+			} else if len(reservations.holds) != 0 {
+				t.Fatalf("holds taken = %v, want none", reservations.holds)
+			}
+
+			if got := reservations.products[0].ReservedStock; got != tt.reserved {
+				t.Errorf("reserved stock = %d, want %d", got, tt.reserved)
+			}
+		})
+	}
+}
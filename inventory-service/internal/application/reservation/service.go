@@ -0,0 +1,230 @@
+package reservation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/vitorwhois/microservice-invoice-billing/inventory-service/internal/application/retry"
+	"github.com/vitorwhois/microservice-invoice-billing/inventory-service/internal/domain/product"
+)
+
+// Service holds and settles stock reservations. Operations that lose the race
+// for a product to another request are run again from a fresh read, within
+// the retry policy.
+type Service struct {
+	products     product.Repository
+	reservations product.ReservationRepository
+	defaultTTL   time.Duration
+	retry        retry.Policy
+	failureMode  string
+}
+
+func NewReservationService(products product.Repository, reservations product.ReservationRepository, defaultTTL time.Duration, policy retry.Policy, failureMode string) *Service {
+	return &Service{
+		products:     products,
+		reservations: reservations,
+		defaultTTL:   defaultTTL,
+		retry:        policy,
+		failureMode:  failureMode,
+	}
+}
+
+func (s *Service) Reserve(ctx context.Context, productID int, quantity int, owner string, ttl time.Duration) (*product.Reservation, error) {
+	log.Printf("Reserving %d units of product %d for %q", quantity, productID, owner)
+
+	if s.failureMode == "reserve" {
+		log.Printf("Simulating failure in Reserve for product %d", productID)
+		return nil, errors.New("simulated failure in stock reservation")
+	}
+
+	if ttl <= 0 {
+		ttl = s.defaultTTL
+	}
+
+	var res *product.Reservation
+	err := s.retry.Do(ctx, fmt.Sprintf("product %d", productID), func() error {
+		var err error
+		res, err = product.NewReservation(productID, quantity, owner, ttl)
+		if err != nil {
+			return err
+		}
+
+		p, err := s.products.GetByID(ctx, productID)
+		if err != nil {
+			return err
+		}
+
+		if err := p.ReserveStock(quantity); err != nil {
+			return err
+		}
+
+		return s.reservations.Create(ctx, res, p)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (s *Service) GetReservation(ctx context.Context, id int) (*product.Reservation, error) {
+	return s.reservations.GetByID(ctx, id)
+}
+
+func (s *Service) Confirm(ctx context.Context, id int) (*product.Reservation, error) {
+	if s.failureMode == "confirm" {
+		log.Printf("Simulating failure in Confirm for reservation %d", id)
+		return nil, errors.New("simulated failure in stock confirmation")
+	}
+
+	return s.settle(ctx, id, func(res *product.Reservation) error {
+		now := time.Now()
+		if res.IsExpired(now) {
+			// Hand the stock back right away instead of waiting for the sweeper
+			if err := s.release(ctx, res, now); err != nil {
+				log.Printf("Error releasing expired reservation %d: %v", id, err)
+			}
+			return product.ErrReservationExpired
+		}
+
+		p, err := s.products.GetByID(ctx, res.ProductID)
+		if err != nil {
+			return err
+		}
+
+		if err := res.Confirm(now); err != nil {
+			return err
+		}
+		if err := p.ConfirmReservation(res.Quantity); err != nil {
+			return err
+		}
+
+		return s.reservations.Update(ctx, res, p)
+	})
+}
+
+func (s *Service) Cancel(ctx context.Context, id int) (*product.Reservation, error) {
+	return s.settle(ctx, id, func(res *product.Reservation) error {
+		p, err := s.products.GetByID(ctx, res.ProductID)
+		if err != nil {
+			return err
+		}
+
+		if err := res.Cancel(time.Now()); err != nil {
+			return err
+		}
+		if err := p.CancelReservation(res.Quantity); err != nil {
+			return err
+		}
+
+		return s.reservations.Update(ctx, res, p)
+	})
+}
+
+// Resize changes the quantity held by a reservation, reserving or releasing
+// the difference. expected must match the current quantity.
+func (s *Service) Resize(ctx context.Context, id int, quantity, expected int) (*product.Reservation, error) {
+	return s.settle(ctx, id, func(res *product.Reservation) error {
+		now := time.Now()
+		if res.IsExpired(now) {
+			if err := s.release(ctx, res, now); err != nil {
+				log.Printf("Error releasing expired reservation %d: %v", id, err)
+			}
+			return product.ErrReservationExpired
+		}
+
+		p, err := s.products.GetByID(ctx, res.ProductID)
+		if err != nil {
+			return err
+		}
+
+		previous := res.Quantity
+		if err := res.Resize(quantity, expected, now); err != nil {
+			return err
+		}
+		if delta := quantity - previous; delta > 0 {
+			err = p.ReserveStock(delta)
+		} else if delta < 0 {
+			err = p.CancelReservation(-delta)
+		}
+		if err != nil {
+			return err
+		}
+
+		return s.reservations.Resize(ctx, res, previous, p)
+	})
+}
+
+// settle loads reservation id, which must not belong to a batch, and applies
+// change to it. The reservation is read again on every attempt, so a retry
+// never works on the state of the one that lost the race.
+func (s *Service) settle(ctx context.Context, id int, change func(*product.Reservation) error) (*product.Reservation, error) {
+	var res *product.Reservation
+	err := s.retry.Do(ctx, fmt.Sprintf("reservation %d", id), func() error {
+		var err error
+		res, err = s.reservations.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if res.BatchID != 0 {
+			return product.ErrReservationInBatch
+		}
+		return change(res)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// ReleaseExpired returns the stock held by expired reservations and batches to
+// the available balance and reports how many were released.
+func (s *Service) ReleaseExpired(ctx context.Context, batchSize int) (int, error) {
+	now := time.Now()
+	expired, err := s.reservations.ListExpired(ctx, now, batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	released := 0
+	for _, res := range expired {
+		if err := s.release(ctx, res, now); err != nil {
+			log.Printf("Error releasing expired reservation %d: %v", res.ID, err)
+			continue
+		}
+		released++
+	}
+
+	batches, err := s.reservations.ListExpiredBatches(ctx, now, batchSize)
+	if err != nil {
+		return released, err
+	}
+
+	for _, b := range batches {
+		if err := s.releaseBatch(ctx, b, now); err != nil {
+			log.Printf("Error releasing expired batch %d: %v", b.ID, err)
+			continue
+		}
+		released++
+	}
+	return released, nil
+}
+
+func (s *Service) release(ctx context.Context, res *product.Reservation, now time.Time) error {
+	p, err := s.products.GetByID(ctx, res.ProductID)
+	if err != nil {
+		return err
+	}
+
+	if err := res.Expire(now); err != nil {
+		return err
+	}
+	if err := p.CancelReservation(res.Quantity); err != nil {
+		return err
+	}
+
+	return s.reservations.Update(ctx, res, p)
+}
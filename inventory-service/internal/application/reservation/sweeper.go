@@ -0,0 +1,36 @@
+package reservation
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+const sweepBatchSize = 100
+
+// RunSweeper releases expired reservations every interval until ctx is done.
+func (s *Service) RunSweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for {
+				released, err := s.ReleaseExpired(ctx, sweepBatchSize)
+				if err != nil {
+					log.Printf("Error sweeping expired reservations: %v", err)
+					break
+				}
+				if released > 0 {
+					log.Printf("Released %d expired reservations", released)
+				}
+				if released < sweepBatchSize {
+					break
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,53 @@
+// Package retry repeats stock operations that lost the race for a product.
+package retry
+
+import (
+	"context"
+	"log"
+	"math/rand"
+	"time"
+
+	"github.com/vitorwhois/microservice-invoice-billing/inventory-service/internal/domain/product"
+)
+
+// Policy bounds how often a stock operation that lost the race for a product
+// is reloaded and applied again. Attempts counts the first try, so 1 disables
+// retries.
+type Policy struct {
+	Attempts  int
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// Do runs op until it returns anything but product.ErrConcurrentUpdate or the
+// attempts run out, in which case ErrConcurrentUpdate is returned. op must
+// read afresh everything it changes, since what it read on the previous
+// attempt is stale. what names the operation in the log.
+func (p Policy) Do(ctx context.Context, what string, op func() error) error {
+	delay := p.BaseDelay
+	for attempt := 1; ; attempt++ {
+		err := op()
+		if err != product.ErrConcurrentUpdate || attempt >= p.Attempts {
+			if err == product.ErrConcurrentUpdate {
+				log.Printf("Giving up on %s after %d concurrent updates", what, attempt)
+			}
+			return err
+		}
+
+		// Jitter keeps the requests that collided from colliding again
+		wait := delay
+		if wait > 0 {
+			wait = wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+
+		delay *= 2
+		if p.MaxDelay > 0 && delay > p.MaxDelay {
+			delay = p.MaxDelay
+		}
+	}
+}
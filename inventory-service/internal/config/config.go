@@ -8,8 +8,10 @@ import (
 )
 
 type Config struct {
-	Database DatabaseConfig
-	Server   ServerConfig
+	Database    DatabaseConfig
+	Server      ServerConfig
+	Reservation ReservationConfig
+	Stock       StockConfig
 }
 
 type DatabaseConfig struct {
@@ -26,6 +28,17 @@ type ServerConfig struct {
 	WriteTimeout int
 }
 
+type ReservationConfig struct {
+	TTLSeconds           int
+	SweepIntervalSeconds int
+}
+
+type StockConfig struct {
+	RetryAttempts    int
+	RetryBaseDelayMs int
+	RetryMaxDelayMs  int
+}
+
 func Load() (*Config, error) {
 	err := godotenv.Load()
 	if err != nil {
@@ -40,6 +53,11 @@ func Load() (*Config, error) {
 	viper.SetDefault("DB_PASSWORD", "postgres")
 	viper.SetDefault("DB_NAME", "inventory")
 	viper.SetDefault("SERVER_PORT", "8080")
+	viper.SetDefault("RESERVATION_TTL_SECONDS", 900)
+	viper.SetDefault("RESERVATION_SWEEP_INTERVAL_SECONDS", 30)
+	viper.SetDefault("STOCK_RETRY_ATTEMPTS", 5)
+	viper.SetDefault("STOCK_RETRY_BASE_DELAY_MS", 10)
+	viper.SetDefault("STOCK_RETRY_MAX_DELAY_MS", 200)
 
 	return &Config{
 		Database: DatabaseConfig{
@@ -54,5 +72,14 @@ func Load() (*Config, error) {
 			ReadTimeout:  15,
 			WriteTimeout: 15,
 		},
+		Reservation: ReservationConfig{
+			TTLSeconds:           viper.GetInt("RESERVATION_TTL_SECONDS"),
+			SweepIntervalSeconds: viper.GetInt("RESERVATION_SWEEP_INTERVAL_SECONDS"),
+		},
+		Stock: StockConfig{
+			RetryAttempts:    viper.GetInt("STOCK_RETRY_ATTEMPTS"),
+			RetryBaseDelayMs: viper.GetInt("STOCK_RETRY_BASE_DELAY_MS"),
+			RetryMaxDelayMs:  viper.GetInt("STOCK_RETRY_MAX_DELAY_MS"),
+		},
 	}, nil
 }
@@ -4,6 +4,8 @@ import (
 	"errors"
 	"log"
 	"time"
+
+	"github.com/vitorwhois/microservice-invoice-billing/money"
 )
 
 var (
@@ -11,26 +13,38 @@ var (
 	ErrInvalidStock      = errors.New("invalid stock quantity")
 	ErrNotFound          = errors.New("product not found")
 	ErrConcurrentUpdate  = errors.New("concurrent modification")
+	ErrInvalidCurrency   = errors.New("invalid currency")
+	// ErrRestockApplied is a restock whose idempotency key was already used
+	ErrRestockApplied = errors.New("restock already applied")
 )
 
 type Product struct {
 	ID            int
 	Name          string
-	Price         float64
+	Price         money.Money
+	Currency      string
 	Stock         int
 	ReservedStock int
 	Version       int
 	CreatedAt     time.Time
 }
 
-func NewProduct(name string, price float64, stock int) (*Product, error) {
+// NewProduct prices the product in the currency of price, BRL when unset.
+func NewProduct(name string, price money.Money, stock int) (*Product, error) {
 	if stock < 0 {
 		return nil, ErrInvalidStock
 	}
+	if price.Currency == "" {
+		price.Currency = money.DefaultCurrency
+	}
+	if !money.ValidCurrency(price.Currency) {
+		return nil, ErrInvalidCurrency
+	}
 
 	return &Product{
 		Name:      name,
 		Price:     price,
+		Currency:  price.Currency,
 		Stock:     stock,
 		Version:   1,
 		CreatedAt: time.Now(),
@@ -68,3 +82,13 @@ func (p *Product) CancelReservation(quantity int) error {
 	p.Version++
 	return nil
 }
+
+// Restock returns units to the shelf, e.g. goods sent back under a credit note.
+func (p *Product) Restock(quantity int) error {
+	if quantity <= 0 {
+		return ErrInvalidStock
+	}
+	p.Stock += quantity
+	p.Version++
+	return nil
+}
@@ -6,5 +6,8 @@ type Repository interface {
 	Create(ctx context.Context, product *Product) error
 	GetByID(ctx context.Context, id int) (*Product, error)
 	Update(ctx context.Context, product *Product) error
+	// UpdateRestock saves a restocked product and records key in the same
+	// transaction, failing with ErrRestockApplied when key was seen before.
+	UpdateRestock(ctx context.Context, product *Product, key string, quantity int) error
 	GetAll(ctx context.Context) ([]*Product, error)
 }
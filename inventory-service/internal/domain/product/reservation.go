@@ -0,0 +1,239 @@
+package product
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+)
+
+var (
+	ErrReservationNotFound  = errors.New("reservation not found")
+	ErrReservationNotActive = errors.New("reservation is no longer active")
+	ErrReservationExpired   = errors.New("reservation expired")
+	ErrInvalidQuantity      = errors.New("invalid quantity")
+	ErrEmptyBatch           = errors.New("reservation batch has no lines")
+	ErrReservationInBatch   = errors.New("reservation belongs to a batch")
+	ErrReservationChanged   = errors.New("reservation quantity changed")
+)
+
+type ReservationStatus string
+
+const (
+	ReservationActive    ReservationStatus = "ACTIVE"
+	ReservationConfirmed ReservationStatus = "CONFIRMED"
+	ReservationCancelled ReservationStatus = "CANCELLED"
+	ReservationExpired   ReservationStatus = "EXPIRED"
+)
+
+type Reservation struct {
+	ID        int
+	BatchID   int
+	ProductID int
+	Quantity  int
+	Owner     string
+	Status    ReservationStatus
+	ExpiresAt time.Time
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func NewReservation(productID int, quantity int, owner string, ttl time.Duration) (*Reservation, error) {
+	if quantity <= 0 {
+		return nil, ErrInvalidQuantity
+	}
+
+	now := time.Now()
+	return &Reservation{
+		ProductID: productID,
+		Quantity:  quantity,
+		Owner:     owner,
+		Status:    ReservationActive,
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}, nil
+}
+
+func (r *Reservation) IsExpired(now time.Time) bool {
+	return r.Status == ReservationActive && !now.Before(r.ExpiresAt)
+}
+
+func (r *Reservation) Confirm(now time.Time) error {
+	if r.Status != ReservationActive {
+		return ErrReservationNotActive
+	}
+	if r.IsExpired(now) {
+		return ErrReservationExpired
+	}
+	r.Status = ReservationConfirmed
+	r.UpdatedAt = now
+	return nil
+}
+
+func (r *Reservation) Cancel(now time.Time) error {
+	if r.Status != ReservationActive {
+		return ErrReservationNotActive
+	}
+	r.Status = ReservationCancelled
+	r.UpdatedAt = now
+	return nil
+}
+
+// Resize changes the quantity held by an active reservation. expected is the
+// quantity the caller last saw, so two callers resizing from the same
+// reading cannot both succeed.
+func (r *Reservation) Resize(quantity, expected int, now time.Time) error {
+	if quantity <= 0 {
+		return ErrInvalidQuantity
+	}
+	if r.Status != ReservationActive {
+		return ErrReservationNotActive
+	}
+	if r.IsExpired(now) {
+		return ErrReservationExpired
+	}
+	if r.Quantity != expected {
+		return ErrReservationChanged
+	}
+	r.Quantity = quantity
+	r.UpdatedAt = now
+	return nil
+}
+
+func (r *Reservation) Expire(now time.Time) error {
+	if !r.IsExpired(now) {
+		return ErrReservationNotActive
+	}
+	r.Status = ReservationExpired
+	r.UpdatedAt = now
+	return nil
+}
+
+// ReservationBatch groups reservations for several products that are taken,
+// confirmed and cancelled together.
+type ReservationBatch struct {
+	ID           int
+	Owner        string
+	Status       ReservationStatus
+	ExpiresAt    time.Time
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Reservations []*Reservation
+}
+
+// BatchLine asks for quantity units of a product. HoldID optionally names an
+// active reservation of the same product whose units are handed over to the
+// batch instead of being reserved a second time.
+type BatchLine struct {
+	ProductID int
+	Quantity  int
+	HoldID    int
+}
+
+// NewReservationBatch builds one reservation per product; repeated products
+// are merged so each product row is touched once. Reservations are sorted by
+// product so that concurrent batches lock product rows in the same order.
+func NewReservationBatch(lines []BatchLine, owner string, ttl time.Duration) (*ReservationBatch, error) {
+	if len(lines) == 0 {
+		return nil, ErrEmptyBatch
+	}
+
+	now := time.Now()
+	b := &ReservationBatch{
+		Owner:     owner,
+		Status:    ReservationActive,
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	byProduct := make(map[int]*Reservation)
+	for _, l := range lines {
+		if l.Quantity <= 0 {
+			return nil, ErrInvalidQuantity
+		}
+		if res, ok := byProduct[l.ProductID]; ok {
+			res.Quantity += l.Quantity
+			continue
+		}
+		res, err := NewReservation(l.ProductID, l.Quantity, owner, ttl)
+		if err != nil {
+			return nil, err
+		}
+		res.ExpiresAt = b.ExpiresAt
+		byProduct[l.ProductID] = res
+		b.Reservations = append(b.Reservations, res)
+	}
+	sort.Slice(b.Reservations, func(i, j int) bool {
+		return b.Reservations[i].ProductID < b.Reservations[j].ProductID
+	})
+	return b, nil
+}
+
+func (b *ReservationBatch) IsExpired(now time.Time) bool {
+	return b.Status == ReservationActive && !now.Before(b.ExpiresAt)
+}
+
+func (b *ReservationBatch) Confirm(now time.Time) error {
+	if b.Status != ReservationActive {
+		return ErrReservationNotActive
+	}
+	if b.IsExpired(now) {
+		return ErrReservationExpired
+	}
+	for _, res := range b.Reservations {
+		if err := res.Confirm(now); err != nil {
+			return err
+		}
+	}
+	b.Status = ReservationConfirmed
+	b.UpdatedAt = now
+	return nil
+}
+
+func (b *ReservationBatch) Cancel(now time.Time) error {
+	if b.Status != ReservationActive {
+		return ErrReservationNotActive
+	}
+	for _, res := range b.Reservations {
+		if err := res.Cancel(now); err != nil {
+			return err
+		}
+	}
+	b.Status = ReservationCancelled
+	b.UpdatedAt = now
+	return nil
+}
+
+func (b *ReservationBatch) Expire(now time.Time) error {
+	if !b.IsExpired(now) {
+		return ErrReservationNotActive
+	}
+	for _, res := range b.Reservations {
+		if err := res.Expire(now); err != nil {
+			return err
+		}
+	}
+	b.Status = ReservationExpired
+	b.UpdatedAt = now
+	return nil
+}
+
+// ReservationRepository persists reservations together with the products
+// whose counters they changed, so both are written in a single transaction.
+type ReservationRepository interface {
+	Create(ctx context.Context, reservation *Reservation, product *Product) error
+	Update(ctx context.Context, reservation *Reservation, product *Product) error
+	// Resize saves the new quantity of a reservation that still holds
+	// previous units.
+	Resize(ctx context.Context, reservation *Reservation, previous int, product *Product) error
+	GetByID(ctx context.Context, id int) (*Reservation, error)
+	ListExpired(ctx context.Context, now time.Time, limit int) ([]*Reservation, error)
+
+	// CreateBatch also closes the holds handed over to the batch.
+	CreateBatch(ctx context.Context, batch *ReservationBatch, holds []*Reservation, products []*Product) error
+	UpdateBatch(ctx context.Context, batch *ReservationBatch, products []*Product) error
+	GetBatch(ctx context.Context, id int) (*ReservationBatch, error)
+	ListExpiredBatches(ctx context.Context, now time.Time, limit int) ([]*ReservationBatch, error)
+}
@@ -4,8 +4,11 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/vitorwhois/microservice-invoice-billing/inventory-service/internal/application/product"
+	domainproduct "github.com/vitorwhois/microservice-invoice-billing/inventory-service/internal/domain/product"
+	"github.com/vitorwhois/microservice-invoice-billing/money"
 
 	"github.com/gorilla/mux"
 )
@@ -19,23 +22,38 @@ func NewProductHandler(service *product.Service) *ProductHandler {
 }
 
 func (h *ProductHandler) Create(w http.ResponseWriter, r *http.Request) {
+	// currency defaults to BRL
 	var request struct {
-		Name  string  `json:"name"`
-		Price float64 `json:"price"`
-		Stock int     `json:"stock"`
+		Name     string        `json:"name"`
+		Price    money.Literal `json:"price"`
+		Currency string        `json:"currency"`
+		Stock    int           `json:"stock"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	if request.Name == "" || request.Price <= 0 || request.Stock < 0 {
+	currency := strings.ToUpper(request.Currency)
+	if currency == "" {
+		currency = money.DefaultCurrency
+	}
+	if !money.ValidCurrency(currency) {
+		http.Error(w, domainproduct.ErrInvalidCurrency.Error(), http.StatusBadRequest)
+		return
+	}
+	price, err := request.Price.In(currency)
+	if err != nil || request.Name == "" || !price.IsPositive() || request.Stock < 0 {
 		http.Error(w, "Invalid product data", http.StatusBadRequest)
 		return
 	}
 
-	createdProduct, err := h.service.CreateProduct(r.Context(), request.Name, request.Price, request.Stock)
+	createdProduct, err := h.service.CreateProduct(r.Context(), request.Name, price, request.Stock)
 	if err != nil {
+		if err == domainproduct.ErrInvalidCurrency {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -46,36 +64,7 @@ func (h *ProductHandler) Create(w http.ResponseWriter, r *http.Request) {
 
 }
 
-func (h *ProductHandler) ReserveStock(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-
-	id, err := strconv.Atoi(vars["id"])
-
-	var request struct {
-		Quantity int `json:"quantity"`
-	}
-
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		http.Error(w, "Invalid input", http.StatusBadRequest)
-		return
-	}
-
-	if request.Quantity <= 0 {
-		http.Error(w, "Invalid quantity", http.StatusBadRequest)
-		return
-	}
-
-	err = h.service.ReserveStock(r.Context(), id, request.Quantity)
-	if err != nil {
-
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	w.WriteHeader(http.StatusOK)
-}
-
-func (h *ProductHandler) ConfirmStock(w http.ResponseWriter, r *http.Request) {
+func (h *ProductHandler) Restock(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
 	if err != nil {
@@ -84,7 +73,8 @@ func (h *ProductHandler) ConfirmStock(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var request struct {
-		Quantity int `json:"quantity"`
+		Quantity       int    `json:"quantity"`
+		IdempotencyKey string `json:"idempotency_key"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
@@ -92,41 +82,14 @@ func (h *ProductHandler) ConfirmStock(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.service.ConfirmStock(r.Context(), id, request.Quantity)
+	err = h.service.Restock(r.Context(), id, request.Quantity, request.IdempotencyKey)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeProductError(w, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{"status": "confirmed"})
-}
-
-func (h *ProductHandler) CancelReservation(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id, err := strconv.Atoi(vars["id"])
-	if err != nil {
-		http.Error(w, "Invalid product ID", http.StatusBadRequest)
-		return
-	}
-
-	var request struct {
-		Quantity int `json:"quantity"`
-	}
-
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	}
-
-	err = h.service.CancelReservation(r.Context(), id, request.Quantity)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{"status": "reservation_canceled"})
+	json.NewEncoder(w).Encode(map[string]string{"status": "restocked"})
 }
 
 func (h *ProductHandler) GetProduct(w http.ResponseWriter, r *http.Request) {
@@ -138,7 +101,7 @@ func (h *ProductHandler) GetProduct(w http.ResponseWriter, r *http.Request) {
 	}
 	product, err := h.service.GetProductByID(r.Context(), id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeProductError(w, err)
 		return
 	}
 	if product == nil {
@@ -158,3 +121,23 @@ func (h *ProductHandler) GetAllProducts(w http.ResponseWriter, r *http.Request)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(products)
 }
+
+// retryAfterSeconds is the Retry-After hint sent when a stock update kept
+// losing the race for a product.
+const retryAfterSeconds = "1"
+
+func writeProductError(w http.ResponseWriter, err error) {
+	switch err {
+	case domainproduct.ErrNotFound:
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case domainproduct.ErrConcurrentUpdate:
+		// The product is contended; the same request is likely to go through
+		// a moment later
+		w.Header().Set("Retry-After", retryAfterSeconds)
+		http.Error(w, err.Error(), http.StatusConflict)
+	case domainproduct.ErrInsufficientStock, domainproduct.ErrInvalidStock:
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
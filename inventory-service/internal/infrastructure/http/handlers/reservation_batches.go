@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/vitorwhois/microservice-invoice-billing/inventory-service/internal/domain/product"
+
+	"github.com/gorilla/mux"
+)
+
+func (h *ReservationHandler) CreateBatch(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Owner      string `json:"owner"`
+		TTLSeconds int    `json:"ttl_seconds"`
+		Lines      []struct {
+			ProductID int `json:"product_id"`
+			Quantity  int `json:"quantity"`
+			HoldID    int `json:"hold_id"`
+		} `json:"lines"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	if request.TTLSeconds < 0 {
+		http.Error(w, "Invalid ttl_seconds", http.StatusBadRequest)
+		return
+	}
+
+	lines := make([]product.BatchLine, 0, len(request.Lines))
+	for _, l := range request.Lines {
+		lines = append(lines, product.BatchLine{ProductID: l.ProductID, Quantity: l.Quantity, HoldID: l.HoldID})
+	}
+
+	b, err := h.service.ReserveBatch(r.Context(), lines, request.Owner,
+		time.Duration(request.TTLSeconds)*time.Second)
+	if err != nil {
+		writeReservationError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(b)
+}
+
+func (h *ReservationHandler) GetBatch(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid batch ID", http.StatusBadRequest)
+		return
+	}
+
+	b, err := h.service.GetBatch(r.Context(), id)
+	if err != nil {
+		writeReservationError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(b)
+}
+
+func (h *ReservationHandler) ConfirmBatch(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid batch ID", http.StatusBadRequest)
+		return
+	}
+
+	b, err := h.service.ConfirmBatch(r.Context(), id)
+	if err != nil {
+		writeReservationError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(b)
+}
+
+func (h *ReservationHandler) CancelBatch(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid batch ID", http.StatusBadRequest)
+		return
+	}
+
+	b, err := h.service.CancelBatch(r.Context(), id)
+	if err != nil {
+		writeReservationError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(b)
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/vitorwhois/microservice-invoice-billing/inventory-service/internal/application/reservation"
+	"github.com/vitorwhois/microservice-invoice-billing/inventory-service/internal/domain/product"
+
+	"github.com/gorilla/mux"
+)
+
+type ReservationHandler struct {
+	service *reservation.Service
+}
+
+func NewReservationHandler(service *reservation.Service) *ReservationHandler {
+	return &ReservationHandler{service: service}
+}
+
+func (h *ReservationHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		ProductID  int    `json:"product_id"`
+		Quantity   int    `json:"quantity"`
+		Owner      string `json:"owner"`
+		TTLSeconds int    `json:"ttl_seconds"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	if request.Quantity <= 0 || request.TTLSeconds < 0 {
+		http.Error(w, "Invalid quantity", http.StatusBadRequest)
+		return
+	}
+
+	res, err := h.service.Reserve(r.Context(), request.ProductID, request.Quantity, request.Owner,
+		time.Duration(request.TTLSeconds)*time.Second)
+	if err != nil {
+		writeReservationError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(res)
+}
+
+func (h *ReservationHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid reservation ID", http.StatusBadRequest)
+		return
+	}
+
+	res, err := h.service.GetReservation(r.Context(), id)
+	if err != nil {
+		writeReservationError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
+
+func (h *ReservationHandler) Confirm(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid reservation ID", http.StatusBadRequest)
+		return
+	}
+
+	res, err := h.service.Confirm(r.Context(), id)
+	if err != nil {
+		writeReservationError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
+
+func (h *ReservationHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid reservation ID", http.StatusBadRequest)
+		return
+	}
+
+	res, err := h.service.Cancel(r.Context(), id)
+	if err != nil {
+		writeReservationError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
+
+// Resize changes the quantity of a reservation. expected_quantity is the
+// quantity the caller last read.
+func (h *ReservationHandler) Resize(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid reservation ID", http.StatusBadRequest)
+		return
+	}
+
+	var request struct {
+		Quantity         int `json:"quantity"`
+		ExpectedQuantity int `json:"expected_quantity"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	if request.Quantity <= 0 {
+		http.Error(w, "Invalid quantity", http.StatusBadRequest)
+		return
+	}
+
+	res, err := h.service.Resize(r.Context(), id, request.Quantity, request.ExpectedQuantity)
+	if err != nil {
+		writeReservationError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
+
+func writeReservationError(w http.ResponseWriter, err error) {
+	switch err {
+	case product.ErrNotFound, product.ErrReservationNotFound:
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case product.ErrConcurrentUpdate:
+		w.Header().Set("Retry-After", retryAfterSeconds)
+		http.Error(w, err.Error(), http.StatusConflict)
+	case product.ErrInsufficientStock, product.ErrReservationNotActive, product.ErrReservationInBatch,
+		product.ErrReservationChanged:
+		http.Error(w, err.Error(), http.StatusConflict)
+	case product.ErrReservationExpired:
+		http.Error(w, err.Error(), http.StatusGone)
+	case product.ErrInvalidQuantity, product.ErrEmptyBatch:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -6,13 +6,20 @@ import (
 	"github.com/gorilla/mux"
 )
 
-func NewRouter(productHandler *handlers.ProductHandler) *mux.Router {
+func NewRouter(productHandler *handlers.ProductHandler, reservationHandler *handlers.ReservationHandler) *mux.Router {
 	router := mux.NewRouter()
 	router.HandleFunc("/products", productHandler.Create).Methods("POST")
 	router.HandleFunc("/products", productHandler.GetAllProducts).Methods("GET")
-	router.HandleFunc("/products/{id}/reserve-stock", productHandler.ReserveStock).Methods("POST")
-	router.HandleFunc("/products/{id}/confirm-stock", productHandler.ConfirmStock).Methods("POST")
-	router.HandleFunc("/products/{id}/cancel-reserve", productHandler.CancelReservation).Methods("POST")
+	router.HandleFunc("/products/{id}/restock", productHandler.Restock).Methods("POST")
 	router.HandleFunc("/products/{id}", productHandler.GetProduct).Methods("GET")
+	router.HandleFunc("/reservations", reservationHandler.Create).Methods("POST")
+	router.HandleFunc("/reservations/{id}", reservationHandler.Get).Methods("GET")
+	router.HandleFunc("/reservations/{id}/confirm", reservationHandler.Confirm).Methods("POST")
+	router.HandleFunc("/reservations/{id}/cancel", reservationHandler.Cancel).Methods("POST")
+	router.HandleFunc("/reservations/{id}/resize", reservationHandler.Resize).Methods("POST")
+	router.HandleFunc("/reservation-batches", reservationHandler.CreateBatch).Methods("POST")
+	router.HandleFunc("/reservation-batches/{id}", reservationHandler.GetBatch).Methods("GET")
+	router.HandleFunc("/reservation-batches/{id}/confirm", reservationHandler.ConfirmBatch).Methods("POST")
+	router.HandleFunc("/reservation-batches/{id}/cancel", reservationHandler.CancelBatch).Methods("POST")
 	return router
 }
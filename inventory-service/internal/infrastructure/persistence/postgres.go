@@ -5,6 +5,7 @@ import (
 	"database/sql"
 
 	"github.com/vitorwhois/microservice-invoice-billing/inventory-service/internal/domain/product"
+	"github.com/vitorwhois/microservice-invoice-billing/money"
 )
 
 type PostgresRepository struct {
@@ -17,33 +18,40 @@ func NewProductRepository(db *sql.DB) product.Repository {
 
 func (r *PostgresRepository) Create(ctx context.Context, p *product.Product) error {
 	query := `
-        INSERT INTO products (name, price, stock, reserved_stock, version)
-        VALUES ($1, $2, $3, $4, $5)
+        INSERT INTO products (name, price, currency, stock, reserved_stock, version)
+        VALUES ($1, $2, $3, $4, $5, $6)
         RETURNING id`
 
 	return r.db.QueryRowContext(ctx, query,
-		p.Name, p.Price, p.Stock, p.ReservedStock, p.Version,
+		p.Name, p.Price, p.Currency, p.Stock, p.ReservedStock, p.Version,
 	).Scan(&p.ID)
 }
 
 func (r *PostgresRepository) GetByID(ctx context.Context, id int) (*product.Product, error) {
 	p := &product.Product{}
 	query := `
-        SELECT id, name, price, stock, reserved_stock, version, created_at
+        SELECT id, name, price, currency, stock, reserved_stock, version, created_at
         FROM products WHERE id = $1`
 
+	var price money.Literal
 	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&p.ID, &p.Name, &p.Price, &p.Stock, &p.ReservedStock, &p.Version, &p.CreatedAt,
+		&p.ID, &p.Name, &price, &p.Currency, &p.Stock, &p.ReservedStock, &p.Version, &p.CreatedAt,
 	)
 	if err == sql.ErrNoRows {
 		return nil, product.ErrNotFound
 	}
-	return p, err
+	if err != nil {
+		return nil, err
+	}
+	if p.Price, err = price.In(p.Currency); err != nil {
+		return nil, err
+	}
+	return p, nil
 }
 
 func (r *PostgresRepository) GetAll(ctx context.Context) ([]*product.Product, error) {
 	query := `
-        SELECT id, name, price, stock, reserved_stock, version, created_at
+        SELECT id, name, price, currency, stock, reserved_stock, version, created_at
         FROM products
         ORDER BY created_at DESC`
 
@@ -56,11 +64,15 @@ func (r *PostgresRepository) GetAll(ctx context.Context) ([]*product.Product, er
 	products := make([]*product.Product, 0)
 	for rows.Next() {
 		p := &product.Product{}
+		var price money.Literal
 		if err := rows.Scan(
-			&p.ID, &p.Name, &p.Price, &p.Stock, &p.ReservedStock, &p.Version, &p.CreatedAt,
+			&p.ID, &p.Name, &price, &p.Currency, &p.Stock, &p.ReservedStock, &p.Version, &p.CreatedAt,
 		); err != nil {
 			return nil, err
 		}
+		if p.Price, err = price.In(p.Currency); err != nil {
+			return nil, err
+		}
 		products = append(products, p)
 	}
 
@@ -72,12 +84,52 @@ func (r *PostgresRepository) GetAll(ctx context.Context) ([]*product.Product, er
 }
 
 func (r *PostgresRepository) Update(ctx context.Context, p *product.Product) error {
+	return updateProduct(ctx, r.db, p)
+}
+
+func (r *PostgresRepository) UpdateRestock(ctx context.Context, p *product.Product, key string, quantity int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+        INSERT INTO restocks (idempotency_key, product_id, quantity)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (idempotency_key) DO NOTHING`
+
+	result, err := tx.ExecContext(ctx, query, key, p.ID, quantity)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return product.ErrRestockApplied
+	}
+
+	if err := updateProduct(ctx, tx, p); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// updateProduct writes the stock counters guarded by the optimistic version
+// check. It runs either on the pool or inside a caller's transaction.
+func updateProduct(ctx context.Context, db execer, p *product.Product) error {
 	query := `
         UPDATE products 
         SET stock = $1, reserved_stock = $2, version = $3
         WHERE id = $4 AND version = $5`
 
-	result, err := r.db.ExecContext(ctx, query,
+	result, err := db.ExecContext(ctx, query,
 		p.Stock, p.ReservedStock, p.Version,
 		p.ID, p.Version-1,
 	)
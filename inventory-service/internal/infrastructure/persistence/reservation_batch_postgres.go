@@ -0,0 +1,154 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/vitorwhois/microservice-invoice-billing/inventory-service/internal/domain/product"
+)
+
+// CreateBatch writes the batch, its reservations, the holds it took over and
+// every product counter in one transaction: either all lines are reserved or
+// none is. Products are written in the order given, which callers keep sorted
+// by ID so that concurrent batches cannot deadlock.
+func (r *PostgresReservationRepository) CreateBatch(ctx context.Context, b *product.ReservationBatch, holds []*product.Reservation, products []*product.Product) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+        INSERT INTO reservation_batches (owner, status, expires_at, created_at, updated_at)
+        VALUES ($1, $2, $3, $4, $5)
+        RETURNING id`
+
+	err = tx.QueryRowContext(ctx, query,
+		b.Owner, b.Status, b.ExpiresAt, b.CreatedAt, b.UpdatedAt,
+	).Scan(&b.ID)
+	if err != nil {
+		return err
+	}
+
+	// A hold released or confirmed meanwhile fails the whole batch
+	for _, hold := range holds {
+		if err := updateReservationStatus(ctx, tx, "reservations", hold.ID, hold.Status, hold.UpdatedAt); err != nil {
+			return err
+		}
+	}
+
+	for _, p := range products {
+		if err := updateProduct(ctx, tx, p); err != nil {
+			return err
+		}
+	}
+
+	for _, res := range b.Reservations {
+		res.BatchID = b.ID
+		if err := insertReservation(ctx, tx, res); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *PostgresReservationRepository) UpdateBatch(ctx context.Context, b *product.ReservationBatch, products []*product.Product) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := updateReservationStatus(ctx, tx, "reservation_batches", b.ID, b.Status, b.UpdatedAt); err != nil {
+		return err
+	}
+
+	for _, res := range b.Reservations {
+		if err := updateReservationStatus(ctx, tx, "reservations", res.ID, res.Status, res.UpdatedAt); err != nil {
+			return err
+		}
+	}
+
+	for _, p := range products {
+		if err := updateProduct(ctx, tx, p); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *PostgresReservationRepository) GetBatch(ctx context.Context, id int) (*product.ReservationBatch, error) {
+	query := `
+        SELECT id, owner, status, expires_at, created_at, updated_at
+        FROM reservation_batches WHERE id = $1`
+
+	b := &product.ReservationBatch{}
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&b.ID, &b.Owner, &b.Status, &b.ExpiresAt, &b.CreatedAt, &b.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, product.ErrReservationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.loadBatchReservations(ctx, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func (r *PostgresReservationRepository) ListExpiredBatches(ctx context.Context, now time.Time, limit int) ([]*product.ReservationBatch, error) {
+	query := `
+        SELECT id, owner, status, expires_at, created_at, updated_at
+        FROM reservation_batches
+        WHERE status = $1 AND expires_at <= $2
+        ORDER BY expires_at
+        LIMIT $3`
+
+	rows, err := r.db.QueryContext(ctx, query, product.ReservationActive, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	batches := make([]*product.ReservationBatch, 0)
+	for rows.Next() {
+		b := &product.ReservationBatch{}
+		if err := rows.Scan(&b.ID, &b.Owner, &b.Status, &b.ExpiresAt, &b.CreatedAt, &b.UpdatedAt); err != nil {
+			return nil, err
+		}
+		batches = append(batches, b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, b := range batches {
+		if err := r.loadBatchReservations(ctx, b); err != nil {
+			return nil, err
+		}
+	}
+	return batches, nil
+}
+
+func (r *PostgresReservationRepository) loadBatchReservations(ctx context.Context, b *product.ReservationBatch) error {
+	query := `
+        SELECT ` + reservationColumns + `
+        FROM reservations
+        WHERE batch_id = $1
+        ORDER BY product_id`
+
+	rows, err := r.db.QueryContext(ctx, query, b.ID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	b.Reservations, err = scanReservations(rows)
+	return err
+}
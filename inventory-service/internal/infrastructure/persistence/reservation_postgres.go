@@ -0,0 +1,188 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/vitorwhois/microservice-invoice-billing/inventory-service/internal/domain/product"
+)
+
+type PostgresReservationRepository struct {
+	db *sql.DB
+}
+
+func NewReservationRepository(db *sql.DB) product.ReservationRepository {
+	return &PostgresReservationRepository{db: db}
+}
+
+func (r *PostgresReservationRepository) Create(ctx context.Context, res *product.Reservation, p *product.Product) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := updateProduct(ctx, tx, p); err != nil {
+		return err
+	}
+
+	if err := insertReservation(ctx, tx, res); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func insertReservation(ctx context.Context, tx *sql.Tx, res *product.Reservation) error {
+	query := `
+        INSERT INTO reservations (batch_id, product_id, quantity, owner, status, expires_at, created_at, updated_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+        RETURNING id`
+
+	batchID := sql.NullInt64{Int64: int64(res.BatchID), Valid: res.BatchID != 0}
+	return tx.QueryRowContext(ctx, query,
+		batchID, res.ProductID, res.Quantity, res.Owner, res.Status, res.ExpiresAt, res.CreatedAt, res.UpdatedAt,
+	).Scan(&res.ID)
+}
+
+func (r *PostgresReservationRepository) Update(ctx context.Context, res *product.Reservation, p *product.Product) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := updateReservationStatus(ctx, tx, "reservations", res.ID, res.Status, res.UpdatedAt); err != nil {
+		return err
+	}
+
+	if err := updateProduct(ctx, tx, p); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *PostgresReservationRepository) Resize(ctx context.Context, res *product.Reservation, previous int, p *product.Product) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+        UPDATE reservations
+        SET quantity = $1, updated_at = $2
+        WHERE id = $3 AND status = $4 AND quantity = $5`
+
+	result, err := tx.ExecContext(ctx, query, res.Quantity, res.UpdatedAt, res.ID, product.ReservationActive, previous)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return product.ErrReservationChanged
+	}
+
+	if err := updateProduct(ctx, tx, p); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// updateReservationStatus moves an active reservation (or batch) to its new
+// status. Requiring the row to still be ACTIVE keeps a confirm and a cancel
+// (or the sweeper) from both applying their stock effect.
+func updateReservationStatus(ctx context.Context, tx *sql.Tx, table string, id int, status product.ReservationStatus, updatedAt time.Time) error {
+	query := `
+        UPDATE ` + table + `
+        SET status = $1, updated_at = $2
+        WHERE id = $3 AND status = $4`
+
+	result, err := tx.ExecContext(ctx, query, status, updatedAt, id, product.ReservationActive)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return product.ErrReservationNotActive
+	}
+	return nil
+}
+
+func (r *PostgresReservationRepository) GetByID(ctx context.Context, id int) (*product.Reservation, error) {
+	query := `
+        SELECT ` + reservationColumns + `
+        FROM reservations WHERE id = $1`
+
+	res, err := scanReservation(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, product.ErrReservationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (r *PostgresReservationRepository) ListExpired(ctx context.Context, now time.Time, limit int) ([]*product.Reservation, error) {
+	// Batch members expire together with their batch
+	query := `
+        SELECT ` + reservationColumns + `
+        FROM reservations
+        WHERE status = $1 AND expires_at <= $2 AND batch_id IS NULL
+        ORDER BY expires_at
+        LIMIT $3`
+
+	rows, err := r.db.QueryContext(ctx, query, product.ReservationActive, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanReservations(rows)
+}
+
+const reservationColumns = `id, COALESCE(batch_id, 0), product_id, quantity, owner, status, expires_at, created_at, updated_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanReservation(row rowScanner) (*product.Reservation, error) {
+	res := &product.Reservation{}
+	err := row.Scan(
+		&res.ID, &res.BatchID, &res.ProductID, &res.Quantity, &res.Owner, &res.Status,
+		&res.ExpiresAt, &res.CreatedAt, &res.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func scanReservations(rows *sql.Rows) ([]*product.Reservation, error) {
+	reservations := make([]*product.Reservation, 0)
+	for rows.Next() {
+		res, err := scanReservation(rows)
+		if err != nil {
+			return nil, err
+		}
+		reservations = append(reservations, res)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return reservations, nil
+}
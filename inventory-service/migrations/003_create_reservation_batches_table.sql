@@ -0,0 +1,13 @@
+CREATE TABLE reservation_batches (
+    id SERIAL PRIMARY KEY,
+    owner VARCHAR(255) NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE reservations ADD COLUMN batch_id INTEGER REFERENCES reservation_batches(id);
+
+CREATE INDEX idx_reservations_batch_id ON reservations (batch_id);
+CREATE INDEX idx_reservation_batches_active_expiry ON reservation_batches (expires_at) WHERE status = 'ACTIVE';
@@ -0,0 +1,7 @@
+-- Restocks applied under an idempotency key, so a retried return is counted once
+CREATE TABLE IF NOT EXISTS restocks (
+    idempotency_key VARCHAR(255) PRIMARY KEY,
+    product_id INTEGER NOT NULL REFERENCES products(id),
+    quantity INTEGER NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
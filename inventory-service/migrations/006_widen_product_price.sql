@@ -0,0 +1,4 @@
+-- Prices are stored with up to four decimal places, the most any ISO 4217
+-- currency has, and up to 15 integer digits so that currencies such as IDR
+-- fit.
+ALTER TABLE products ALTER COLUMN price TYPE NUMERIC(19,4);
@@ -0,0 +1,3 @@
+module github.com/vitorwhois/microservice-invoice-billing/money
+
+go 1.21
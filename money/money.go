@@ -0,0 +1,337 @@
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// DefaultCurrency is the currency of amounts that do not carry one, such as
+// those stored before invoices were denominated in other currencies.
+const DefaultCurrency = "BRL"
+
+var (
+	ErrInvalidAmount    = errors.New("invalid monetary amount")
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+	ErrOverflow         = errors.New("monetary amount out of range")
+)
+
+// Money is an exact monetary value: Amount is expressed in the minor units of
+// Currency (centavos for BRL, yen for JPY, fils for KWD) and never goes
+// through a float.
+//
+// Rounding rule: whenever a value carries more decimal places than its
+// currency has minor units (parsing "10.005" BRL, applying a rate) it is
+// rounded half away from zero.
+type Money struct {
+	Amount   int64
+	Currency string
+}
+
+// ValidCurrency reports whether code is an active ISO 4217 currency code.
+func ValidCurrency(code string) bool {
+	_, ok := currencies[code]
+	return ok
+}
+
+// MinorUnits is the number of decimal places of currency as listed in ISO
+// 4217: 2 for BRL, 0 for JPY, 3 for KWD. An empty currency is DefaultCurrency.
+func MinorUnits(currency string) int {
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+	if units, ok := currencies[currency]; ok {
+		return units
+	}
+	return currencies[DefaultCurrency]
+}
+
+func New(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+func Zero(currency string) Money {
+	return Money{Currency: currency}
+}
+
+// Parse reads a plain decimal literal such as "2800", "2800.5" or "-0.015" in
+// the minor units of currency.
+func Parse(s string, currency string) (Money, error) {
+	amount, err := parseMinorUnits(s, MinorUnits(currency))
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{Amount: amount, Currency: currency}, nil
+}
+
+func MustParse(s string, currency string) Money {
+	m, err := Parse(s, currency)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+func parseMinorUnits(s string, scale int) (int64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, ErrInvalidAmount
+	}
+
+	negative := false
+	switch s[0] {
+	case '-':
+		negative = true
+		s = s[1:]
+	case '+':
+		s = s[1:]
+	}
+
+	intPart, fracPart, _ := strings.Cut(s, ".")
+	if intPart == "" && fracPart == "" {
+		return 0, ErrInvalidAmount
+	}
+	if intPart == "" {
+		intPart = "0"
+	}
+	if !isDigits(intPart) || !isDigits(fracPart) {
+		return 0, ErrInvalidAmount
+	}
+
+	roundUp := false
+	if len(fracPart) > scale {
+		roundUp = fracPart[scale] >= '5'
+		fracPart = fracPart[:scale]
+	}
+	fracPart += strings.Repeat("0", scale-len(fracPart))
+
+	amount, err := strconv.ParseInt(intPart+fracPart, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidAmount
+	}
+	if roundUp {
+		amount++
+	}
+	if negative {
+		amount = -amount
+	}
+	return amount, nil
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func (m Money) IsZero() bool     { return m.Amount == 0 }
+func (m Money) IsNegative() bool { return m.Amount < 0 }
+func (m Money) IsPositive() bool { return m.Amount > 0 }
+
+// SameCurrency reports whether m and o can be combined. A zero value without a
+// currency is compatible with anything so that `var total money.Money` works
+// as an accumulator.
+func (m Money) SameCurrency(o Money) bool {
+	return m.Currency == "" || o.Currency == "" || m.Currency == o.Currency
+}
+
+func (m Money) mustMatch(o Money) string {
+	if !m.SameCurrency(o) {
+		panic(fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, o.Currency))
+	}
+	if m.Currency != "" {
+		return m.Currency
+	}
+	return o.Currency
+}
+
+func (m Money) Add(o Money) Money {
+	currency := m.mustMatch(o)
+	return Money{Amount: m.Amount + o.Amount, Currency: currency}
+}
+
+func (m Money) Sub(o Money) Money {
+	currency := m.mustMatch(o)
+	return Money{Amount: m.Amount - o.Amount, Currency: currency}
+}
+
+// Mul multiplies by an integer quantity; the result is exact.
+func (m Money) Mul(quantity int64) Money {
+	return Money{Amount: m.Amount * quantity, Currency: m.Currency}
+}
+
+// MulRatio returns m * num / den rounded half away from zero, e.g.
+// MulRatio(1800, 10000) applies a rate of 18.00%. The product goes through
+// big.Int, so it may exceed int64; a result that does not fit panics with
+// ErrOverflow, which a ratio of at most one never causes.
+func (m Money) MulRatio(num, den int64) Money {
+	return Money{Amount: divRound(big.NewInt(m.Amount), big.NewInt(num), big.NewInt(den)), Currency: m.Currency}
+}
+
+// divRound returns n * num / d rounded half away from zero.
+func divRound(n, num, d *big.Int) int64 {
+	n = new(big.Int).Mul(n, num)
+	if d.Sign() < 0 {
+		n.Neg(n)
+		d = new(big.Int).Neg(d)
+	}
+	q, r := new(big.Int).QuoRem(n, d, new(big.Int))
+	if r.Mul(r.Abs(r), big.NewInt(2)).Cmp(d) >= 0 {
+		q.Add(q, big.NewInt(int64(n.Sign())))
+	}
+	if !q.IsInt64() {
+		panic(fmt.Errorf("%w: %s / %s", ErrOverflow, n, d))
+	}
+	return q.Int64()
+}
+
+func (m Money) Neg() Money {
+	return Money{Amount: -m.Amount, Currency: m.Currency}
+}
+
+// Cmp returns -1, 0 or +1 depending on whether m is less than, equal to or
+// greater than o.
+func (m Money) Cmp(o Money) int {
+	m.mustMatch(o)
+	switch {
+	case m.Amount < o.Amount:
+		return -1
+	case m.Amount > o.Amount:
+		return 1
+	}
+	return 0
+}
+
+// Decimal formats the amount as a plain decimal literal with the minor units
+// of its currency ("2800.00" BRL, "2800" JPY).
+func (m Money) Decimal() string {
+	amount := m.Amount
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	scale := MinorUnits(m.Currency)
+	if scale == 0 {
+		return fmt.Sprintf("%s%d", sign, amount)
+	}
+	unit := int64(1)
+	for i := 0; i < scale; i++ {
+		unit *= 10
+	}
+	return fmt.Sprintf("%s%d.%0*d", sign, amount/unit, scale, amount%unit)
+}
+
+func (m Money) String() string {
+	if m.Currency == "" {
+		return m.Decimal()
+	}
+	return m.Currency + " " + m.Decimal()
+}
+
+// MarshalJSON encodes the value as a JSON number with exactly the minor units
+// of its currency, keeping the wire format of the previous float64 fields.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.Decimal()), nil
+}
+
+// UnmarshalJSON accepts a JSON number or a decimal string. The literal text is
+// parsed directly, so no precision is lost through float64. The currency must
+// be set beforehand since it decides the minor units; it defaults to
+// DefaultCurrency otherwise. Use Literal when the currency comes later.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var l Literal
+	if err := l.UnmarshalJSON(data); err != nil || l == "" {
+		return err
+	}
+	return m.set(l)
+}
+
+// Scan reads NUMERIC columns, which the postgres driver hands over as text.
+// As with UnmarshalJSON the currency must be seeded before scanning; use
+// Literal when it is read from the same row.
+func (m *Money) Scan(src interface{}) error {
+	var l Literal
+	if err := l.Scan(src); err != nil {
+		return err
+	}
+	if l == "" {
+		m.Amount = 0
+		return nil
+	}
+	return m.set(l)
+}
+
+func (m *Money) set(l Literal) error {
+	if m.Currency == "" {
+		m.Currency = DefaultCurrency
+	}
+	amount, err := parseMinorUnits(string(l), MinorUnits(m.Currency))
+	if err != nil {
+		return err
+	}
+	m.Amount = amount
+	return nil
+}
+
+// Value stores the amount as a decimal literal so NUMERIC columns receive it
+// without conversion.
+func (m Money) Value() (driver.Value, error) {
+	return m.Decimal(), nil
+}
+
+// Literal is a decimal amount read before its currency is known: a request
+// field in the currency of the invoice, or a NUMERIC column scanned alongside
+// the currency column. In turns it into Money once the currency is at hand.
+type Literal string
+
+// In parses the literal in the minor units of currency. An empty literal is
+// zero.
+func (l Literal) In(currency string) (Money, error) {
+	if l == "" {
+		return Zero(currency), nil
+	}
+	return Parse(string(l), currency)
+}
+
+// UnmarshalJSON accepts a JSON number or a decimal string, as Money does.
+func (l *Literal) UnmarshalJSON(data []byte) error {
+	s := strings.TrimSpace(string(data))
+	if s == "null" {
+		return nil
+	}
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+	if strings.ContainsAny(s, "eE") {
+		return ErrInvalidAmount
+	}
+	if _, err := parseMinorUnits(s, 0); err != nil {
+		return err
+	}
+	*l = Literal(s)
+	return nil
+}
+
+// Scan reads a NUMERIC column as text; NULL leaves the literal empty.
+func (l *Literal) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*l = ""
+	case []byte:
+		*l = Literal(v)
+	case string:
+		*l = Literal(v)
+	case int64:
+		*l = Literal(strconv.FormatInt(v, 10))
+	default:
+		return fmt.Errorf("money: cannot scan %T", src)
+	}
+	return nil
+}
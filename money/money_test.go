@@ -0,0 +1,147 @@
+package money
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input    string
+		currency string
+		want     int64
+	}{
+		{"2800", "BRL", 280000},
+		{"2800.5", "BRL", 280050},
+		{"+3", "BRL", 300},
+		{".5", "BRL", 50},
+		{" 12.34 ", "BRL", 1234},
+		// Extra places are rounded half away from zero
+		{"10.005", "BRL", 1001},
+		{"10.004", "BRL", 1000},
+		{"-0.015", "BRL", -2},
+		{"-0.014", "BRL", -1},
+		{"1.5", "JPY", 2},
+		{"1.4", "JPY", 1},
+		{"1.2345", "KWD", 1235},
+		{"0.00005", "CLF", 1},
+		// Without a currency the amount is in DefaultCurrency
+		{"1.99", "", 199},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.input, tt.currency)
+		if err != nil {
+			t.Errorf("Parse(%q, %q) returned %v", tt.input, tt.currency, err)
+			continue
+		}
+		if got.Amount != tt.want || got.Currency != tt.currency {
+			t.Errorf("Parse(%q, %q) = %d %s, want %d %s", tt.input, tt.currency, got.Amount, got.Currency, tt.want, tt.currency)
+		}
+	}
+}
+
+func TestParseRejectsInvalidAmounts(t *testing.T) {
+	for _, input := range []string{"", " ", "-", ".", "1.2.3", "abc", "1,50", "1e3", "--1", "99999999999999999999"} {
+		if _, err := Parse(input, "BRL"); !errors.Is(err, ErrInvalidAmount) {
+			t.Errorf("Parse(%q) error = %v, want %v", input, err, ErrInvalidAmount)
+		}
+	}
+}
+
+func TestMinorUnits(t *testing.T) {
+	tests := []struct {
+		currency string
+		want     int
+	}{
+		{"BRL", 2},
+		{"USD", 2},
+		{"JPY", 0},
+		{"CLP", 0},
+		{"KWD", 3},
+		{"BHD", 3},
+		{"CLF", 4},
+		{"UYW", 4},
+		// Empty and unknown currencies fall back to DefaultCurrency
+		{"", 2},
+		{"XXX", 2},
+	}
+	for _, tt := range tests {
+		if got := MinorUnits(tt.currency); got != tt.want {
+			t.Errorf("MinorUnits(%q) = %d, want %d", tt.currency, got, tt.want)
+		}
+	}
+}
+
+func TestDecimal(t *testing.T) {
+	tests := []struct {
+		m    Money
+		want string
+	}{
+		{New(280000, "BRL"), "2800.00"},
+		{New(-5, "BRL"), "-0.05"},
+		{New(2800, "JPY"), "2800"},
+		{New(1235, "KWD"), "1.235"},
+		{New(1, "CLF"), "0.0001"},
+	}
+	for _, tt := range tests {
+		if got := tt.m.Decimal(); got != tt.want {
+			t.Errorf("%#v.Decimal() = %q, want %q", tt.m, got, tt.want)
+		}
+	}
+}
+
+func TestMulRatio(t *testing.T) {
+	tests := []struct {
+		amount   int64
+		num, den int64
+		want     int64
+	}{
+		{100000, 1800, 10000, 18000},
+		// Halves round away from zero, whatever carries the sign
+		{5, 1, 2, 3},
+		{-5, 1, 2, -3},
+		{5, -1, 2, -3},
+		{5, 1, -2, -3},
+		{-5, -1, 2, 3},
+		{-5, 1, -2, 3},
+		{3, 1, 4, 1},
+		{-3, 1, 4, -1},
+		{1, 1, 4, 0},
+		{-1, 1, 4, 0},
+		{0, 1, 3, 0},
+		// The product overflows int64 but the result does not
+		{math.MaxInt64, 1800, 10000, 1660206966633859645},
+		{math.MinInt64, 1800, 10000, -1660206966633859645},
+		{math.MaxInt64, 3, 3, math.MaxInt64},
+		{100000000000000000, 1800, 10000, 18000000000000000},
+	}
+	for _, tt := range tests {
+		got := New(tt.amount, "BRL").MulRatio(tt.num, tt.den)
+		if got.Amount != tt.want || got.Currency != "BRL" {
+			t.Errorf("%d.MulRatio(%d, %d) = %d %s, want %d BRL", tt.amount, tt.num, tt.den, got.Amount, got.Currency, tt.want)
+		}
+	}
+}
+
+func TestMulRatioOverflow(t *testing.T) {
+	tests := []struct {
+		amount   int64
+		num, den int64
+	}{
+		{math.MaxInt64, 2, 1},
+		{math.MinInt64, -1, 1},
+		{math.MaxInt64 / 2, 3, 1},
+	}
+	for _, tt := range tests {
+		func() {
+			defer func() {
+				err, _ := recover().(error)
+				if !errors.Is(err, ErrOverflow) {
+					t.Errorf("%d.MulRatio(%d, %d) panicked with %v, want %v", tt.amount, tt.num, tt.den, err, ErrOverflow)
+				}
+			}()
+			New(tt.amount, "BRL").MulRatio(tt.num, tt.den)
+		}()
+	}
+}
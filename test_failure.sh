@@ -11,7 +11,7 @@ echo -e "\n"
 echo "Criando invoice..."
 curl -X POST http://localhost:8081/invoices \
     -H "Content-Type: application/json" \
-    -d '{"number":"INV-001"}'
+    -d '{}'
 echo -e "\n"
 
 # Adicionar um item na invoice